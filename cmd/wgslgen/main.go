@@ -0,0 +1,441 @@
+// Command wgslgen generates Go types whose memory layout matches the
+// structures of a WGSL module, for use with uniform and storage buffers.
+//
+// It is meant to be run through go:generate next to the shader:
+//
+//	//go:generate go run github.com/openfluke/webgpu/cmd/wgslgen -i shader.wgsl -o shader_wgsl.go -pkg main
+//
+// For every host-shareable structure it emits a Go struct with explicit
+// padding fields, a <Name>Size constant, Marshal/Unmarshal methods that
+// encode the little-endian layout the GPU expects, and compile-time
+// assertions on the size and member offsets. Structures with @location
+// or @builtin members are treated as shader stage I/O and skipped unless
+// named with -types.
+//
+// WGSL types map to Go as follows: f32, i32 and u32 (and their atomics)
+// to float32, int32 and uint32, f16 to its raw uint16 bits, vecN<T> to
+// [N]T, matCxR<T> to [C][R]T with three-row columns padded to [4]T,
+// array<E, N> to [N]E with vec3 elements padded to [4]T, and a trailing
+// runtime-sized array to a slice that only Marshal and Unmarshal see.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/openfluke/webgpu/wgsl"
+	gofumpt "mvdan.cc/gofumpt/format"
+)
+
+var (
+	inputFile   string
+	outputFile  string
+	packageName string
+	typeNames   string
+)
+
+func init() {
+	flag.StringVar(&inputFile, "i", "", "input .wgsl file")
+	flag.StringVar(&outputFile, "o", "", "output .go file")
+	flag.StringVar(&packageName, "pkg", "", "package name of the output file")
+	flag.StringVar(&typeNames, "types", "", "comma-separated WGSL structures to generate (default all host-shareable)")
+}
+
+func main() {
+	flag.Parse()
+	if inputFile == "" || outputFile == "" || packageName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src := mustv(os.ReadFile(inputFile))
+	r, err := wgsl.ReflectSource(string(src))
+	if err != nil {
+		log.Fatalf("%s:%v", inputFile, err)
+	}
+
+	var structs []*wgsl.Struct
+	if typeNames != "" {
+		for _, name := range strings.Split(typeNames, ",") {
+			t, err := r.Lookup(strings.TrimSpace(name))
+			if err != nil {
+				log.Fatalf("%s: %v", inputFile, err)
+			}
+			s, ok := t.(*wgsl.Struct)
+			if !ok {
+				log.Fatalf("%s: %s is not a structure", inputFile, name)
+			}
+			structs = append(structs, s)
+		}
+	} else {
+		for _, s := range r.Structs {
+			if !isIOStruct(s) && hostShareable(s) {
+				structs = append(structs, s)
+			}
+		}
+	}
+
+	g := &generator{w: &bytes.Buffer{}, done: map[*wgsl.Struct]bool{}}
+	for _, s := range structs {
+		if err := g.check(s); err != nil {
+			log.Fatalf("%s: %v", inputFile, err)
+		}
+	}
+
+	for _, s := range structs {
+		g.emit(s)
+	}
+
+	w := &bytes.Buffer{}
+	fmt.Fprintf(w, "// Code generated by github.com/openfluke/webgpu/cmd/wgslgen from %s; DO NOT EDIT.\n\n", filepath.Base(inputFile))
+	fmt.Fprintf(w, "package %s\n\n", packageName)
+	fmt.Fprintf(w, "import (\n")
+	for _, pkg := range []string{"encoding/binary", "fmt", "math", "unsafe"} {
+		if bytes.Contains(g.w.Bytes(), []byte(filepath.Base(pkg)+".")) {
+			fmt.Fprintf(w, "%q\n", pkg)
+		}
+	}
+	fmt.Fprintf(w, ")\n\n")
+	w.Write(g.w.Bytes())
+
+	out := mustv(os.Create(outputFile))
+	mustv(out.Write(fmtFile(w.Bytes())))
+	must(out.Close())
+}
+
+type generator struct {
+	w    *bytes.Buffer
+	done map[*wgsl.Struct]bool
+}
+
+// check reports types that have no host-shareable layout.
+func (g *generator) check(t wgsl.Type) error {
+	switch t := t.(type) {
+	case wgsl.Scalar:
+		if t == wgsl.Bool {
+			return fmt.Errorf("bool is not host-shareable")
+		}
+	case *wgsl.Vector:
+		return g.check(t.Elem)
+	case *wgsl.Matrix, *wgsl.Atomic:
+	case *wgsl.Array:
+		return g.check(t.Elem)
+	case *wgsl.Struct:
+		for _, m := range t.Members {
+			if err := g.check(m.Type); err != nil {
+				return fmt.Errorf("%s.%s: %w", t.Name, m.Name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%s is not host-shareable", t)
+	}
+	return nil
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(g.w, format, args...)
+}
+
+func (g *generator) emit(s *wgsl.Struct) {
+	if g.done[s] {
+		return
+	}
+	g.done[s] = true
+	// Nested structures are emitted first so the file reads bottom-up.
+	for _, m := range s.Members {
+		if n := nestedStruct(m.Type); n != nil {
+			g.emit(n)
+		}
+	}
+
+	name := goName(s.Name)
+	tail := runtimeArray(s)
+	size := wgsl.SizeOf(s)
+	if tail != nil {
+		size = s.Members[len(s.Members)-1].Offset
+	}
+
+	if tail != nil {
+		g.printf("// %s matches the WGSL structure %s. %s holds the elements of the\n", name, s.Name, goName(tail.Name))
+		g.printf("// runtime-sized array, which starts at byte %d with a stride of %d.\n", tail.Offset, tail.Type.(*wgsl.Array).Stride())
+	} else {
+		g.printf("// %s matches the WGSL structure %s (size %d, align %d).\n", name, s.Name, size, wgsl.AlignOf(s))
+	}
+	g.printf("type %s struct {\n", name)
+	var end uint64
+	for _, m := range s.Members {
+		if m == tail {
+			g.printf("%s []%s\n", goName(m.Name), goType(m.Type.(*wgsl.Array).Elem, true))
+			break
+		}
+		if m.Offset > end {
+			g.printf("_ [%d]byte\n", m.Offset-end)
+		}
+		g.printf("%s %s // offset %d\n", goName(m.Name), goType(m.Type, false), m.Offset)
+		end = m.Offset + goSize(m.Type)
+	}
+	if tail == nil && size > end {
+		g.printf("_ [%d]byte\n", size-end)
+	}
+	g.printf("}\n\n")
+
+	g.printf("// %sSize is the size in bytes of %s", name, s.Name)
+	if tail != nil {
+		g.printf(" without its runtime-sized array")
+	}
+	g.printf(".\nconst %sSize = %d\n\n", name, size)
+
+	if tail == nil {
+		g.printf("var (\n")
+		g.printf("_ = [1]struct{}{}[unsafe.Sizeof(%s{})-%sSize]\n", name, name)
+		for _, m := range s.Members {
+			g.printf("_ = [1]struct{}{}[unsafe.Offsetof(%s{}.%s)-%d]\n", name, goName(m.Name), m.Offset)
+		}
+		g.printf(")\n\n")
+	}
+
+	stride := uint64(0)
+	if tail != nil {
+		stride = tail.Type.(*wgsl.Array).Stride()
+	}
+
+	g.printf("// Marshal returns the WGSL memory representation of v.\n")
+	g.printf("func (v *%s) Marshal() []byte {\n", name)
+	if tail != nil {
+		g.printf("b := make([]byte, %sSize+len(v.%s)*%d)\n", name, goName(tail.Name), stride)
+	} else {
+		g.printf("b := make([]byte, %sSize)\n", name)
+	}
+	g.printf("v.marshalTo(b)\nreturn b\n}\n\n")
+
+	g.printf("// Unmarshal sets v from its WGSL memory representation in b.\n")
+	g.printf("func (v *%s) Unmarshal(b []byte) error {\n", name)
+	g.printf("if len(b) < %sSize {\n", name)
+	g.printf("return fmt.Errorf(\"%s.Unmarshal: need %%d bytes, got %%d\", %sSize, len(b))\n}\n", name, name)
+	if tail != nil {
+		g.printf("v.%s = make([]%s, (len(b)-%sSize)/%d)\n", goName(tail.Name), goType(tail.Type.(*wgsl.Array).Elem, true), name, stride)
+	}
+	g.printf("v.unmarshalFrom(b)\nreturn nil\n}\n\n")
+
+	g.printf("func (v *%s) marshalTo(b []byte) {\n", name)
+	for _, m := range s.Members {
+		g.encode(m.Type, "v."+goName(m.Name), offset{n: m.Offset}, 0)
+	}
+	g.printf("}\n\n")
+
+	g.printf("func (v *%s) unmarshalFrom(b []byte) {\n", name)
+	for _, m := range s.Members {
+		g.decode(m.Type, "v."+goName(m.Name), offset{n: m.Offset}, 0)
+	}
+	g.printf("}\n\n")
+}
+
+// offset is a byte offset into the encoded buffer: a constant plus the
+// sum of loop index terms such as "i0*16".
+type offset struct {
+	n     uint64
+	terms string
+}
+
+func (o offset) add(n uint64) offset {
+	return offset{o.n + n, o.terms}
+}
+
+func (o offset) index(i string, stride uint64) offset {
+	return offset{o.n, o.terms + fmt.Sprintf("+%s*%d", i, stride)}
+}
+
+func (o offset) String() string {
+	if o.terms == "" {
+		return fmt.Sprint(o.n)
+	}
+	if o.n == 0 {
+		return o.terms[1:]
+	}
+	return fmt.Sprintf("%d%s", o.n, o.terms)
+}
+
+// encode emits statements writing the value x of type t at byte offset
+// off of b. depth numbers the loop variables of nested arrays.
+func (g *generator) encode(t wgsl.Type, x string, off offset, depth int) {
+	switch t := t.(type) {
+	case wgsl.Scalar:
+		switch t {
+		case wgsl.F32:
+			g.printf("binary.LittleEndian.PutUint32(b[%s:], math.Float32bits(%s))\n", off, x)
+		case wgsl.I32:
+			g.printf("binary.LittleEndian.PutUint32(b[%s:], uint32(%s))\n", off, x)
+		case wgsl.U32:
+			g.printf("binary.LittleEndian.PutUint32(b[%s:], %s)\n", off, x)
+		case wgsl.F16:
+			g.printf("binary.LittleEndian.PutUint16(b[%s:], %s)\n", off, x)
+		}
+	case *wgsl.Atomic:
+		g.encode(t.Elem, x, off, depth)
+	case *wgsl.Vector:
+		for i := 0; i < t.Size; i++ {
+			g.encode(t.Elem, fmt.Sprintf("%s[%d]", x, i), off.add(uint64(i)*wgsl.SizeOf(t.Elem)), depth)
+		}
+	case *wgsl.Matrix:
+		col := &wgsl.Vector{Size: t.Rows, Elem: t.Elem}
+		stride := (&wgsl.Array{Elem: col}).Stride()
+		for c := 0; c < t.Cols; c++ {
+			g.encode(col, fmt.Sprintf("%s[%d]", x, c), off.add(uint64(c)*stride), depth)
+		}
+	case *wgsl.Array:
+		i := fmt.Sprintf("i%d", depth)
+		g.printf("for %s := range %s {\n", i, x)
+		g.encode(t.Elem, fmt.Sprintf("%s[%s]", x, i), off.index(i, t.Stride()), depth+1)
+		g.printf("}\n")
+	case *wgsl.Struct:
+		g.printf("%s.marshalTo(b[%s:])\n", x, off)
+	}
+}
+
+// decode is the inverse of encode.
+func (g *generator) decode(t wgsl.Type, x string, off offset, depth int) {
+	switch t := t.(type) {
+	case wgsl.Scalar:
+		switch t {
+		case wgsl.F32:
+			g.printf("%s = math.Float32frombits(binary.LittleEndian.Uint32(b[%s:]))\n", x, off)
+		case wgsl.I32:
+			g.printf("%s = int32(binary.LittleEndian.Uint32(b[%s:]))\n", x, off)
+		case wgsl.U32:
+			g.printf("%s = binary.LittleEndian.Uint32(b[%s:])\n", x, off)
+		case wgsl.F16:
+			g.printf("%s = binary.LittleEndian.Uint16(b[%s:])\n", x, off)
+		}
+	case *wgsl.Atomic:
+		g.decode(t.Elem, x, off, depth)
+	case *wgsl.Vector:
+		for i := 0; i < t.Size; i++ {
+			g.decode(t.Elem, fmt.Sprintf("%s[%d]", x, i), off.add(uint64(i)*wgsl.SizeOf(t.Elem)), depth)
+		}
+	case *wgsl.Matrix:
+		col := &wgsl.Vector{Size: t.Rows, Elem: t.Elem}
+		stride := (&wgsl.Array{Elem: col}).Stride()
+		for c := 0; c < t.Cols; c++ {
+			g.decode(col, fmt.Sprintf("%s[%d]", x, c), off.add(uint64(c)*stride), depth)
+		}
+	case *wgsl.Array:
+		i := fmt.Sprintf("i%d", depth)
+		g.printf("for %s := range %s {\n", i, x)
+		g.decode(t.Elem, fmt.Sprintf("%s[%s]", x, i), off.index(i, t.Stride()), depth+1)
+		g.printf("}\n")
+	case *wgsl.Struct:
+		g.printf("%s.unmarshalFrom(b[%s:])\n", x, off)
+	}
+}
+
+// goType returns the Go type representing t. Vectors with three
+// components are padded to four when they are array elements.
+func goType(t wgsl.Type, elem bool) string {
+	switch t := t.(type) {
+	case wgsl.Scalar:
+		return map[wgsl.Scalar]string{wgsl.F32: "float32", wgsl.I32: "int32", wgsl.U32: "uint32", wgsl.F16: "uint16"}[t]
+	case *wgsl.Atomic:
+		return goType(t.Elem, false)
+	case *wgsl.Vector:
+		n := t.Size
+		if elem && n == 3 {
+			n = 4
+		}
+		return fmt.Sprintf("[%d]%s", n, goType(t.Elem, false))
+	case *wgsl.Matrix:
+		return fmt.Sprintf("[%d]%s", t.Cols, goType(&wgsl.Vector{Size: t.Rows, Elem: t.Elem}, true))
+	case *wgsl.Array:
+		return fmt.Sprintf("[%d]%s", t.Count, goType(t.Elem, true))
+	case *wgsl.Struct:
+		return goName(t.Name)
+	}
+	panic("unreachable")
+}
+
+// goSize returns the size of the Go type representing t.
+func goSize(t wgsl.Type) uint64 {
+	switch t := t.(type) {
+	case *wgsl.Vector:
+		return uint64(t.Size) * wgsl.SizeOf(t.Elem)
+	case *wgsl.Array:
+		return uint64(t.Count) * t.Stride()
+	default:
+		return wgsl.SizeOf(t)
+	}
+}
+
+func goName(name string) string {
+	return strcase.ToCamel(name)
+}
+
+func nestedStruct(t wgsl.Type) *wgsl.Struct {
+	switch t := t.(type) {
+	case *wgsl.Struct:
+		return t
+	case *wgsl.Array:
+		return nestedStruct(t.Elem)
+	}
+	return nil
+}
+
+// runtimeArray returns the trailing runtime-sized array member of s, or
+// nil.
+func runtimeArray(s *wgsl.Struct) *wgsl.Member {
+	last := s.Members[len(s.Members)-1]
+	if a, ok := last.Type.(*wgsl.Array); ok && a.RuntimeSized() {
+		return last
+	}
+	return nil
+}
+
+func isIOStruct(s *wgsl.Struct) bool {
+	for _, m := range s.Decl.Members {
+		if wgsl.Attr(m.Attrs, "location") != nil || wgsl.Attr(m.Attrs, "builtin") != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func hostShareable(t wgsl.Type) bool {
+	return (&generator{}).check(t) == nil
+}
+
+func fmtFile(b []byte) []byte {
+	langVersion := ""
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.GoVersion}}").Output()
+	outSlice := bytes.Split(out, []byte("\n"))
+	out = outSlice[0]
+	out = bytes.TrimSpace(out)
+	if err == nil && len(out) > 0 {
+		langVersion = string(out)
+	}
+
+	// Run gofumpt
+	b, err = gofumpt.Source(b, gofumpt.Options{LangVersion: langVersion, ExtraRules: true})
+	if err != nil {
+		log.Fatalf("cannot run gofumpt on file: %v", err)
+	}
+
+	return b
+}
+
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustv[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
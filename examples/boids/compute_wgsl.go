@@ -0,0 +1,116 @@
+// Code generated by github.com/openfluke/webgpu/cmd/wgslgen from compute.wgsl; DO NOT EDIT.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// Particle matches the WGSL structure Particle (size 16, align 8).
+type Particle struct {
+	Pos [2]float32 // offset 0
+	Vel [2]float32 // offset 8
+}
+
+// ParticleSize is the size in bytes of Particle.
+const ParticleSize = 16
+
+var (
+	_ = [1]struct{}{}[unsafe.Sizeof(Particle{})-ParticleSize]
+	_ = [1]struct{}{}[unsafe.Offsetof(Particle{}.Pos)-0]
+	_ = [1]struct{}{}[unsafe.Offsetof(Particle{}.Vel)-8]
+)
+
+// Marshal returns the WGSL memory representation of v.
+func (v *Particle) Marshal() []byte {
+	b := make([]byte, ParticleSize)
+	v.marshalTo(b)
+	return b
+}
+
+// Unmarshal sets v from its WGSL memory representation in b.
+func (v *Particle) Unmarshal(b []byte) error {
+	if len(b) < ParticleSize {
+		return fmt.Errorf("Particle.Unmarshal: need %d bytes, got %d", ParticleSize, len(b))
+	}
+	v.unmarshalFrom(b)
+	return nil
+}
+
+func (v *Particle) marshalTo(b []byte) {
+	binary.LittleEndian.PutUint32(b[0:], math.Float32bits(v.Pos[0]))
+	binary.LittleEndian.PutUint32(b[4:], math.Float32bits(v.Pos[1]))
+	binary.LittleEndian.PutUint32(b[8:], math.Float32bits(v.Vel[0]))
+	binary.LittleEndian.PutUint32(b[12:], math.Float32bits(v.Vel[1]))
+}
+
+func (v *Particle) unmarshalFrom(b []byte) {
+	v.Pos[0] = math.Float32frombits(binary.LittleEndian.Uint32(b[0:]))
+	v.Pos[1] = math.Float32frombits(binary.LittleEndian.Uint32(b[4:]))
+	v.Vel[0] = math.Float32frombits(binary.LittleEndian.Uint32(b[8:]))
+	v.Vel[1] = math.Float32frombits(binary.LittleEndian.Uint32(b[12:]))
+}
+
+// SimParams matches the WGSL structure SimParams (size 28, align 4).
+type SimParams struct {
+	DeltaT        float32 // offset 0
+	Rule1Distance float32 // offset 4
+	Rule2Distance float32 // offset 8
+	Rule3Distance float32 // offset 12
+	Rule1Scale    float32 // offset 16
+	Rule2Scale    float32 // offset 20
+	Rule3Scale    float32 // offset 24
+}
+
+// SimParamsSize is the size in bytes of SimParams.
+const SimParamsSize = 28
+
+var (
+	_ = [1]struct{}{}[unsafe.Sizeof(SimParams{})-SimParamsSize]
+	_ = [1]struct{}{}[unsafe.Offsetof(SimParams{}.DeltaT)-0]
+	_ = [1]struct{}{}[unsafe.Offsetof(SimParams{}.Rule1Distance)-4]
+	_ = [1]struct{}{}[unsafe.Offsetof(SimParams{}.Rule2Distance)-8]
+	_ = [1]struct{}{}[unsafe.Offsetof(SimParams{}.Rule3Distance)-12]
+	_ = [1]struct{}{}[unsafe.Offsetof(SimParams{}.Rule1Scale)-16]
+	_ = [1]struct{}{}[unsafe.Offsetof(SimParams{}.Rule2Scale)-20]
+	_ = [1]struct{}{}[unsafe.Offsetof(SimParams{}.Rule3Scale)-24]
+)
+
+// Marshal returns the WGSL memory representation of v.
+func (v *SimParams) Marshal() []byte {
+	b := make([]byte, SimParamsSize)
+	v.marshalTo(b)
+	return b
+}
+
+// Unmarshal sets v from its WGSL memory representation in b.
+func (v *SimParams) Unmarshal(b []byte) error {
+	if len(b) < SimParamsSize {
+		return fmt.Errorf("SimParams.Unmarshal: need %d bytes, got %d", SimParamsSize, len(b))
+	}
+	v.unmarshalFrom(b)
+	return nil
+}
+
+func (v *SimParams) marshalTo(b []byte) {
+	binary.LittleEndian.PutUint32(b[0:], math.Float32bits(v.DeltaT))
+	binary.LittleEndian.PutUint32(b[4:], math.Float32bits(v.Rule1Distance))
+	binary.LittleEndian.PutUint32(b[8:], math.Float32bits(v.Rule2Distance))
+	binary.LittleEndian.PutUint32(b[12:], math.Float32bits(v.Rule3Distance))
+	binary.LittleEndian.PutUint32(b[16:], math.Float32bits(v.Rule1Scale))
+	binary.LittleEndian.PutUint32(b[20:], math.Float32bits(v.Rule2Scale))
+	binary.LittleEndian.PutUint32(b[24:], math.Float32bits(v.Rule3Scale))
+}
+
+func (v *SimParams) unmarshalFrom(b []byte) {
+	v.DeltaT = math.Float32frombits(binary.LittleEndian.Uint32(b[0:]))
+	v.Rule1Distance = math.Float32frombits(binary.LittleEndian.Uint32(b[4:]))
+	v.Rule2Distance = math.Float32frombits(binary.LittleEndian.Uint32(b[8:]))
+	v.Rule3Distance = math.Float32frombits(binary.LittleEndian.Uint32(b[12:]))
+	v.Rule1Scale = math.Float32frombits(binary.LittleEndian.Uint32(b[16:]))
+	v.Rule2Scale = math.Float32frombits(binary.LittleEndian.Uint32(b[20:]))
+	v.Rule3Scale = math.Float32frombits(binary.LittleEndian.Uint32(b[24:]))
+}
@@ -16,6 +16,8 @@ import (
 	_ "embed"
 )
 
+//go:generate go run github.com/openfluke/webgpu/cmd/wgslgen -i compute.wgsl -o compute_wgsl.go -pkg main
+
 var forceFallbackAdapter = os.Getenv("WGPU_FORCE_FALLBACK_ADAPTER") == "1"
 
 func init() {
@@ -130,19 +132,19 @@ func InitState(window *glfw.Window) (s *State, err error) {
 	}
 	defer drawShader.Release()
 
-	simParamData := [...]float32{
-		0.04,  // deltaT
-		0.1,   // rule1Distance
-		0.025, // rule2Distance
-		0.025, // rule3Distance
-		0.02,  // rule1Scale
-		0.05,  // rule2Scale
-		0.005, // rule3Scale
+	simParamData := SimParams{
+		DeltaT:        0.04,
+		Rule1Distance: 0.1,
+		Rule2Distance: 0.025,
+		Rule3Distance: 0.025,
+		Rule1Scale:    0.02,
+		Rule2Scale:    0.05,
+		Rule3Scale:    0.005,
 	}
 
 	simParamBuffer, err := s.device.CreateBufferInit(&wgpu.BufferInitDescriptor{
 		Label:    "Simulation Param Buffer",
-		Contents: wgpu.ToBytes(simParamData[:]),
+		Contents: simParamData.Marshal(),
 		Usage:    wgpu.BufferUsageUniform | wgpu.BufferUsageCopyDst,
 	})
 	if err != nil {
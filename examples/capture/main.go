@@ -1,10 +1,9 @@
 package main
 
 import (
-	"image"
+	"context"
 	"image/png"
 	"os"
-	"unsafe"
 
 	"github.com/openfluke/webgpu/wgpu"
 )
@@ -28,26 +27,7 @@ func init() {
 	}
 }
 
-type BufferDimensions struct {
-	width               uint64
-	height              uint64
-	unpaddedBytesPerRow uint64
-	paddedBytesPerRow   uint64
-}
-
-func newBufferDimensions(width uint64, height uint64) BufferDimensions {
-	const bytesPerPixel = unsafe.Sizeof(uint32(0))
-	unpaddedBytesPerRow := width * uint64(bytesPerPixel)
-	align := uint64(wgpu.CopyBytesPerRowAlignment)
-	paddedBytesPerRowPadding := (align - unpaddedBytesPerRow%align) % align
-	paddedBytesPerRow := unpaddedBytesPerRow + uint64(paddedBytesPerRowPadding)
-	return BufferDimensions{
-		width,
-		height,
-		unpaddedBytesPerRow,
-		paddedBytesPerRow,
-	}
-}
+const textureFormat = wgpu.TextureFormatRGBA8UnormSrgb
 
 func main() {
 	width := 100
@@ -72,32 +52,17 @@ func main() {
 	queue := device.GetQueue()
 	defer queue.Release()
 
-	bufferDimensions := newBufferDimensions(uint64(width), uint64(height))
-
-	bufferSize := bufferDimensions.paddedBytesPerRow * bufferDimensions.height
-	// The output buffer lets us retrieve the data as an array
-	outputBuffer, err := device.CreateBuffer(&wgpu.BufferDescriptor{
-		Size:  bufferSize,
-		Usage: wgpu.BufferUsageMapRead | wgpu.BufferUsageCopyDst,
-	})
-	if err != nil {
-		panic(err)
-	}
-	defer outputBuffer.Release()
-
-	textureExtent := wgpu.Extent3D{
-		Width:              uint32(bufferDimensions.width),
-		Height:             uint32(bufferDimensions.height),
-		DepthOrArrayLayers: 1,
-	}
-
 	// The render pipeline renders data into this texture
 	texture, err := device.CreateTexture(&wgpu.TextureDescriptor{
-		Size:          textureExtent,
+		Size: wgpu.Extent3D{
+			Width:              uint32(width),
+			Height:             uint32(height),
+			DepthOrArrayLayers: 1,
+		},
 		MipLevelCount: 1,
 		SampleCount:   1,
 		Dimension:     wgpu.TextureDimension2D,
-		Format:        wgpu.TextureFormatRGBA8UnormSrgb,
+		Format:        textureFormat,
 		Usage:         wgpu.TextureUsageRenderAttachment | wgpu.TextureUsageCopySrc,
 	})
 	if err != nil {
@@ -129,20 +94,6 @@ func main() {
 	defer renderPass.Release()
 	renderPass.End()
 
-	// Copy the data from the texture to the buffer
-	encoder.CopyTextureToBuffer(
-		texture.AsImageCopy(),
-		&wgpu.ImageCopyBuffer{
-			Buffer: outputBuffer,
-			Layout: wgpu.TextureDataLayout{
-				Offset:       0,
-				BytesPerRow:  uint32(bufferDimensions.paddedBytesPerRow),
-				RowsPerImage: wgpu.CopyStrideUndefined,
-			},
-		},
-		&textureExtent,
-	)
-
 	cmdBuffer, err := encoder.Finish(nil)
 	if err != nil {
 		panic(err)
@@ -151,20 +102,16 @@ func main() {
 
 	queue.Submit(cmdBuffer)
 
-	outputBuffer.MapAsync(wgpu.MapModeRead, 0, bufferSize, func(status wgpu.BufferMapAsyncStatus) {
-		if status != wgpu.BufferMapAsyncStatusSuccess {
-			panic("failed to map buffer")
-		}
-	})
-	defer outputBuffer.Unmap()
-
-	device.Poll(true, nil)
-
-	data := outputBuffer.GetMappedRange(0, uint(bufferSize))
+	// Copy the data from the texture to an image
+	img, err := texture.ReadImage(context.Background())
+	if err != nil {
+		panic(err)
+	}
 
 	// Code to print the image data on JS, which does not support os.Create:
-	// u := js.Global().Get("Uint8Array").New(len(data))
-	// js.CopyBytesToJS(u, data)
+	// pix := img.(*image.NRGBA).Pix
+	// u := js.Global().Get("Uint8Array").New(len(pix))
+	// js.CopyBytesToJS(u, pix)
 	// js.Global().Get("console").Call("log", u)
 	// return
 
@@ -176,11 +123,7 @@ func main() {
 	defer f.Close()
 
 	imageEncoder := png.Encoder{CompressionLevel: png.BestCompression}
-	err = imageEncoder.Encode(f, &image.NRGBA{
-		Pix:    data,
-		Stride: int(bufferDimensions.paddedBytesPerRow),
-		Rect:   image.Rect(0, 0, width, height),
-	})
+	err = imageEncoder.Encode(f, img)
 	if err != nil {
 		panic(err)
 	}
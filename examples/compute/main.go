@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -141,22 +142,11 @@ func main() {
 	}
 	queue.Submit(cmdBuffer)
 
-	var status wgpu.BufferMapAsyncStatus
-	err = stagingBuffer.MapAsync(wgpu.MapModeRead, 0, size, func(s wgpu.BufferMapAsyncStatus) {
-		status = s
-	})
+	data, err := stagingBuffer.Read(context.Background(), 0, size)
 	if err != nil {
 		panic(err)
 	}
-	defer stagingBuffer.Unmap()
-
-	device.Poll(true, nil)
-
-	if status != wgpu.BufferMapAsyncStatusSuccess {
-		panic(status)
-	}
-
-	steps := wgpu.FromBytes[uint32](stagingBuffer.GetMappedRange(0, uint(size)))
+	steps := wgpu.FromBytes[uint32](data)
 
 	dispSteps := mapSlice(steps, func(e uint32) string {
 		if e == OVERFLOW {
@@ -9,20 +9,29 @@ package wgpu
 
 extern void gowebgpu_request_device_callback_c(WGPURequestDeviceStatus status, WGPUDevice device, char const *message, void *userdata);
 extern void gowebgpu_device_lost_callback_c(WGPUDeviceLostReason reason, char const * message, void * userdata);
+extern void gowebgpu_uncaptured_error_callback_c(WGPUErrorType type, char const * message, void * userdata);
+
+static inline void gowebgpu_device_descriptor_set_callbacks(WGPUDeviceDescriptor * descriptor, uintptr_t id) {
+	descriptor->deviceLostCallback = gowebgpu_device_lost_callback_c;
+	descriptor->deviceLostUserdata = (void *)id;
+	descriptor->uncapturedErrorCallbackInfo.callback = gowebgpu_uncaptured_error_callback_c;
+	descriptor->uncapturedErrorCallbackInfo.userdata = (void *)id;
+}
 
 */
 import "C"
 import (
-	"errors"
 	"runtime/cgo"
 	"unsafe"
 )
 
 type Adapter struct {
-	ref C.WGPUAdapter
+	ref  C.WGPUAdapter
+	refs refCount
 }
 
 func (p *Adapter) EnumerateFeatures() []FeatureName {
+	p.refs.check("Adapter.EnumerateFeatures", "")
 	size := C.wgpuAdapterEnumerateFeatures(p.ref, nil)
 	if size == 0 {
 		return nil
@@ -34,6 +43,7 @@ func (p *Adapter) EnumerateFeatures() []FeatureName {
 }
 
 func (p *Adapter) GetLimits() SupportedLimits {
+	p.refs.check("Adapter.GetLimits", "")
 	var supportedLimits C.WGPUSupportedLimits
 
 	extras := (*C.WGPUSupportedLimitsExtras)(C.malloc(C.size_t(unsafe.Sizeof(C.WGPUSupportedLimitsExtras{}))))
@@ -83,6 +93,7 @@ func (p *Adapter) GetLimits() SupportedLimits {
 }
 
 func (p *Adapter) GetInfo() AdapterInfo {
+	p.refs.check("Adapter.GetInfo", "")
 	var info C.WGPUAdapterInfo
 
 	C.wgpuAdapterGetInfo(p.ref, &info)
@@ -100,6 +111,7 @@ func (p *Adapter) GetInfo() AdapterInfo {
 }
 
 func (p *Adapter) HasFeature(feature FeatureName) bool {
+	p.refs.check("Adapter.HasFeature", "")
 	hasFeature := C.wgpuAdapterHasFeature(p.ref, C.WGPUFeatureName(feature))
 	return goBool(hasFeature)
 }
@@ -117,18 +129,8 @@ func gowebgpu_request_device_callback_go(status C.WGPURequestDeviceStatus, devic
 	}
 }
 
-//export gowebgpu_device_lost_callback_go
-func gowebgpu_device_lost_callback_go(reason C.WGPUDeviceLostReason, message *C.char, userdata unsafe.Pointer) {
-	handle := *(*cgo.Handle)(userdata)
-	defer handle.Delete()
-
-	cb, ok := handle.Value().(DeviceLostCallback)
-	if ok {
-		cb(DeviceLostReason(reason), C.GoString(message))
-	}
-}
-
 func (p *Adapter) RequestDevice(descriptor *DeviceDescriptor) (*Device, error) {
+	p.refs.check("Adapter.RequestDevice", "")
 	var desc *C.WGPUDeviceDescriptor = nil
 
 	if descriptor != nil {
@@ -202,13 +204,6 @@ func (p *Adapter) RequestDevice(descriptor *DeviceDescriptor) (*Device, error) {
 			desc.requiredLimits.nextInChain = (*C.WGPUChainedStruct)(unsafe.Pointer(requiredLimitsExtras))
 		}
 
-		if descriptor.DeviceLostCallback != nil {
-			handle := cgo.NewHandle(descriptor.DeviceLostCallback)
-
-			desc.deviceLostCallback = C.WGPUDeviceLostCallback(C.gowebgpu_device_lost_callback_c)
-			desc.deviceLostUserdata = unsafe.Pointer(&handle)
-		}
-
 		if descriptor.TracePath != "" {
 			deviceExtras := (*C.WGPUDeviceExtras)(C.malloc(C.size_t(unsafe.Sizeof(C.WGPUDeviceExtras{}))))
 			defer C.free(unsafe.Pointer(deviceExtras))
@@ -225,23 +220,49 @@ func (p *Adapter) RequestDevice(descriptor *DeviceDescriptor) (*Device, error) {
 		}
 	}
 
+	var lostCallback DeviceLostCallback
+	if descriptor != nil {
+		lostCallback = descriptor.DeviceLostCallback
+	}
+	if desc == nil {
+		desc = &C.WGPUDeviceDescriptor{}
+	}
+	id := newDeviceState(lostCallback)
+	C.gowebgpu_device_descriptor_set_callbacks(desc, C.uintptr_t(id))
+
 	var status RequestDeviceStatus
 	var device *Device
 
-	var cb requestDeviceCb = func(s RequestDeviceStatus, d *Device, _ string) {
+	var message string
+
+	var cb requestDeviceCb = func(s RequestDeviceStatus, d *Device, m string) {
 		status = s
 		device = d
+		message = m
 	}
 	handle := cgo.NewHandle(cb)
 	C.wgpuAdapterRequestDevice(p.ref, desc, C.WGPUAdapterRequestDeviceCallback(C.gowebgpu_request_device_callback_c), unsafe.Pointer(&handle))
 
 	if status != RequestDeviceStatusSuccess {
-		return nil, errors.New("failed to request device")
+		if message == "" {
+			message = "failed to request device"
+		}
+		devicesMu.Lock()
+		delete(devices, id)
+		devicesMu.Unlock()
+		return nil, &Error{Op: "Adapter.RequestDevice", Type: ErrorTypeUnknown, Message: message}
 	}
+	device.id = id
+	if descriptor != nil {
+		device.label = descriptor.Label
+	}
+	track(device, "Device", device.label)
 
 	return device, nil
 }
 
 func (p *Adapter) Release() {
-	C.wgpuAdapterRelease(p.ref)
+	if p.refs.release("Adapter.Release", "") {
+		C.wgpuAdapterRelease(p.ref)
+	}
 }
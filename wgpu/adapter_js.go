@@ -27,15 +27,28 @@ func (g Adapter) RequestDevice(descriptor *DeviceDescriptor) (*Device, error) {
 	// Also get the queue since Device will need it
 	//queue := js.Global().Get("webgpuQueue")
 
-	return &Device{
+	d := &Device{
 		jsValue: device,
-	}, nil
+	}
+	if descriptor != nil && descriptor.DeviceLostCallback != nil {
+		s := d.state()
+		devicesMu.Lock()
+		s.lostCallback = descriptor.DeviceLostCallback
+		devicesMu.Unlock()
+	}
+	return d, nil
 }
 
 func (g Adapter) GetInfo() AdapterInfo {
 	return AdapterInfo{} // TODO(kai): implement?
 }
 
+// HasFeature as described:
+// https://gpuweb.github.io/gpuweb/#dom-gpuadapter-features
+func (g Adapter) HasFeature(feature FeatureName) bool {
+	return g.jsValue.Get("features").Call("has", feature.String()).Bool()
+}
+
 func (g Adapter) GetLimits() SupportedLimits {
 	return SupportedLimits{limitsFromJS(g.jsValue.Get("limits"))}
 }
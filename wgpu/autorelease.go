@@ -0,0 +1,99 @@
+//go:build !js
+
+package wgpu
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// autoRelease is set by the wgpu_autorelease build tag. Handles then get
+// a finalizer that queues them, once unreachable, to a goroutine that
+// drops their remaining references, so that wgpu-native is not called
+// from the finalizer goroutine and releases do not run concurrently.
+// Releasing the collected handles is logged at LogLevelDebug.
+//
+// A device stays reachable once its mipmap generator or pipeline cache
+// is created, so it still needs an explicit Release.
+var autoRelease bool
+
+// handle is implemented by every handle type.
+type handle interface {
+	Release()
+	refCounts() *refCount
+}
+
+var autoReleaser struct {
+	once  sync.Once
+	queue chan collectedHandle
+}
+
+type collectedHandle struct {
+	handle handle
+	kind   string
+}
+
+// logLevel is the level last passed to SetLogLevel.
+var logLevel atomic.Uint32
+
+// track records a handle created and labelled by its caller in the leak
+// tracker and, with auto-release, sets its finalizer.
+func track(h handle, kind, label string) {
+	h.refCounts().id = trackHandle(kind, label)
+	if !autoRelease {
+		return
+	}
+	runtime.SetFinalizer(h, func(h handle) {
+		if h.refCounts().extra.Load() < 0 {
+			return
+		}
+		autoReleaser.once.Do(func() {
+			autoReleaser.queue = make(chan collectedHandle, 64)
+			go releaseCollected(autoReleaser.queue)
+		})
+		autoReleaser.queue <- collectedHandle{handle: h, kind: kind}
+	})
+}
+
+func releaseCollected(queue <-chan collectedHandle) {
+	runtime.LockOSThread()
+	for c := range queue {
+		if LogLevel(logLevel.Load()) >= LogLevelDebug {
+			var label string
+			if l, ok := c.handle.(interface{ Label() string }); ok && l.Label() != "" {
+				label = fmt.Sprintf(" %q", l.Label())
+			}
+			fmt.Fprintf(os.Stderr, "[wgpu] [Debug] %s%s collected without Release\n", c.kind, label)
+		}
+		// The handle is unreachable, so no owner is left to drop the
+		// references it took with Reference.
+		c.handle.refCounts().extra.Store(0)
+		c.handle.Release()
+	}
+}
+
+func (p *Adapter) refCounts() *refCount             { return &p.refs }
+func (p *BindGroup) refCounts() *refCount           { return &p.refs }
+func (p *BindGroupLayout) refCounts() *refCount     { return &p.refs }
+func (p *Buffer) refCounts() *refCount              { return &p.refs }
+func (p *CommandBuffer) refCounts() *refCount       { return &p.refs }
+func (p *CommandEncoder) refCounts() *refCount      { return &p.refs }
+func (p *ComputePassEncoder) refCounts() *refCount  { return &p.refs }
+func (p *ComputePipeline) refCounts() *refCount     { return &p.refs }
+func (p *Device) refCounts() *refCount              { return &p.refs }
+func (p *Instance) refCounts() *refCount            { return &p.refs }
+func (p *PipelineLayout) refCounts() *refCount      { return &p.refs }
+func (p *QuerySet) refCounts() *refCount            { return &p.refs }
+func (p *Queue) refCounts() *refCount               { return &p.refs }
+func (p *RenderBundle) refCounts() *refCount        { return &p.refs }
+func (p *RenderBundleEncoder) refCounts() *refCount { return &p.refs }
+func (p *RenderPassEncoder) refCounts() *refCount   { return &p.refs }
+func (p *RenderPipeline) refCounts() *refCount      { return &p.refs }
+func (p *Sampler) refCounts() *refCount             { return &p.refs }
+func (p *ShaderModule) refCounts() *refCount        { return &p.refs }
+func (p *Surface) refCounts() *refCount             { return &p.refs }
+func (p *Texture) refCounts() *refCount             { return &p.refs }
+func (p *TextureView) refCounts() *refCount         { return &p.refs }
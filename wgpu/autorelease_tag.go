@@ -0,0 +1,7 @@
+//go:build !js && wgpu_autorelease
+
+package wgpu
+
+func init() {
+	autoRelease = true
+}
@@ -87,6 +87,12 @@ type BindGroupLayoutEntry struct {
 	ExternalTexture ExternalTextureBindingLayout
 }
 
+// setExternalTextureLayout marks entry as a texture_external binding.
+func setExternalTextureLayout(entry *BindGroupLayoutEntry) error {
+	entry.ExternalTexture = ExternalTextureBindingLayout{jsValue: js.ValueOf(map[string]any{})}
+	return nil
+}
+
 func (g BindGroupLayoutEntry) toJS() any {
 	result := make(map[string]any)
 	result["binding"] = g.Binding
@@ -108,6 +114,7 @@ func (g BindGroupLayoutEntry) toJS() any {
 
 func (g BindGroupLayoutDescriptor) toJS() any {
 	return map[string]any{
+		"label": g.Label,
 		"entries": mapSlice(g.Entries, func(entry BindGroupLayoutEntry) any {
 			return entry.toJS()
 		}),
@@ -146,6 +153,7 @@ func (g BindGroupEntry) toJS() any {
 
 func (g BindGroupDescriptor) toJS() any {
 	return map[string]any{
+		"label":  g.Label,
 		"layout": pointerToJS(g.Layout),
 		"entries": mapSlice(g.Entries, func(entry BindGroupEntry) any {
 			return entry.toJS()
@@ -7,19 +7,20 @@ package wgpu
 #include <stdlib.h>
 #include "./lib/wgpu.h"
 
-extern void gowebgpu_error_callback_c(WGPUErrorType type, char const * message, void * userdata);
+extern void gowebgpu_push_error_scopes(WGPUDevice device);
+extern void gowebgpu_pop_error_scopes(WGPUDevice device, void * userdata);
 extern void gowebgpu_buffer_map_callback_c(WGPUBufferMapAsyncStatus status, void *userdata);
 
 static inline void gowebgpu_buffer_map_async(WGPUBuffer buffer, WGPUMapModeFlags mode, size_t offset, size_t size, WGPUBufferMapAsyncCallback callback, void * userdata, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuBufferMapAsync(buffer, mode, offset, size, callback, userdata);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_buffer_unmap(WGPUBuffer buffer, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuBufferUnmap(buffer);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_buffer_release(WGPUBuffer buffer, WGPUDevice device) {
@@ -30,7 +31,6 @@ static inline void gowebgpu_buffer_release(WGPUBuffer buffer, WGPUDevice device)
 */
 import "C"
 import (
-	"errors"
 	"runtime/cgo"
 	"unsafe"
 )
@@ -38,22 +38,30 @@ import (
 type Buffer struct {
 	deviceRef C.WGPUDevice
 	ref       C.WGPUBuffer
+	label     string
+	refs      refCount
+
+	device *Device
 }
 
 func (p *Buffer) Destroy() {
+	p.refs.check("Buffer.Destroy", p.label)
 	C.wgpuBufferDestroy(p.ref)
 }
 
 func (p *Buffer) GetMappedRange(offset, size uint) []byte {
+	p.refs.check("Buffer.GetMappedRange", p.label)
 	buf := C.wgpuBufferGetMappedRange(p.ref, C.size_t(offset), C.size_t(size))
 	return unsafe.Slice((*byte)(buf), size)
 }
 
 func (p *Buffer) GetSize() uint64 {
+	p.refs.check("Buffer.GetSize", p.label)
 	return uint64(C.wgpuBufferGetSize(p.ref))
 }
 
 func (p *Buffer) GetUsage() BufferUsage {
+	p.refs.check("Buffer.GetUsage", p.label)
 	return BufferUsage(C.wgpuBufferGetUsage(p.ref))
 }
 
@@ -69,11 +77,10 @@ func gowebgpu_buffer_map_callback_go(status C.WGPUBufferMapAsyncStatus, userdata
 }
 
 func (p *Buffer) MapAsync(mode MapMode, offset uint64, size uint64, callback BufferMapCallback) (err error) {
+	p.refs.check("Buffer.MapAsync", p.label)
 	callbackHandle := cgo.NewHandle(callback)
 
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Buffer).MapAsync(): " + message)
-	}
+	cb := newErrorCallback(&err, "Buffer.MapAsync", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -91,9 +98,8 @@ func (p *Buffer) MapAsync(mode MapMode, offset uint64, size uint64, callback Buf
 }
 
 func (p *Buffer) Unmap() (err error) {
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Buffer).Unmap(): " + message)
-	}
+	p.refs.check("Buffer.Unmap", p.label)
+	cb := newErrorCallback(&err, "Buffer.Unmap", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -106,5 +112,7 @@ func (p *Buffer) Unmap() (err error) {
 }
 
 func (p *Buffer) Release() {
-	C.gowebgpu_buffer_release(p.ref, p.deviceRef)
+	if p.refs.release("Buffer.Release", p.label) {
+		C.gowebgpu_buffer_release(p.ref, p.deviceRef)
+	}
 }
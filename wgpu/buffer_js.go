@@ -10,6 +10,8 @@ import (
 // https://gpuweb.github.io/gpuweb/#gpubuffer
 type Buffer struct {
 	jsValue js.Value
+
+	device *Device
 }
 
 func (g Buffer) toJS() any {
@@ -36,24 +38,46 @@ func (g Buffer) GetMappedRange(offset, size uint) []byte {
 func (g Buffer) MapAsync(mode MapMode, offset uint64, size uint64, callback BufferMapCallback) (err error) {
 	promise := g.jsValue.Call("mapAsync", uint32(mode), offset, size)
 
+	var successCallback, errorCallback js.Func
+	release := func() {
+		successCallback.Release()
+		errorCallback.Release()
+	}
+
 	// Set up success handler
-	successCallback := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+	successCallback = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		release()
 		callback(BufferMapAsyncStatusSuccess)
 		return nil
 	})
 
 	// Set up error handler
-	errorCallback := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		callback(BufferMapAsyncStatusSuccess) // Use Success as fallback since Error doesn't exist
+	errorCallback = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		release()
+		callback(mapStatusFromJS(args[0]))
 		return nil
 	})
 
 	// Handle the promise
-	promise.Call("then", successCallback).Call("catch", errorCallback)
+	promise.Call("then", successCallback, errorCallback)
 
 	return nil
 }
 
+// mapStatusFromJS converts the DOMException a mapAsync promise rejects
+// with to a BufferMapAsyncStatus.
+func mapStatusFromJS(reason js.Value) BufferMapAsyncStatus {
+	switch reason.Get("name").String() {
+	case "AbortError":
+		return BufferMapAsyncStatusUnmappedBeforeCallback
+	case "OperationError":
+		return BufferMapAsyncStatusValidationError
+	case "RangeError":
+		return BufferMapAsyncStatusOffsetOutOfRange
+	}
+	return BufferMapAsyncStatusUnknown
+}
+
 func (g Buffer) Unmap() (err error) {
 	g.jsValue.Call("unmap")
 	return
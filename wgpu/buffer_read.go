@@ -0,0 +1,79 @@
+package wgpu
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// MapError is returned when mapping a buffer fails. It matches
+// ErrDeviceLost when Status is BufferMapAsyncStatusDeviceLost.
+type MapError struct {
+	Op     string
+	Label  string
+	Status BufferMapAsyncStatus
+}
+
+func (e *MapError) Error() string {
+	return (&Error{Op: e.Op, Label: e.Label, Message: "mapping failed: " + e.Status.String()}).Error()
+}
+
+func (e *MapError) Is(target error) bool {
+	return target == ErrDeviceLost && e.Status == BufferMapAsyncStatusDeviceLost
+}
+
+// Read maps size bytes of the buffer at offset for reading, waits for
+// the mapping, copies the bytes out and unmaps the buffer. The buffer
+// needs BufferUsageMapRead and must not be mapped already. Offset and
+// size need not be aligned.
+//
+// Read returns ctx.Err() if ctx is done first, a *MapError if mapping
+// fails and an error matching ErrDeviceLost if the device is lost.
+func (p *Buffer) Read(ctx context.Context, offset, size uint64) ([]byte, error) {
+	dst := make([]byte, size)
+	if err := p.read(ctx, "Buffer.Read", offset, dst); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// ReadInto is like Read, but reads len(dst) bytes from the start of the
+// buffer into dst.
+func (p *Buffer) ReadInto(ctx context.Context, dst []byte) error {
+	return p.read(ctx, "Buffer.ReadInto", 0, dst)
+}
+
+func (p *Buffer) read(ctx context.Context, op string, offset uint64, dst []byte) error {
+	if len(dst) == 0 {
+		return nil
+	}
+	end := offset + uint64(len(dst))
+	if end > p.GetSize() {
+		return &MapError{Op: op, Label: p.Label(), Status: BufferMapAsyncStatusSizeOutOfRange}
+	}
+
+	// MapAsync needs an 8-byte aligned offset and a 4-byte aligned size.
+	mapStart := offset &^ (MapAlignment - 1)
+	mapEnd := min((end+CopyBufferAlignment-1)&^(CopyBufferAlignment-1), p.GetSize())
+
+	var status BufferMapAsyncStatus
+	var done atomic.Bool
+	err := p.MapAsync(MapModeRead, mapStart, mapEnd-mapStart, func(s BufferMapAsyncStatus) {
+		status = s
+		done.Store(true)
+	})
+	if err != nil {
+		return err
+	}
+	if err := p.device.PollUntil(ctx, done.Load); err != nil {
+		// Unmapping cancels the pending mapping.
+		p.Unmap()
+		return err
+	}
+	if status != BufferMapAsyncStatusSuccess {
+		return &MapError{Op: op, Label: p.Label(), Status: status}
+	}
+
+	mapped := p.GetMappedRange(uint(mapStart), uint(mapEnd-mapStart))
+	copy(dst, mapped[offset-mapStart:])
+	return p.Unmap()
+}
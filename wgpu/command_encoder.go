@@ -7,74 +7,75 @@ package wgpu
 #include <stdlib.h>
 #include "./lib/wgpu.h"
 
-extern void gowebgpu_error_callback_c(WGPUErrorType type, char const * message, void * userdata);
+extern void gowebgpu_push_error_scopes(WGPUDevice device);
+extern void gowebgpu_pop_error_scopes(WGPUDevice device, void * userdata);
 
 static inline void gowebgpu_command_encoder_clear_buffer(WGPUCommandEncoder commandEncoder, WGPUBuffer buffer, uint64_t offset, uint64_t size, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuCommandEncoderClearBuffer(commandEncoder, buffer, offset, size);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_command_encoder_copy_buffer_to_buffer(WGPUCommandEncoder commandEncoder, WGPUBuffer source, uint64_t sourceOffset, WGPUBuffer destination, uint64_t destinationOffset, uint64_t size, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuCommandEncoderCopyBufferToBuffer(commandEncoder, source, sourceOffset, destination, destinationOffset, size);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_command_encoder_copy_buffer_to_texture(WGPUCommandEncoder commandEncoder, WGPUImageCopyBuffer const * source, WGPUImageCopyTexture const * destination, WGPUExtent3D const * copySize, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuCommandEncoderCopyBufferToTexture(commandEncoder, source, destination, copySize);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_command_encoder_copy_texture_to_buffer(WGPUCommandEncoder commandEncoder, WGPUImageCopyTexture const * source, WGPUImageCopyBuffer const * destination, WGPUExtent3D const * copySize, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuCommandEncoderCopyTextureToBuffer(commandEncoder, source, destination, copySize);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_command_encoder_copy_texture_to_texture(WGPUCommandEncoder commandEncoder, WGPUImageCopyTexture const * source, WGPUImageCopyTexture const * destination, WGPUExtent3D const * copySize, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuCommandEncoderCopyTextureToTexture(commandEncoder, source, destination, copySize);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline WGPUCommandBuffer gowebgpu_command_encoder_finish(WGPUCommandEncoder commandEncoder, WGPUCommandBufferDescriptor const * descriptor, WGPUDevice device, void * error_userdata) {
 	WGPUCommandBuffer ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuCommandEncoderFinish(commandEncoder, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref;
 }
 
 static inline void gowebgpu_command_encoder_insert_debug_marker(WGPUCommandEncoder commandEncoder, char const * markerLabel, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuCommandEncoderInsertDebugMarker(commandEncoder, markerLabel);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_command_encoder_pop_debug_group(WGPUCommandEncoder commandEncoder, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuCommandEncoderPopDebugGroup(commandEncoder);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_command_encoder_push_debug_group(WGPUCommandEncoder commandEncoder, char const * groupLabel, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuCommandEncoderPushDebugGroup(commandEncoder, groupLabel);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_command_encoder_resolve_query_set(WGPUCommandEncoder commandEncoder, WGPUQuerySet querySet, uint32_t firstQuery, uint32_t queryCount, WGPUBuffer destination, uint64_t destinationOffset, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuCommandEncoderResolveQuerySet(commandEncoder, querySet, firstQuery, queryCount, destination, destinationOffset);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_command_encoder_write_timestamp(WGPUCommandEncoder commandEncoder, WGPUQuerySet querySet, uint32_t queryIndex, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuCommandEncoderWriteTimestamp(commandEncoder, querySet, queryIndex);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_command_encoder_release(WGPUCommandEncoder commandEncoder, WGPUDevice device) {
@@ -85,7 +86,6 @@ static inline void gowebgpu_command_encoder_release(WGPUCommandEncoder commandEn
 */
 import "C"
 import (
-	"errors"
 	"runtime/cgo"
 	"unsafe"
 )
@@ -93,6 +93,8 @@ import (
 type CommandEncoder struct {
 	deviceRef C.WGPUDevice
 	ref       C.WGPUCommandEncoder
+	label     string
+	refs      refCount
 }
 
 type ComputePassDescriptor struct {
@@ -103,6 +105,7 @@ type ComputePassDescriptor struct {
 }
 
 func (p *CommandEncoder) BeginComputePass(descriptor *ComputePassDescriptor) *ComputePassEncoder {
+	p.refs.check("CommandEncoder.BeginComputePass", p.label)
 	var desc *C.WGPUComputePassDescriptor
 
 	if descriptor != nil && descriptor.Label != "" {
@@ -120,10 +123,16 @@ func (p *CommandEncoder) BeginComputePass(descriptor *ComputePassDescriptor) *Co
 	}
 
 	C.wgpuDeviceReference(p.deviceRef)
-	return &ComputePassEncoder{deviceRef: p.deviceRef, ref: ref}
+	pass := &ComputePassEncoder{deviceRef: p.deviceRef, ref: ref}
+	if descriptor != nil {
+		pass.label = descriptor.Label
+	}
+	track(pass, "ComputePassEncoder", pass.label)
+	return pass
 }
 
 func (p *CommandEncoder) BeginRenderPass(descriptor *RenderPassDescriptor) *RenderPassEncoder {
+	p.refs.check("CommandEncoder.BeginRenderPass", p.label)
 	var desc C.WGPURenderPassDescriptor
 
 	if descriptor != nil {
@@ -154,9 +163,11 @@ func (p *CommandEncoder) BeginRenderPass(descriptor *RenderPassDescriptor) *Rend
 					},
 				}
 				if v.View != nil {
+					v.View.refs.check("CommandEncoder.BeginRenderPass", v.View.label)
 					colorAttachment.view = v.View.ref
 				}
 				if v.ResolveTarget != nil {
+					v.ResolveTarget.refs.check("CommandEncoder.BeginRenderPass", v.ResolveTarget.label)
 					colorAttachment.resolveTarget = v.ResolveTarget.ref
 				}
 
@@ -172,6 +183,7 @@ func (p *CommandEncoder) BeginRenderPass(descriptor *RenderPassDescriptor) *Rend
 			defer C.free(unsafe.Pointer(depthStencilAttachment))
 
 			if descriptor.DepthStencilAttachment.View != nil {
+				descriptor.DepthStencilAttachment.View.refs.check("CommandEncoder.BeginRenderPass", descriptor.DepthStencilAttachment.View.label)
 				depthStencilAttachment.view = descriptor.DepthStencilAttachment.View.ref
 			}
 			depthStencilAttachment.depthLoadOp = C.WGPULoadOp(descriptor.DepthStencilAttachment.DepthLoadOp)
@@ -189,13 +201,18 @@ func (p *CommandEncoder) BeginRenderPass(descriptor *RenderPassDescriptor) *Rend
 
 	ref := C.wgpuCommandEncoderBeginRenderPass(p.ref, &desc)
 	C.wgpuDeviceReference(p.deviceRef)
-	return &RenderPassEncoder{deviceRef: p.deviceRef, ref: ref}
+	pass := &RenderPassEncoder{deviceRef: p.deviceRef, ref: ref}
+	if descriptor != nil {
+		pass.label = descriptor.Label
+	}
+	track(pass, "RenderPassEncoder", pass.label)
+	return pass
 }
 
 func (p *CommandEncoder) ClearBuffer(buffer *Buffer, offset uint64, size uint64) (err error) {
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*CommandEncoder).ClearBuffer(): " + message)
-	}
+	p.refs.check("CommandEncoder.ClearBuffer", p.label)
+	buffer.refs.check("CommandEncoder.ClearBuffer", buffer.label)
+	cb := newErrorCallback(&err, "CommandEncoder.ClearBuffer", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -211,9 +228,10 @@ func (p *CommandEncoder) ClearBuffer(buffer *Buffer, offset uint64, size uint64)
 }
 
 func (p *CommandEncoder) CopyBufferToBuffer(source *Buffer, sourceOffset uint64, destination *Buffer, destinatonOffset uint64, size uint64) (err error) {
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*CommandEncoder).CopyBufferToBuffer(): " + message)
-	}
+	p.refs.check("CommandEncoder.CopyBufferToBuffer", p.label)
+	source.refs.check("CommandEncoder.CopyBufferToBuffer", source.label)
+	destination.refs.check("CommandEncoder.CopyBufferToBuffer", destination.label)
+	cb := newErrorCallback(&err, "CommandEncoder.CopyBufferToBuffer", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -231,9 +249,11 @@ func (p *CommandEncoder) CopyBufferToBuffer(source *Buffer, sourceOffset uint64,
 }
 
 func (p *CommandEncoder) CopyBufferToTexture(source *ImageCopyBuffer, destination *ImageCopyTexture, copySize *Extent3D) (err error) {
+	p.refs.check("CommandEncoder.CopyBufferToTexture", p.label)
 	var src C.WGPUImageCopyBuffer
 	if source != nil {
 		if source.Buffer != nil {
+			source.Buffer.refs.check("CommandEncoder.CopyBufferToTexture", source.Buffer.label)
 			src.buffer = source.Buffer.ref
 		}
 		src.layout = C.WGPUTextureDataLayout{
@@ -255,6 +275,7 @@ func (p *CommandEncoder) CopyBufferToTexture(source *ImageCopyBuffer, destinatio
 			aspect: C.WGPUTextureAspect(destination.Aspect),
 		}
 		if destination.Texture != nil {
+			destination.Texture.refs.check("CommandEncoder.CopyBufferToTexture", destination.Texture.label)
 			dst.texture = destination.Texture.ref
 		}
 	}
@@ -268,9 +289,7 @@ func (p *CommandEncoder) CopyBufferToTexture(source *ImageCopyBuffer, destinatio
 		}
 	}
 
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*CommandEncoder).CopyBufferToTexture(): " + message)
-	}
+	cb := newErrorCallback(&err, "CommandEncoder.CopyBufferToTexture", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -286,6 +305,7 @@ func (p *CommandEncoder) CopyBufferToTexture(source *ImageCopyBuffer, destinatio
 }
 
 func (p *CommandEncoder) CopyTextureToBuffer(source *ImageCopyTexture, destination *ImageCopyBuffer, copySize *Extent3D) (err error) {
+	p.refs.check("CommandEncoder.CopyTextureToBuffer", p.label)
 	var src C.WGPUImageCopyTexture
 	if source != nil {
 		src = C.WGPUImageCopyTexture{
@@ -298,6 +318,7 @@ func (p *CommandEncoder) CopyTextureToBuffer(source *ImageCopyTexture, destinati
 			aspect: C.WGPUTextureAspect(source.Aspect),
 		}
 		if source.Texture != nil {
+			source.Texture.refs.check("CommandEncoder.CopyTextureToBuffer", source.Texture.label)
 			src.texture = source.Texture.ref
 		}
 	}
@@ -305,6 +326,7 @@ func (p *CommandEncoder) CopyTextureToBuffer(source *ImageCopyTexture, destinati
 	var dst C.WGPUImageCopyBuffer
 	if destination != nil {
 		if destination.Buffer != nil {
+			destination.Buffer.refs.check("CommandEncoder.CopyTextureToBuffer", destination.Buffer.label)
 			dst.buffer = destination.Buffer.ref
 		}
 		dst.layout = C.WGPUTextureDataLayout{
@@ -323,9 +345,7 @@ func (p *CommandEncoder) CopyTextureToBuffer(source *ImageCopyTexture, destinati
 		}
 	}
 
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*CommandEncoder).CopyTextureToBuffer(): " + message)
-	}
+	cb := newErrorCallback(&err, "CommandEncoder.CopyTextureToBuffer", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -341,6 +361,7 @@ func (p *CommandEncoder) CopyTextureToBuffer(source *ImageCopyTexture, destinati
 }
 
 func (p *CommandEncoder) CopyTextureToTexture(source *ImageCopyTexture, destination *ImageCopyTexture, copySize *Extent3D) (err error) {
+	p.refs.check("CommandEncoder.CopyTextureToTexture", p.label)
 	var src C.WGPUImageCopyTexture
 	if source != nil {
 		src = C.WGPUImageCopyTexture{
@@ -353,6 +374,7 @@ func (p *CommandEncoder) CopyTextureToTexture(source *ImageCopyTexture, destinat
 			aspect: C.WGPUTextureAspect(source.Aspect),
 		}
 		if source.Texture != nil {
+			source.Texture.refs.check("CommandEncoder.CopyTextureToTexture", source.Texture.label)
 			src.texture = source.Texture.ref
 		}
 	}
@@ -369,6 +391,7 @@ func (p *CommandEncoder) CopyTextureToTexture(source *ImageCopyTexture, destinat
 			aspect: C.WGPUTextureAspect(destination.Aspect),
 		}
 		if destination.Texture != nil {
+			destination.Texture.refs.check("CommandEncoder.CopyTextureToTexture", destination.Texture.label)
 			dst.texture = destination.Texture.ref
 		}
 	}
@@ -382,9 +405,7 @@ func (p *CommandEncoder) CopyTextureToTexture(source *ImageCopyTexture, destinat
 		}
 	}
 
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*CommandEncoder).CopyTextureToTexture(): " + message)
-	}
+	cb := newErrorCallback(&err, "CommandEncoder.CopyTextureToTexture", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -400,6 +421,7 @@ func (p *CommandEncoder) CopyTextureToTexture(source *ImageCopyTexture, destinat
 }
 
 func (p *CommandEncoder) Finish(descriptor *CommandBufferDescriptor) (*CommandBuffer, error) {
+	p.refs.check("CommandEncoder.Finish", p.label)
 	var desc *C.WGPUCommandBufferDescriptor
 
 	if descriptor != nil && descriptor.Label != "" {
@@ -412,9 +434,11 @@ func (p *CommandEncoder) Finish(descriptor *CommandBufferDescriptor) (*CommandBu
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*CommandEncoder).Finish(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, "CommandEncoder.Finish", label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -429,16 +453,17 @@ func (p *CommandEncoder) Finish(descriptor *CommandBufferDescriptor) (*CommandBu
 		return nil, err
 	}
 
-	return &CommandBuffer{ref}, nil
+	buffer := &CommandBuffer{ref: ref, label: label}
+	track(buffer, "CommandBuffer", label)
+	return buffer, nil
 }
 
 func (p *CommandEncoder) InsertDebugMarker(markerLabel string) (err error) {
+	p.refs.check("CommandEncoder.InsertDebugMarker", p.label)
 	markerLabelStr := C.CString(markerLabel)
 	defer C.free(unsafe.Pointer(markerLabelStr))
 
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*CommandEncoder).InsertDebugMarker(): " + message)
-	}
+	cb := newErrorCallback(&err, "CommandEncoder.InsertDebugMarker", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -452,9 +477,8 @@ func (p *CommandEncoder) InsertDebugMarker(markerLabel string) (err error) {
 }
 
 func (p *CommandEncoder) PopDebugGroup() (err error) {
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*CommandEncoder).PopDebugGroup(): " + message)
-	}
+	p.refs.check("CommandEncoder.PopDebugGroup", p.label)
+	cb := newErrorCallback(&err, "CommandEncoder.PopDebugGroup", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -467,12 +491,11 @@ func (p *CommandEncoder) PopDebugGroup() (err error) {
 }
 
 func (p *CommandEncoder) PushDebugGroup(groupLabel string) (err error) {
+	p.refs.check("CommandEncoder.PushDebugGroup", p.label)
 	groupLabelStr := C.CString(groupLabel)
 	defer C.free(unsafe.Pointer(groupLabelStr))
 
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*CommandEncoder).PushDebugGroup(): " + message)
-	}
+	cb := newErrorCallback(&err, "CommandEncoder.PushDebugGroup", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -486,9 +509,10 @@ func (p *CommandEncoder) PushDebugGroup(groupLabel string) (err error) {
 }
 
 func (p *CommandEncoder) ResolveQuerySet(querySet *QuerySet, firstQuery uint32, queryCount uint32, destination *Buffer, destinationOffset uint64) (err error) {
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*CommandEncoder).ResolveQuerySet(): " + message)
-	}
+	p.refs.check("CommandEncoder.ResolveQuerySet", p.label)
+	querySet.refs.check("CommandEncoder.ResolveQuerySet", querySet.label)
+	destination.refs.check("CommandEncoder.ResolveQuerySet", destination.label)
+	cb := newErrorCallback(&err, "CommandEncoder.ResolveQuerySet", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -506,9 +530,9 @@ func (p *CommandEncoder) ResolveQuerySet(querySet *QuerySet, firstQuery uint32,
 }
 
 func (p *CommandEncoder) WriteTimestamp(querySet *QuerySet, queryIndex uint32) (err error) {
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*CommandEncoder).WriteTimestamp(): " + message)
-	}
+	p.refs.check("CommandEncoder.WriteTimestamp", p.label)
+	querySet.refs.check("CommandEncoder.WriteTimestamp", querySet.label)
+	cb := newErrorCallback(&err, "CommandEncoder.WriteTimestamp", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -523,5 +547,7 @@ func (p *CommandEncoder) WriteTimestamp(querySet *QuerySet, queryIndex uint32) (
 }
 
 func (p *CommandEncoder) Release() {
-	C.gowebgpu_command_encoder_release(p.ref, p.deviceRef)
+	if p.refs.release("CommandEncoder.Release", p.label) {
+		C.gowebgpu_command_encoder_release(p.ref, p.deviceRef)
+	}
 }
@@ -143,6 +143,9 @@ type DeviceDescriptor struct {
 	TracePath          string
 }
 
+// DeviceLostCallback is called once when the device is lost or
+// released. Natively it runs on its own goroutine when wgpu-native
+// reports the loss, and during Device.Release otherwise.
 type DeviceLostCallback func(reason DeviceLostReason, message string)
 
 // TextureDescriptor as described:
@@ -309,4 +312,13 @@ type BindGroupEntry struct {
 type ProgrammableStageDescriptor struct {
 	Module     *ShaderModule
 	EntryPoint string
+	Constants  []ConstantEntry
+}
+
+// ConstantEntry sets a pipeline-overridable constant as described:
+// https://gpuweb.github.io/gpuweb/#dom-gpuprogrammablestage-constants
+// Key is the name of the override declaration, or its @id in decimal.
+type ConstantEntry struct {
+	Key   string
+	Value float64
 }
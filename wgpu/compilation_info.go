@@ -0,0 +1,172 @@
+package wgpu
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/openfluke/webgpu/wgsl"
+)
+
+// CompilationMessage as described:
+// https://gpuweb.github.io/gpuweb/#gpucompilationmessage
+// Unlike in WebGPU, LinePos, Offset and Length count bytes of the
+// UTF-8 source rather than UTF-16 code units. LineNum and LinePos are
+// 1-based and zero if the message has no position.
+type CompilationMessage struct {
+	Message string
+	Type    CompilationMessageType
+	LineNum uint64
+	LinePos uint64
+	Offset  uint64
+	Length  uint64
+}
+
+// CompilationInfo as described:
+// https://gpuweb.github.io/gpuweb/#gpucompilationinfo
+type CompilationInfo struct {
+	Messages []CompilationMessage
+}
+
+// CompilationError is the error Device.CreateShaderModule returns when
+// WGSL source fails to compile, natively and on the web. It wraps the
+// *Error and holds its messages, to be formatted with Info.Format.
+type CompilationError struct {
+	Err    *Error
+	Source string
+	Info   CompilationInfo
+}
+
+func (e *CompilationError) Error() string { return e.Err.Error() }
+
+func (e *CompilationError) Unwrap() error { return e.Err }
+
+// Format formats the messages with Format, one after the other.
+func (info *CompilationInfo) Format(name, source string) string {
+	var b strings.Builder
+	for i := range info.Messages {
+		b.WriteString(info.Messages[i].Format(name, source))
+	}
+	return b.String()
+}
+
+// Format formats the message like a compiler does, followed by the
+// source line it points at with carets under its span:
+//
+//	shader.wgsl:3:9: error: expected ';', found '}'
+//	  3 |     let x = 1
+//	    |             ^
+//
+// name is the name of the source file; messages without a position
+// print only the first line.
+func (m *CompilationMessage) Format(name, source string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	if m.LineNum > 0 {
+		b.WriteString(":" + strconv.FormatUint(m.LineNum, 10) + ":" + strconv.FormatUint(m.LinePos, 10))
+	}
+	b.WriteString(": " + m.Type.String() + ": " + m.Message + "\n")
+	if m.LineNum == 0 || m.Offset > uint64(len(source)) {
+		return b.String()
+	}
+
+	start := strings.LastIndexByte(source[:m.Offset], '\n') + 1
+	end := strings.IndexByte(source[start:], '\n')
+	if end < 0 {
+		end = len(source)
+	} else {
+		end += start
+	}
+	line := strings.TrimSuffix(source[start:end], "\r")
+	gutter := strconv.FormatUint(m.LineNum, 10)
+	b.WriteString(" " + gutter + " | " + line + "\n")
+
+	// Keep the tabs before the span so the carets line up.
+	b.WriteString(" " + strings.Repeat(" ", len(gutter)) + " | ")
+	for _, r := range source[start:m.Offset] {
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	spanEnd := min(m.Offset+m.Length, uint64(start+len(line)))
+	b.WriteString(strings.Repeat("^", max(utf8.RuneCountInString(source[m.Offset:max(spanEnd, m.Offset)]), 1)) + "\n")
+	return b.String()
+}
+
+var (
+	// nagaSpan matches the location naga prints under an error, such as
+	// "┌─ wgsl:3:9", and nagaCarets the carets under the span.
+	nagaSpan   = regexp.MustCompile(`┌─ [^:\n]*:(\d+):(\d+)`)
+	nagaCarets = regexp.MustCompile(`│\s*(\^+)`)
+	nagaError  = regexp.MustCompile(`(?:parsing|validation) error: *(.*)`)
+)
+
+// newCompilationError returns a *CompilationError for the error wgpu
+// reported for source. wgpu-native does not implement
+// wgpuShaderModuleGetCompilationInfo, so the message is recovered from
+// naga's report, or from the wgsl package's parser if the report has no
+// position.
+func newCompilationError(err *Error, source string) *CompilationError {
+	m := CompilationMessage{Type: CompilationMessageTypeError, Message: nagaMessage(err.Message)}
+
+	if match := nagaSpan.FindStringSubmatchIndex(err.Message); match != nil {
+		line, _ := strconv.Atoi(err.Message[match[2]:match[3]])
+		column, _ := strconv.Atoi(err.Message[match[4]:match[5]])
+		// naga counts columns in characters.
+		if offset, ok := sourceOffset(source, line, column); ok {
+			m.LineNum, m.Offset = uint64(line), uint64(offset)
+			m.LinePos = uint64(offset - strings.LastIndexByte(source[:offset], '\n'))
+			if carets := nagaCarets.FindStringSubmatch(err.Message[match[1]:]); carets != nil {
+				m.Length = uint64(runeBytes(source[offset:], len(carets[1])))
+			}
+		}
+	} else {
+		_, parseErr := wgsl.Parse(source)
+		var syntaxErr *wgsl.Error
+		if errors.As(parseErr, &syntaxErr) && syntaxErr.Pos.IsValid() {
+			m.Message = syntaxErr.Msg
+			m.LineNum, m.LinePos, m.Offset = uint64(syntaxErr.Pos.Line), uint64(syntaxErr.Pos.Column), uint64(syntaxErr.Pos.Offset)
+		}
+	}
+	return &CompilationError{Err: err, Source: source, Info: CompilationInfo{Messages: []CompilationMessage{m}}}
+}
+
+// nagaMessage extracts the summary from a naga error report: the text
+// after "parsing error:" or "validation error:", or else the last line.
+func nagaMessage(report string) string {
+	if match := nagaError.FindStringSubmatch(report); match != nil && strings.TrimSpace(match[1]) != "" {
+		return strings.TrimSpace(match[1])
+	}
+	lines := strings.Split(strings.TrimSpace(report), "\n")
+	return strings.TrimPrefix(strings.TrimSpace(lines[len(lines)-1]), "= ")
+}
+
+// sourceOffset returns the byte offset of the 1-based line and character
+// column in source.
+func sourceOffset(source string, line, column int) (int, bool) {
+	offset := 0
+	for ; line > 1; line-- {
+		i := strings.IndexByte(source[offset:], '\n')
+		if i < 0 {
+			return 0, false
+		}
+		offset += i + 1
+	}
+	offset += runeBytes(source[offset:], column-1)
+	return offset, offset <= len(source)
+}
+
+// runeBytes returns the number of bytes of the first n characters of s,
+// stopping at the end of the line.
+func runeBytes(s string, n int) int {
+	i := 0
+	for ; n > 0 && i < len(s) && s[i] != '\n'; n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
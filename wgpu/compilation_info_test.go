@@ -0,0 +1,145 @@
+package wgpu
+
+import "testing"
+
+func TestSourceOffset(t *testing.T) {
+	tests := []struct {
+		source       string
+		line, column int
+		want         int
+		ok           bool
+	}{
+		{"ab\ncd\n", 1, 1, 0, true},
+		{"ab\ncd\n", 2, 2, 4, true},
+		{"ab\ncd\n", 3, 1, 6, true},
+		{"ab\ncd\n", 4, 1, 0, false},
+		// Columns count characters, not bytes.
+		{"é x", 1, 3, 3, true},
+		{"a\n日本語", 2, 3, 8, true},
+		{"\tx", 1, 2, 1, true},
+		// Columns past the end of the line stop at it.
+		{"ab\ncd", 1, 10, 2, true},
+		{"ab", 1, 3, 2, true},
+	}
+	for _, test := range tests {
+		got, ok := sourceOffset(test.source, test.line, test.column)
+		if got != test.want || ok != test.ok {
+			t.Errorf("sourceOffset(%q, %d, %d) = %d, %v, want %d, %v", test.source, test.line, test.column, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestCompilationMessageFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		message CompilationMessage
+		want    string
+	}{
+		{
+			"no position",
+			"fn f() {}\n",
+			CompilationMessage{Message: "boom", Type: CompilationMessageTypeError},
+			"s.wgsl: error: boom\n",
+		},
+		{
+			"span",
+			"fn f() {\n    let x = 1\n}\n",
+			CompilationMessage{Message: "unused", Type: CompilationMessageTypeWarning, LineNum: 2, LinePos: 9, Offset: 17, Length: 1},
+			"s.wgsl:2:9: warning: unused\n" +
+				" 2 |     let x = 1\n" +
+				"   |         ^\n",
+		},
+		{
+			"tabs",
+			"fn f() {\n\tlet x = 1\n}\n",
+			CompilationMessage{Message: "bad", Type: CompilationMessageTypeError, LineNum: 2, LinePos: 10, Offset: 18, Length: 1},
+			"s.wgsl:2:10: error: bad\n" +
+				" 2 | \tlet x = 1\n" +
+				"   | \t        ^\n",
+		},
+		{
+			"multi-byte",
+			"let s = \"héllo\";",
+			CompilationMessage{Message: "string", Type: CompilationMessageTypeError, LineNum: 1, LinePos: 10, Offset: 9, Length: 6},
+			"s.wgsl:1:10: error: string\n" +
+				" 1 | let s = \"héllo\";\n" +
+				"   |          ^^^^^\n",
+		},
+		{
+			"end of line",
+			"let x = 1\r\nlet y = 2\n",
+			CompilationMessage{Message: "expected ';'", Type: CompilationMessageTypeError, LineNum: 1, LinePos: 10, Offset: 9, Length: 2},
+			"s.wgsl:1:10: error: expected ';'\n" +
+				" 1 | let x = 1\n" +
+				"   |          ^\n",
+		},
+		{
+			"span past the line",
+			"let x = 1\nlet y = 2\n",
+			CompilationMessage{Message: "long", Type: CompilationMessageTypeError, LineNum: 1, LinePos: 9, Offset: 8, Length: 12},
+			"s.wgsl:1:9: error: long\n" +
+				" 1 | let x = 1\n" +
+				"   |         ^\n",
+		},
+		{
+			"offset past the source",
+			"x",
+			CompilationMessage{Message: "eof", Type: CompilationMessageTypeError, LineNum: 3, LinePos: 1, Offset: 10},
+			"s.wgsl:3:1: error: eof\n",
+		},
+	}
+	for _, test := range tests {
+		if got := test.message.Format("s.wgsl", test.source); got != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNewCompilationError(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+		source string
+		want   CompilationMessage
+	}{
+		{
+			"naga span",
+			"Shader parsing error: expected ';', found '='\n" +
+				"  ┌─ wgsl:2:9\n" +
+				"  │\n" +
+				"2 │     let é = 1\n" +
+				"  │         ^ expected ';'\n",
+			"fn f() {\n    let é = 1\n}\n",
+			CompilationMessage{Message: "expected ';', found '='", LineNum: 2, LinePos: 9, Offset: 17, Length: 2},
+		},
+		{
+			"parser position",
+			"Validation Error\n\nCaused by:\n    In wgpuDeviceCreateShaderModule\n      invalid shader\n",
+			"fn f( {}\n",
+			CompilationMessage{LineNum: 1, LinePos: 7, Offset: 6},
+		},
+		{
+			"no position",
+			"Validation Error\n\nCaused by:\n    In wgpuDeviceCreateShaderModule\n      = invalid shader\n",
+			"fn f() {}\n",
+			CompilationMessage{Message: "invalid shader"},
+		},
+	}
+	for _, test := range tests {
+		err := newCompilationError(&Error{Op: "Device.CreateShaderModule", Type: ErrorTypeValidation, Message: test.report}, test.source)
+		if err.Source != test.source || err.Err.Message != test.report || len(err.Info.Messages) != 1 {
+			t.Errorf("%s: got %+v", test.name, err)
+			continue
+		}
+		m := err.Info.Messages[0]
+		want := test.want
+		want.Type = CompilationMessageTypeError
+		if want.Message == "" {
+			want.Message = m.Message
+		}
+		if m != want {
+			t.Errorf("%s: got %+v, want %+v", test.name, m, want)
+		}
+	}
+}
@@ -7,12 +7,13 @@ package wgpu
 #include <stdlib.h>
 #include "./lib/wgpu.h"
 
-extern void gowebgpu_error_callback_c(WGPUErrorType type, char const * message, void * userdata);
+extern void gowebgpu_push_error_scopes(WGPUDevice device);
+extern void gowebgpu_pop_error_scopes(WGPUDevice device, void * userdata);
 
 static inline void gowebgpu_compute_pass_encoder_end(WGPUComputePassEncoder computePassEncoder, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuComputePassEncoderEnd(computePassEncoder);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_compute_pass_encoder_release(WGPUComputePassEncoder computePassEncoder, WGPUDevice device) {
@@ -23,7 +24,6 @@ static inline void gowebgpu_compute_pass_encoder_release(WGPUComputePassEncoder
 */
 import "C"
 import (
-	"errors"
 	"runtime/cgo"
 	"unsafe"
 )
@@ -31,24 +31,30 @@ import (
 type ComputePassEncoder struct {
 	deviceRef C.WGPUDevice
 	ref       C.WGPUComputePassEncoder
+	label     string
+	refs      refCount
 }
 
 func (p *ComputePassEncoder) BeginPipelineStatisticsQuery(querySet *QuerySet, queryIndex uint32) {
+	p.refs.check("ComputePassEncoder.BeginPipelineStatisticsQuery", p.label)
+	querySet.refs.check("ComputePassEncoder.BeginPipelineStatisticsQuery", querySet.label)
 	C.wgpuComputePassEncoderBeginPipelineStatisticsQuery(p.ref, querySet.ref, C.uint32_t(queryIndex))
 }
 
 func (p *ComputePassEncoder) DispatchWorkgroups(workgroupCountX, workgroupCountY, workgroupCountZ uint32) {
+	p.refs.check("ComputePassEncoder.DispatchWorkgroups", p.label)
 	C.wgpuComputePassEncoderDispatchWorkgroups(p.ref, C.uint32_t(workgroupCountX), C.uint32_t(workgroupCountY), C.uint32_t(workgroupCountZ))
 }
 
 func (p *ComputePassEncoder) DispatchWorkgroupsIndirect(indirectBuffer *Buffer, indirectOffset uint64) {
+	p.refs.check("ComputePassEncoder.DispatchWorkgroupsIndirect", p.label)
+	indirectBuffer.refs.check("ComputePassEncoder.DispatchWorkgroupsIndirect", indirectBuffer.label)
 	C.wgpuComputePassEncoderDispatchWorkgroupsIndirect(p.ref, indirectBuffer.ref, C.uint64_t(indirectOffset))
 }
 
 func (p *ComputePassEncoder) End() (err error) {
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*ComputePassEncoder).End(): " + message)
-	}
+	p.refs.check("ComputePassEncoder.End", p.label)
+	cb := newErrorCallback(&err, "ComputePassEncoder.End", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -57,10 +63,12 @@ func (p *ComputePassEncoder) End() (err error) {
 }
 
 func (p *ComputePassEncoder) EndPipelineStatisticsQuery() {
+	p.refs.check("ComputePassEncoder.EndPipelineStatisticsQuery", p.label)
 	C.wgpuComputePassEncoderEndPipelineStatisticsQuery(p.ref)
 }
 
 func (p *ComputePassEncoder) InsertDebugMarker(markerLabel string) {
+	p.refs.check("ComputePassEncoder.InsertDebugMarker", p.label)
 	markerLabelStr := C.CString(markerLabel)
 	defer C.free(unsafe.Pointer(markerLabelStr))
 
@@ -68,10 +76,12 @@ func (p *ComputePassEncoder) InsertDebugMarker(markerLabel string) {
 }
 
 func (p *ComputePassEncoder) PopDebugGroup() {
+	p.refs.check("ComputePassEncoder.PopDebugGroup", p.label)
 	C.wgpuComputePassEncoderPopDebugGroup(p.ref)
 }
 
 func (p *ComputePassEncoder) PushDebugGroup(groupLabel string) {
+	p.refs.check("ComputePassEncoder.PushDebugGroup", p.label)
 	groupLabelStr := C.CString(groupLabel)
 	defer C.free(unsafe.Pointer(groupLabelStr))
 
@@ -79,6 +89,8 @@ func (p *ComputePassEncoder) PushDebugGroup(groupLabel string) {
 }
 
 func (p *ComputePassEncoder) SetBindGroup(groupIndex uint32, group *BindGroup, dynamicOffsets []uint32) {
+	p.refs.check("ComputePassEncoder.SetBindGroup", p.label)
+	group.refs.check("ComputePassEncoder.SetBindGroup", group.label)
 	dynamicOffsetCount := len(dynamicOffsets)
 	if dynamicOffsetCount == 0 {
 		C.wgpuComputePassEncoderSetBindGroup(p.ref, C.uint32_t(groupIndex), group.ref, 0, nil)
@@ -91,9 +103,13 @@ func (p *ComputePassEncoder) SetBindGroup(groupIndex uint32, group *BindGroup, d
 }
 
 func (p *ComputePassEncoder) SetPipeline(pipeline *ComputePipeline) {
+	p.refs.check("ComputePassEncoder.SetPipeline", p.label)
+	pipeline.refs.check("ComputePassEncoder.SetPipeline", pipeline.label)
 	C.wgpuComputePassEncoderSetPipeline(p.ref, pipeline.ref)
 }
 
 func (p *ComputePassEncoder) Release() {
-	C.gowebgpu_compute_pass_encoder_release(p.ref, p.deviceRef)
+	if p.refs.release("ComputePassEncoder.Release", p.label) {
+		C.gowebgpu_compute_pass_encoder_release(p.ref, p.deviceRef)
+	}
 }
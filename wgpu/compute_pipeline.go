@@ -11,18 +11,25 @@ package wgpu
 import "C"
 
 type ComputePipeline struct {
-	ref C.WGPUComputePipeline
+	ref   C.WGPUComputePipeline
+	label string
+	refs  refCount
 }
 
 func (p *ComputePipeline) GetBindGroupLayout(groupIndex uint32) *BindGroupLayout {
+	p.refs.check("ComputePipeline.GetBindGroupLayout", p.label)
 	ref := C.wgpuComputePipelineGetBindGroupLayout(p.ref, C.uint32_t(groupIndex))
 	if ref == nil {
 		panic("Failed to accquire BindGroupLayout")
 	}
 
-	return &BindGroupLayout{ref}
+	layout := &BindGroupLayout{ref: ref}
+	track(layout, "BindGroupLayout", "")
+	return layout
 }
 
 func (p *ComputePipeline) Release() {
-	C.wgpuComputePipelineRelease(p.ref)
+	if p.refs.release("ComputePipeline.Release", p.label) {
+		C.wgpuComputePipelineRelease(p.ref)
+	}
 }
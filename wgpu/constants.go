@@ -0,0 +1,145 @@
+package wgpu
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/openfluke/webgpu/wgsl"
+)
+
+// ConstantEntries returns the entries setting the pipeline-overridable
+// constants in values, sorted by key, to specialize a pipeline stage:
+//
+//	Compute: wgpu.ProgrammableStageDescriptor{
+//		Module:     module,
+//		EntryPoint: "main",
+//		Constants:  wgpu.ConstantEntries(map[string]float64{"block_size": 64}),
+//	}
+func ConstantEntries(values map[string]float64) []ConstantEntry {
+	entries := make([]ConstantEntry, 0, len(values))
+	for key, value := range values {
+		entries = append(entries, ConstantEntry{Key: key, Value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// ValidateConstants checks the constants of a pipeline stage against the
+// override declarations of its module, as pipeline creation does: each
+// key must name an override, by @id if it has one, each value must be
+// representable in its type, and the overrides without an initializer
+// that the entry point uses must be given. An empty entryPoint selects
+// the only entry point of the module for stage.
+func ValidateConstants(module *wgsl.Reflection, stage ShaderStage, entryPoint string, constants []ConstantEntry) error {
+	if msg := checkConstants(module, stage, entryPoint, constants); msg != "" {
+		return &Error{Op: "ValidateConstants", Type: ErrorTypeValidation, Message: msg}
+	}
+	return nil
+}
+
+func checkConstants(module *wgsl.Reflection, stage ShaderStage, entryPoint string, constants []ConstantEntry) string {
+	var ep *wgsl.EntryPoint
+	for _, e := range module.EntryPoints {
+		if ShaderStage(e.Stage) != stage || (entryPoint != "" && e.Name != entryPoint) {
+			continue
+		}
+		if ep != nil {
+			return "more than one " + stage.String() + " entry point"
+		}
+		ep = e
+	}
+	if ep == nil {
+		if entryPoint != "" {
+			return "no " + stage.String() + " entry point " + strconv.Quote(entryPoint)
+		}
+		return "no " + stage.String() + " entry point"
+	}
+
+	overrides := make(map[string]*wgsl.Override, len(module.Overrides))
+	for _, o := range module.Overrides {
+		overrides[o.Key()] = o
+	}
+	given := make(map[string]bool, len(constants))
+	for _, c := range constants {
+		o := overrides[c.Key]
+		if o == nil {
+			return "constant " + strconv.Quote(c.Key) + " does not name an override declaration"
+		}
+		if given[c.Key] {
+			return "constant " + strconv.Quote(c.Key) + " is set twice"
+		}
+		given[c.Key] = true
+		if !representable(c.Value, o.Type) {
+			return "constant " + strconv.Quote(c.Key) + " value " + strconv.FormatFloat(c.Value, 'g', -1, 64) + " is not representable as " + o.Type.String()
+		}
+	}
+	for _, o := range ep.Overrides {
+		if o.Default == nil && !given[o.Key()] {
+			return "override " + o.Name + " used by entry point " + ep.Name + " has no initializer and no constant"
+		}
+	}
+	return ""
+}
+
+// representable reports whether v converts to a value of type t without
+// loss. Any value converts to bool, non-zero values to true.
+func representable(v float64, t wgsl.Scalar) bool {
+	switch t {
+	case wgsl.Bool:
+		return !math.IsNaN(v)
+	case wgsl.I32:
+		return v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32
+	case wgsl.U32:
+		return v == math.Trunc(v) && v >= 0 && v <= math.MaxUint32
+	case wgsl.F32:
+		return !math.IsNaN(v) && math.Abs(v) <= math.MaxFloat32
+	case wgsl.F16:
+		return !math.IsNaN(v) && math.Abs(v) <= 65504
+	}
+	return false
+}
+
+// validateStageConstants checks the constants of a pipeline stage with
+// ValidateConstants if its module was created from WGSL source, so that
+// a missing override is reported even without constants. Modules the
+// wgsl package cannot parse are left to the implementation to validate.
+func validateStageConstants(op, label string, module *ShaderModule, stage ShaderStage, entryPoint string, constants []ConstantEntry) error {
+	if module == nil || module.code == "" {
+		return nil
+	}
+	reflection, err := wgsl.ReflectSource(module.code)
+	if err != nil {
+		return nil
+	}
+	if msg := checkConstants(reflection, stage, entryPoint, constants); msg != "" {
+		return &Error{Op: op, Label: label, Type: ErrorTypeValidation, Message: msg}
+	}
+	return nil
+}
+
+// validateComputeConstants validates the constants of a compute pipeline
+// with validateStageConstants.
+func validateComputeConstants(op string, descriptor *ComputePipelineDescriptor) error {
+	if descriptor == nil {
+		return nil
+	}
+	stage := &descriptor.Compute
+	return validateStageConstants(op, descriptor.Label, stage.Module, ShaderStageCompute, stage.EntryPoint, stage.Constants)
+}
+
+// validateRenderConstants validates the constants of the stages of a
+// render pipeline with validateStageConstants.
+func validateRenderConstants(op string, descriptor *RenderPipelineDescriptor) error {
+	if descriptor == nil {
+		return nil
+	}
+	vertex := &descriptor.Vertex
+	if err := validateStageConstants(op, descriptor.Label, vertex.Module, ShaderStageVertex, vertex.EntryPoint, vertex.Constants); err != nil {
+		return err
+	}
+	if fragment := descriptor.Fragment; fragment != nil {
+		return validateStageConstants(op, descriptor.Label, fragment.Module, ShaderStageFragment, fragment.EntryPoint, fragment.Constants)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package wgpu
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfluke/webgpu/wgsl"
+)
+
+func TestConstantEntries(t *testing.T) {
+	got := ConstantEntries(map[string]float64{"b": 2, "a": 1, "10": 3})
+	want := []ConstantEntry{{Key: "10", Value: 3}, {Key: "a", Value: 1}, {Key: "b", Value: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := ConstantEntries(nil); got == nil || len(got) != 0 {
+		t.Errorf("ConstantEntries(nil) = %#v, want an empty slice", got)
+	}
+}
+
+func TestRepresentable(t *testing.T) {
+	tests := []struct {
+		v    float64
+		t    wgsl.Scalar
+		want bool
+	}{
+		{0, wgsl.Bool, true},
+		{-3.5, wgsl.Bool, true},
+		{math.NaN(), wgsl.Bool, false},
+
+		{math.MaxInt32, wgsl.I32, true},
+		{math.MaxInt32 + 1, wgsl.I32, false},
+		{math.MinInt32, wgsl.I32, true},
+		{math.MinInt32 - 1, wgsl.I32, false},
+		{1.5, wgsl.I32, false},
+		{math.Inf(1), wgsl.I32, false},
+
+		{0, wgsl.U32, true},
+		{-1, wgsl.U32, false},
+		{math.MaxUint32, wgsl.U32, true},
+		{math.MaxUint32 + 1, wgsl.U32, false},
+		{0.5, wgsl.U32, false},
+
+		{math.MaxFloat32, wgsl.F32, true},
+		{-math.MaxFloat32, wgsl.F32, true},
+		{math.MaxFloat64, wgsl.F32, false},
+		{math.Inf(-1), wgsl.F32, false},
+		{math.NaN(), wgsl.F32, false},
+
+		{65504, wgsl.F16, true},
+		{-65504, wgsl.F16, true},
+		{65505, wgsl.F16, false},
+		{0.1, wgsl.F16, true},
+		{math.NaN(), wgsl.F16, false},
+	}
+	for _, test := range tests {
+		if got := representable(test.v, test.t); got != test.want {
+			t.Errorf("representable(%v, %s) = %v, want %v", test.v, test.t, got, test.want)
+		}
+	}
+}
+
+func TestCheckConstants(t *testing.T) {
+	module, err := wgsl.ReflectSource(`
+enable f16;
+override size: u32;
+@id(7) override scale: f32 = 1.0;
+override offset: i32 = 0;
+override half_max: f16 = 1.0;
+override unused: u32;
+@compute @workgroup_size(size) fn main() {
+	_ = scale;
+	_ = offset;
+	_ = half_max;
+}
+@vertex fn vs() -> @builtin(position) vec4f { return vec4f(); }
+@vertex fn vs2() -> @builtin(position) vec4f { return vec4f(); }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		stage      ShaderStage
+		entryPoint string
+		constants  []ConstantEntry
+		want       string
+	}{
+		{"valid", ShaderStageCompute, "", []ConstantEntry{{Key: "size", Value: 64}}, ""},
+		{"named entry point", ShaderStageCompute, "main", []ConstantEntry{{Key: "size", Value: 64}, {Key: "7", Value: 2}}, ""},
+		{"missing override", ShaderStageCompute, "", nil, "override size used by entry point main has no initializer"},
+		{"unused override", ShaderStageCompute, "", []ConstantEntry{{Key: "size", Value: 1}, {Key: "unused", Value: 1}}, ""},
+		{"unknown key", ShaderStageCompute, "", []ConstantEntry{{Key: "size", Value: 1}, {Key: "other", Value: 1}}, `"other" does not name`},
+		{"name of an override with an id", ShaderStageCompute, "", []ConstantEntry{{Key: "size", Value: 1}, {Key: "scale", Value: 1}}, `"scale" does not name`},
+		{"duplicate key", ShaderStageCompute, "", []ConstantEntry{{Key: "size", Value: 1}, {Key: "size", Value: 2}}, `"size" is set twice`},
+		{"u32 range", ShaderStageCompute, "", []ConstantEntry{{Key: "size", Value: -1}}, "not representable as u32"},
+		{"i32 range", ShaderStageCompute, "", []ConstantEntry{{Key: "size", Value: 1}, {Key: "offset", Value: math.MaxInt32 + 1}}, "not representable as i32"},
+		{"f16 range", ShaderStageCompute, "", []ConstantEntry{{Key: "size", Value: 1}, {Key: "half_max", Value: 70000}}, "not representable as f16"},
+		{"f16 max", ShaderStageCompute, "", []ConstantEntry{{Key: "size", Value: 1}, {Key: "half_max", Value: 65504}}, ""},
+		{"no entry point", ShaderStageFragment, "", nil, "no fragment entry point"},
+		{"unknown entry point", ShaderStageCompute, "other", nil, `no compute entry point "other"`},
+		{"ambiguous entry point", ShaderStageVertex, "", nil, "more than one vertex entry point"},
+		{"vertex entry point", ShaderStageVertex, "vs2", nil, ""},
+	}
+	for _, test := range tests {
+		got := checkConstants(module, test.stage, test.entryPoint, test.constants)
+		if test.want == "" && got != "" || !strings.Contains(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestValidateStageConstants(t *testing.T) {
+	module := &ShaderModule{code: "override n: u32;\n@compute @workgroup_size(n) fn main() {}\n"}
+	if err := validateStageConstants("test", "", module, ShaderStageCompute, "", nil); err == nil {
+		t.Error("missing override n not reported")
+	}
+	if err := validateStageConstants("test", "", module, ShaderStageCompute, "", []ConstantEntry{{Key: "n", Value: 64}}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,127 @@
+package wgpu
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// CopyLayout is the layout of one mip level of a texture in a buffer,
+// for CopyTextureToBuffer and CopyBufferToTexture. Rows of blocks are
+// padded to CopyBytesPerRowAlignment.
+type CopyLayout struct {
+	// Layout is the buffer layout to pass with the copy.
+	Layout TextureDataLayout
+	// Extent is the copy size to pass with the copy: the size of the
+	// mip level rounded up to whole blocks.
+	Extent Extent3D
+
+	// RowBytes is the number of bytes of a row of blocks without
+	// padding; Layout.BytesPerRow is the padded number.
+	RowBytes uint32
+	// Rows is the number of rows of blocks per image, and Images the
+	// number of images, that is depth slices or array layers.
+	Rows   uint32
+	Images uint32
+
+	// Size is the buffer size the copy needs, including the padding of
+	// every row.
+	Size uint64
+}
+
+// TextureCopyLayout computes the buffer layout for copying a mip level
+// of a texture with the given format, dimension and size. For 3D
+// textures the depth shrinks with the mip level; for 2D textures
+// size.DepthOrArrayLayers is the number of array layers, all of which
+// are copied. Depth-stencil formats must be copied one aspect at a time.
+func TextureCopyLayout(format TextureFormat, dimension TextureDimension, size Extent3D, mipLevel uint32, aspect TextureAspect) (CopyLayout, error) {
+	fail := func(msg string) (CopyLayout, error) {
+		return CopyLayout{}, &Error{Op: "TextureCopyLayout", Type: ErrorTypeValidation, Message: msg}
+	}
+	blockSize := format.BlockCopySize(aspect)
+	if blockSize == 0 {
+		return fail("aspect " + aspect.String() + " of format " + format.String() + " cannot be copied")
+	}
+	largest := size.Width
+	width, height, images := size.Width>>mipLevel, uint32(1), max(size.DepthOrArrayLayers, 1)
+	if dimension != TextureDimension1D {
+		largest = max(largest, size.Height)
+		height = size.Height >> mipLevel
+	}
+	if dimension == TextureDimension3D {
+		largest = max(largest, size.DepthOrArrayLayers)
+		images = size.DepthOrArrayLayers >> mipLevel
+	}
+	if mipLevel >= 32 || largest>>mipLevel == 0 {
+		return fail("mip level " + strconv.FormatUint(uint64(mipLevel), 10) + " is out of range")
+	}
+	width, height, images = max(width, 1), max(height, 1), max(images, 1)
+
+	// The sizes are computed in 64 bits, since rounding up to whole
+	// blocks and rows padded to the alignment can exceed 32 bits.
+	blockWidth, blockHeight := format.BlockDimensions()
+	blocksWide := (uint64(width) + uint64(blockWidth) - 1) / uint64(blockWidth)
+	rows := (uint64(height) + uint64(blockHeight) - 1) / uint64(blockHeight)
+	extentWidth, extentHeight := blocksWide*uint64(blockWidth), rows*uint64(blockHeight)
+	rowBytes := blocksWide * uint64(blockSize)
+	bytesPerRow := (rowBytes + CopyBytesPerRowAlignment - 1) &^ (CopyBytesPerRowAlignment - 1)
+	if max(extentWidth, extentHeight, rowBytes, bytesPerRow) > math.MaxUint32 {
+		return fail("mip level " + strconv.FormatUint(uint64(mipLevel), 10) + " is too large to copy")
+	}
+	// Both factors of the image size fit in 32 bits, so only the last
+	// product can overflow.
+	hi, total := bits.Mul64(bytesPerRow*rows, uint64(images))
+	if hi != 0 {
+		return fail("mip level " + strconv.FormatUint(uint64(mipLevel), 10) + " is too large to copy")
+	}
+
+	return CopyLayout{
+		Layout: TextureDataLayout{
+			BytesPerRow:  uint32(bytesPerRow),
+			RowsPerImage: uint32(rows),
+		},
+		Extent:   Extent3D{Width: uint32(extentWidth), Height: uint32(extentHeight), DepthOrArrayLayers: images},
+		RowBytes: uint32(rowBytes),
+		Rows:     uint32(rows),
+		Images:   images,
+		Size:     total,
+	}, nil
+}
+
+// TightSize returns the size of the data without row padding.
+func (l *CopyLayout) TightSize() uint64 {
+	return uint64(l.RowBytes) * uint64(l.Rows) * uint64(l.Images)
+}
+
+// Unpad copies data laid out as l, such as the contents of a buffer
+// filled by CopyTextureToBuffer, into a new slice without row padding.
+func (l *CopyLayout) Unpad(padded []byte) ([]byte, error) {
+	if uint64(len(padded)) < l.Size-uint64(l.Layout.BytesPerRow-l.RowBytes) {
+		return nil, l.sizeError("CopyLayout.Unpad", len(padded))
+	}
+	tight := make([]byte, l.TightSize())
+	l.copyRows(tight, padded, l.RowBytes, l.Layout.BytesPerRow)
+	return tight, nil
+}
+
+// Pad copies tightly packed data into a new slice laid out as l, ready
+// to be written to a buffer for CopyBufferToTexture.
+func (l *CopyLayout) Pad(tight []byte) ([]byte, error) {
+	if uint64(len(tight)) != l.TightSize() {
+		return nil, l.sizeError("CopyLayout.Pad", len(tight))
+	}
+	padded := make([]byte, l.Size)
+	l.copyRows(padded, tight, l.Layout.BytesPerRow, l.RowBytes)
+	return padded, nil
+}
+
+func (l *CopyLayout) copyRows(dst, src []byte, dstStride, srcStride uint32) {
+	for i := uint64(0); i < uint64(l.Rows)*uint64(l.Images); i++ {
+		d, s := uint64(i)*uint64(dstStride), uint64(i)*uint64(srcStride)
+		copy(dst[d:d+uint64(l.RowBytes)], src[s:s+uint64(l.RowBytes)])
+	}
+}
+
+func (l *CopyLayout) sizeError(op string, n int) error {
+	return &Error{Op: op, Type: ErrorTypeValidation, Message: "unexpected data size " + strconv.Itoa(n)}
+}
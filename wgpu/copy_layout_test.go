@@ -0,0 +1,196 @@
+package wgpu
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTextureCopyLayout(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    TextureFormat
+		dimension TextureDimension
+		size      Extent3D
+		mipLevel  uint32
+		aspect    TextureAspect
+		want      CopyLayout
+	}{
+		{
+			name:      "rgba8 2D",
+			format:    TextureFormatRGBA8Unorm,
+			dimension: TextureDimension2D,
+			size:      Extent3D{Width: 100, Height: 50, DepthOrArrayLayers: 1},
+			want: CopyLayout{
+				Layout:   TextureDataLayout{BytesPerRow: 512, RowsPerImage: 50},
+				Extent:   Extent3D{Width: 100, Height: 50, DepthOrArrayLayers: 1},
+				RowBytes: 400, Rows: 50, Images: 1, Size: 512 * 50,
+			},
+		},
+		{
+			name:      "rgba8 2D mip tail",
+			format:    TextureFormatRGBA8Unorm,
+			dimension: TextureDimension2D,
+			size:      Extent3D{Width: 100, Height: 50, DepthOrArrayLayers: 1},
+			mipLevel:  6,
+			want: CopyLayout{
+				Layout:   TextureDataLayout{BytesPerRow: 256, RowsPerImage: 1},
+				Extent:   Extent3D{Width: 1, Height: 1, DepthOrArrayLayers: 1},
+				RowBytes: 4, Rows: 1, Images: 1, Size: 256,
+			},
+		},
+		{
+			name:      "bc1 rounds up to blocks",
+			format:    TextureFormatBC1RGBAUnorm,
+			dimension: TextureDimension2D,
+			size:      Extent3D{Width: 10, Height: 6, DepthOrArrayLayers: 1},
+			want: CopyLayout{
+				Layout:   TextureDataLayout{BytesPerRow: 256, RowsPerImage: 2},
+				Extent:   Extent3D{Width: 12, Height: 8, DepthOrArrayLayers: 1},
+				RowBytes: 24, Rows: 2, Images: 1, Size: 512,
+			},
+		},
+		{
+			name:      "bc7 mip tail is one block",
+			format:    TextureFormatBC7RGBAUnorm,
+			dimension: TextureDimension2D,
+			size:      Extent3D{Width: 64, Height: 64, DepthOrArrayLayers: 1},
+			mipLevel:  6,
+			want: CopyLayout{
+				Layout:   TextureDataLayout{BytesPerRow: 256, RowsPerImage: 1},
+				Extent:   Extent3D{Width: 4, Height: 4, DepthOrArrayLayers: 1},
+				RowBytes: 16, Rows: 1, Images: 1, Size: 256,
+			},
+		},
+		{
+			name:      "2D array keeps layers",
+			format:    TextureFormatR8Unorm,
+			dimension: TextureDimension2D,
+			size:      Extent3D{Width: 16, Height: 16, DepthOrArrayLayers: 6},
+			mipLevel:  2,
+			want: CopyLayout{
+				Layout:   TextureDataLayout{BytesPerRow: 256, RowsPerImage: 4},
+				Extent:   Extent3D{Width: 4, Height: 4, DepthOrArrayLayers: 6},
+				RowBytes: 4, Rows: 4, Images: 6, Size: 256 * 4 * 6,
+			},
+		},
+		{
+			name:      "3D depth shrinks",
+			format:    TextureFormatRGBA16Float,
+			dimension: TextureDimension3D,
+			size:      Extent3D{Width: 8, Height: 8, DepthOrArrayLayers: 32},
+			mipLevel:  3,
+			want: CopyLayout{
+				Layout:   TextureDataLayout{BytesPerRow: 256, RowsPerImage: 1},
+				Extent:   Extent3D{Width: 1, Height: 1, DepthOrArrayLayers: 4},
+				RowBytes: 8, Rows: 1, Images: 4, Size: 256 * 4,
+			},
+		},
+		{
+			name:      "3D level past the width",
+			format:    TextureFormatR8Unorm,
+			dimension: TextureDimension3D,
+			size:      Extent3D{Width: 2, Height: 2, DepthOrArrayLayers: 16},
+			mipLevel:  4,
+			want: CopyLayout{
+				Layout:   TextureDataLayout{BytesPerRow: 256, RowsPerImage: 1},
+				Extent:   Extent3D{Width: 1, Height: 1, DepthOrArrayLayers: 1},
+				RowBytes: 1, Rows: 1, Images: 1, Size: 256,
+			},
+		},
+		{
+			name:      "1D ignores height",
+			format:    TextureFormatRGBA8Unorm,
+			dimension: TextureDimension1D,
+			size:      Extent3D{Width: 64, Height: 1, DepthOrArrayLayers: 1},
+			mipLevel:  1,
+			want: CopyLayout{
+				Layout:   TextureDataLayout{BytesPerRow: 256, RowsPerImage: 1},
+				Extent:   Extent3D{Width: 32, Height: 1, DepthOrArrayLayers: 1},
+				RowBytes: 128, Rows: 1, Images: 1, Size: 256,
+			},
+		},
+		{
+			name:      "depth aspect",
+			format:    TextureFormatDepth32FloatStencil8,
+			dimension: TextureDimension2D,
+			size:      Extent3D{Width: 4, Height: 4, DepthOrArrayLayers: 1},
+			aspect:    TextureAspectDepthOnly,
+			want: CopyLayout{
+				Layout:   TextureDataLayout{BytesPerRow: 256, RowsPerImage: 4},
+				Extent:   Extent3D{Width: 4, Height: 4, DepthOrArrayLayers: 1},
+				RowBytes: 16, Rows: 4, Images: 1, Size: 1024,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TextureCopyLayout(tt.format, tt.dimension, tt.size, tt.mipLevel, tt.aspect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextureCopyLayoutErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    TextureFormat
+		dimension TextureDimension
+		size      Extent3D
+		mipLevel  uint32
+		aspect    TextureAspect
+	}{
+		{"mip level past the size", TextureFormatRGBA8Unorm, TextureDimension2D, Extent3D{Width: 4, Height: 4, DepthOrArrayLayers: 1}, 3, TextureAspectAll},
+		{"mip level 32", TextureFormatRGBA8Unorm, TextureDimension2D, Extent3D{Width: 0xffffffff, Height: 1, DepthOrArrayLayers: 1}, 32, TextureAspectAll},
+		{"combined depth stencil", TextureFormatDepth24PlusStencil8, TextureDimension2D, Extent3D{Width: 4, Height: 4, DepthOrArrayLayers: 1}, 0, TextureAspectAll},
+		{"row bytes wrap", TextureFormatBC1RGBAUnorm, TextureDimension2D, Extent3D{Width: 0x7fffffff, Height: 0x7fffffff, DepthOrArrayLayers: 1}, 0, TextureAspectAll},
+		{"padded row wraps", TextureFormatR8Unorm, TextureDimension2D, Extent3D{Width: 0xffffff01, Height: 1, DepthOrArrayLayers: 1}, 0, TextureAspectAll},
+		{"extent wraps", TextureFormatBC1RGBAUnorm, TextureDimension2D, Extent3D{Width: 0xffffffff, Height: 4, DepthOrArrayLayers: 1}, 0, TextureAspectAll},
+		{"size wraps", TextureFormatRGBA32Float, TextureDimension3D, Extent3D{Width: 0x1000000, Height: 0xffffffff, DepthOrArrayLayers: 0xffffffff}, 0, TextureAspectAll},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := TextureCopyLayout(tt.format, tt.dimension, tt.size, tt.mipLevel, tt.aspect)
+			var wgpuErr *Error
+			if !errors.As(err, &wgpuErr) || wgpuErr.Type != ErrorTypeValidation {
+				t.Fatalf("got %v, want a validation *Error", err)
+			}
+		})
+	}
+}
+
+func TestCopyLayoutPadUnpad(t *testing.T) {
+	l, err := TextureCopyLayout(TextureFormatRGBA8Unorm, TextureDimension2D, Extent3D{Width: 3, Height: 2, DepthOrArrayLayers: 2}, 0, TextureAspectAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tight := make([]byte, l.TightSize())
+	for i := range tight {
+		tight[i] = byte(i + 1)
+	}
+	padded, err := l.Pad(tight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(len(padded)) != l.Size || padded[l.Layout.BytesPerRow] != tight[l.RowBytes] {
+		t.Fatalf("rows not padded to %d bytes", l.Layout.BytesPerRow)
+	}
+	// The padding of the last row may be missing.
+	got, err := l.Unpad(padded[:l.Size-uint64(l.Layout.BytesPerRow-l.RowBytes)])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(tight) {
+		t.Error("Unpad does not invert Pad")
+	}
+	if _, err := l.Pad(tight[1:]); err == nil {
+		t.Error("Pad accepts short data")
+	}
+	if _, err := l.Unpad(padded[:10]); err == nil {
+		t.Error("Unpad accepts short data")
+	}
+}
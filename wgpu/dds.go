@@ -0,0 +1,263 @@
+package wgpu
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+const ddsMagic = "DDS "
+
+// Offsets and sizes of the DDS header, after the magic, and of the DX10
+// header that follows it, see
+// https://learn.microsoft.com/en-us/windows/win32/direct3ddds/dds-header
+const (
+	ddsHeaderSize     = 4 + 124
+	ddsDX10HeaderSize = 20
+	ddsPixelFormat    = 4 + 72
+	ddsFourCC         = ddsPixelFormat + 8
+)
+
+// DDS header flags.
+const (
+	ddsdMipMapCount = 0x20000
+	ddpfAlphaPixels = 0x1
+	ddpfFourCC      = 0x4
+	ddpfRGB         = 0x40
+	ddpfLuminance   = 0x20000
+	ddsCaps2Cubemap = 0x200
+	ddsCaps2Faces   = 0xFC00 // DDSCAPS2_CUBEMAP_POSITIVEX and the other five
+	ddsCaps2Volume  = 0x200000
+)
+
+// D3D10_RESOURCE_DIMENSION and D3D10_RESOURCE_MISC_TEXTURECUBE, from the
+// DX10 header.
+const (
+	ddsDimension1D = 2
+	ddsDimension3D = 4
+	ddsMiscCube    = 0x4
+)
+
+// ParseDDS parses a DirectDraw Surface file, with or without the DX10
+// header, as specified by
+// https://learn.microsoft.com/en-us/windows/win32/direct3ddds/dx-graphics-dds-pguide
+// Legacy cube maps must have all six faces.
+func ParseDDS(data []byte) (*TextureData, error) {
+	fail := func(msg string) (*TextureData, error) {
+		return nil, &Error{Op: "ParseDDS", Type: ErrorTypeValidation, Message: msg}
+	}
+	if len(data) < ddsHeaderSize || string(data[:4]) != ddsMagic {
+		return fail("not a DDS file")
+	}
+	u32 := func(offset int) uint32 { return binary.LittleEndian.Uint32(data[offset:]) }
+	flags, height, width, depth, levels := u32(8), u32(12), u32(16), u32(24), u32(28)
+	caps2 := u32(4 + 108)
+
+	d := &TextureData{
+		Dimension:     TextureDimension2D,
+		Size:          Extent3D{Width: width, Height: max(height, 1), DepthOrArrayLayers: 1},
+		MipLevelCount: 1,
+	}
+	if flags&ddsdMipMapCount != 0 {
+		d.MipLevelCount = max(levels, 1)
+	}
+	offset := ddsHeaderSize
+	// layers is the number of array layers or cube faces; 3D textures
+	// have one, holding the depth slices.
+	layers := uint32(1)
+
+	if string(data[ddsFourCC:ddsFourCC+4]) == "DX10" {
+		if len(data) < ddsHeaderSize+ddsDX10HeaderSize {
+			return fail("truncated DX10 header")
+		}
+		dxgiFormat, dimension, misc, arraySize := u32(offset), u32(offset+4), u32(offset+8), u32(offset+12)
+		offset += ddsDX10HeaderSize
+		d.Format = dxgiFormats[dxgiFormat]
+		if d.Format == TextureFormatUndefined {
+			return fail("DXGI format " + strconv.FormatUint(uint64(dxgiFormat), 10) + " has no WebGPU equivalent")
+		}
+		if arraySize > math.MaxUint32/6 {
+			return fail("invalid array size " + strconv.FormatUint(uint64(arraySize), 10))
+		}
+		layers = max(arraySize, 1)
+		switch {
+		case dimension == ddsDimension1D:
+			d.Dimension = TextureDimension1D
+		case dimension == ddsDimension3D:
+			d.Dimension = TextureDimension3D
+			layers = 1
+		case misc&ddsMiscCube != 0:
+			d.Cube = true
+			layers *= 6
+		}
+	} else {
+		d.Format = ddsLegacyFormat(data[ddsPixelFormat : ddsPixelFormat+32])
+		if d.Format == TextureFormatUndefined {
+			return fail("unsupported pixel format")
+		}
+		switch {
+		case caps2&ddsCaps2Volume != 0:
+			d.Dimension = TextureDimension3D
+		case caps2&ddsCaps2Cubemap != 0:
+			if caps2&ddsCaps2Faces != ddsCaps2Faces {
+				return fail("cube map lacks faces")
+			}
+			d.Cube = true
+			layers = 6
+		}
+	}
+	if d.Dimension == TextureDimension3D {
+		d.Size.DepthOrArrayLayers = max(depth, 1)
+	} else {
+		d.Size.DepthOrArrayLayers = layers
+	}
+
+	if err := d.checkHeader("ParseDDS"); err != nil {
+		return nil, err
+	}
+	layouts := make([]CopyLayout, d.MipLevelCount)
+	remaining := uint64(len(data) - offset)
+	for level := range layouts {
+		layout, err := d.levelLayout(uint32(level))
+		if err != nil {
+			return nil, err
+		}
+		if layout.TightSize() > remaining {
+			return fail("truncated data of level " + strconv.Itoa(level))
+		}
+		remaining -= layout.TightSize()
+		layouts[level] = layout
+	}
+
+	// DDS stores the levels of one layer after the other; TextureData
+	// stores the layers of one level after the other.
+	d.Levels = make([][]byte, d.MipLevelCount)
+	for level, layout := range layouts {
+		d.Levels[level] = make([]byte, 0, layout.TightSize())
+	}
+	for layer := uint32(0); layer < layers; layer++ {
+		for level, layout := range layouts {
+			size := int(layout.TightSize() / uint64(layers))
+			d.Levels[level] = append(d.Levels[level], data[offset:offset+size]...)
+			offset += size
+		}
+	}
+	return d, nil
+}
+
+// ddsLegacyFormat returns the texture format of a DDS_PIXELFORMAT, or
+// TextureFormatUndefined if it is not supported.
+func ddsLegacyFormat(pf []byte) TextureFormat {
+	u32 := func(offset int) uint32 { return binary.LittleEndian.Uint32(pf[offset:]) }
+	flags, fourCC, bits := u32(4), pf[8:12], u32(12)
+	r, g, b, a := u32(16), u32(20), u32(24), u32(28)
+
+	switch {
+	case flags&ddpfFourCC != 0:
+		switch string(fourCC) {
+		case "DXT1":
+			return TextureFormatBC1RGBAUnorm
+		case "DXT2", "DXT3":
+			return TextureFormatBC2RGBAUnorm
+		case "DXT4", "DXT5":
+			return TextureFormatBC3RGBAUnorm
+		case "ATI1", "BC4U":
+			return TextureFormatBC4RUnorm
+		case "BC4S":
+			return TextureFormatBC4RSnorm
+		case "ATI2", "BC5U":
+			return TextureFormatBC5RGUnorm
+		case "BC5S":
+			return TextureFormatBC5RGSnorm
+		}
+		// Some writers store a D3DFORMAT in place of the four
+		// characters.
+		switch binary.LittleEndian.Uint32(fourCC) {
+		case 111: // D3DFMT_R16F
+			return TextureFormatR16Float
+		case 112: // D3DFMT_G16R16F
+			return TextureFormatRG16Float
+		case 113: // D3DFMT_A16B16G16R16F
+			return TextureFormatRGBA16Float
+		case 114: // D3DFMT_R32F
+			return TextureFormatR32Float
+		case 115: // D3DFMT_G32R32F
+			return TextureFormatRG32Float
+		case 116: // D3DFMT_A32B32G32R32F
+			return TextureFormatRGBA32Float
+		}
+	case flags&ddpfRGB != 0 && bits == 32:
+		if flags&ddpfAlphaPixels == 0 {
+			// X8R8G8B8 and X8B8G8R8: the texture has the unused
+			// byte as alpha.
+			a = 0xff000000
+		}
+		switch {
+		case r == 0xff && g == 0xff00 && b == 0xff0000 && a == 0xff000000:
+			return TextureFormatRGBA8Unorm
+		case r == 0xff0000 && g == 0xff00 && b == 0xff && a == 0xff000000:
+			return TextureFormatBGRA8Unorm
+		}
+	case flags&ddpfLuminance != 0 && bits == 8:
+		return TextureFormatR8Unorm
+	}
+	return TextureFormatUndefined
+}
+
+// dxgiFormats maps DXGI_FORMAT values to texture formats, see
+// https://learn.microsoft.com/en-us/windows/win32/api/dxgiformat/ne-dxgiformat-dxgi_format
+var dxgiFormats = map[uint32]TextureFormat{
+	2:  TextureFormatRGBA32Float,
+	3:  TextureFormatRGBA32Uint,
+	4:  TextureFormatRGBA32Sint,
+	10: TextureFormatRGBA16Float,
+	12: TextureFormatRGBA16Uint,
+	14: TextureFormatRGBA16Sint,
+	16: TextureFormatRG32Float,
+	17: TextureFormatRG32Uint,
+	18: TextureFormatRG32Sint,
+	24: TextureFormatRGB10A2Unorm,
+	25: TextureFormatRGB10A2Uint,
+	26: TextureFormatRG11B10Ufloat,
+	28: TextureFormatRGBA8Unorm,
+	29: TextureFormatRGBA8UnormSrgb,
+	30: TextureFormatRGBA8Uint,
+	31: TextureFormatRGBA8Snorm,
+	32: TextureFormatRGBA8Sint,
+	34: TextureFormatRG16Float,
+	36: TextureFormatRG16Uint,
+	38: TextureFormatRG16Sint,
+	40: TextureFormatDepth32Float,
+	41: TextureFormatR32Float,
+	42: TextureFormatR32Uint,
+	43: TextureFormatR32Sint,
+	49: TextureFormatRG8Unorm,
+	50: TextureFormatRG8Uint,
+	51: TextureFormatRG8Snorm,
+	52: TextureFormatRG8Sint,
+	54: TextureFormatR16Float,
+	55: TextureFormatDepth16Unorm,
+	57: TextureFormatR16Uint,
+	59: TextureFormatR16Sint,
+	61: TextureFormatR8Unorm,
+	62: TextureFormatR8Uint,
+	63: TextureFormatR8Snorm,
+	64: TextureFormatR8Sint,
+	67: TextureFormatRGB9E5Ufloat,
+	71: TextureFormatBC1RGBAUnorm,
+	72: TextureFormatBC1RGBAUnormSrgb,
+	74: TextureFormatBC2RGBAUnorm,
+	75: TextureFormatBC2RGBAUnormSrgb,
+	77: TextureFormatBC3RGBAUnorm,
+	78: TextureFormatBC3RGBAUnormSrgb,
+	80: TextureFormatBC4RUnorm,
+	81: TextureFormatBC4RSnorm,
+	83: TextureFormatBC5RGUnorm,
+	84: TextureFormatBC5RGSnorm,
+	87: TextureFormatBGRA8Unorm,
+	91: TextureFormatBGRA8UnormSrgb,
+	95: TextureFormatBC6HRGBUfloat,
+	96: TextureFormatBC6HRGBFloat,
+	98: TextureFormatBC7RGBAUnorm,
+	99: TextureFormatBC7RGBAUnormSrgb,
+}
@@ -8,92 +8,114 @@ package wgpu
 #include "./lib/wgpu.h"
 
 extern void gowebgpu_error_callback_c(WGPUErrorType type, char const * message, void * userdata);
+extern void gowebgpu_push_error_scopes(WGPUDevice device);
+extern void gowebgpu_pop_error_scopes(WGPUDevice device, void * userdata);
+extern _Thread_local void * gowebgpu_unscoped_error_userdata;
 
 static inline WGPUBindGroup gowebgpu_device_create_bind_group(WGPUDevice device, WGPUBindGroupDescriptor const * descriptor, void * error_userdata) {
 	WGPUBindGroup ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuDeviceCreateBindGroup(device, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref;
 }
 
 static inline WGPUBindGroupLayout gowebgpu_device_create_bind_group_layout(WGPUDevice device, WGPUBindGroupLayoutDescriptor const * descriptor, void * error_userdata) {
 	WGPUBindGroupLayout ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuDeviceCreateBindGroupLayout(device, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref;
 }
 
 static inline WGPUBuffer gowebgpu_device_create_buffer(WGPUDevice device, WGPUBufferDescriptor const * descriptor, void * error_userdata) {
 	WGPUBuffer ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuDeviceCreateBuffer(device, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref;
 }
 
 static inline WGPUCommandEncoder gowebgpu_device_create_command_encoder(WGPUDevice device, WGPUCommandEncoderDescriptor const * descriptor, void * error_userdata) {
 	WGPUCommandEncoder ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuDeviceCreateCommandEncoder(device, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref;
 }
 
 static inline WGPUComputePipeline gowebgpu_device_create_compute_pipeline(WGPUDevice device, WGPUComputePipelineDescriptor const * descriptor, void * error_userdata) {
 	WGPUComputePipeline ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuDeviceCreateComputePipeline(device, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
+	return ref;
+}
+
+// gowebgpu_device_create_compute_pipeline_unscoped creates the pipeline
+// without error scopes, so without the device's scope lock. Its errors
+// reach error_userdata through the uncaptured error callback.
+static inline WGPUComputePipeline gowebgpu_device_create_compute_pipeline_unscoped(WGPUDevice device, WGPUComputePipelineDescriptor const * descriptor, void * error_userdata) {
+	WGPUComputePipeline ref = NULL;
+	gowebgpu_unscoped_error_userdata = error_userdata;
+	ref = wgpuDeviceCreateComputePipeline(device, descriptor);
+	gowebgpu_unscoped_error_userdata = NULL;
 	return ref;
 }
 
 static inline WGPUPipelineLayout gowebgpu_device_create_pipeline_layout(WGPUDevice device, WGPUPipelineLayoutDescriptor const * descriptor, void * error_userdata) {
 	WGPUPipelineLayout ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuDeviceCreatePipelineLayout(device, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref;
 }
 
 static inline WGPUQuerySet gowebgpu_device_create_query_set(WGPUDevice device, WGPUQuerySetDescriptor const * descriptor, void * error_userdata) {
 	WGPUQuerySet ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuDeviceCreateQuerySet(device, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref;
 }
 
 static inline WGPURenderPipeline gowebgpu_device_create_render_pipeline(WGPUDevice device, WGPURenderPipelineDescriptor const * descriptor, void * error_userdata) {
 	WGPURenderPipeline ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuDeviceCreateRenderPipeline(device, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
+	return ref;
+}
+
+static inline WGPURenderPipeline gowebgpu_device_create_render_pipeline_unscoped(WGPUDevice device, WGPURenderPipelineDescriptor const * descriptor, void * error_userdata) {
+	WGPURenderPipeline ref = NULL;
+	gowebgpu_unscoped_error_userdata = error_userdata;
+	ref = wgpuDeviceCreateRenderPipeline(device, descriptor);
+	gowebgpu_unscoped_error_userdata = NULL;
 	return ref;
 }
 
 static inline WGPUSampler gowebgpu_device_create_sampler(WGPUDevice device, WGPUSamplerDescriptor const * descriptor, void * error_userdata) {
 	WGPUSampler ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuDeviceCreateSampler(device, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref;
 }
 
 static inline WGPUShaderModule gowebgpu_device_create_shader_module(WGPUDevice device, WGPUShaderModuleDescriptor const * descriptor, void * error_userdata) {
 	WGPUShaderModule ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuDeviceCreateShaderModule(device, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref;
 }
 
 static inline WGPUTexture gowebgpu_device_create_texture(WGPUDevice device, WGPUTextureDescriptor const * descriptor, void * error_userdata) {
 	WGPUTexture ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuDeviceCreateTexture(device, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref;
 }
 
@@ -102,15 +124,144 @@ import "C"
 import (
 	"errors"
 	"runtime/cgo"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
 type Device struct {
-	ref C.WGPUDevice
+	ref   C.WGPUDevice
+	label string
+	refs  refCount
+
+	// id identifies the device to the callbacks installed by
+	// Adapter.RequestDevice, which outlive the Go value.
+	id uintptr
+
+	// errorScopes counts the scopes pushed with PushErrorScope, since
+	// wgpu-native aborts when popping an empty stack.
+	errorScopes atomic.Int32
+}
+
+// deviceState is the Go-side state of a device, looked up by id from
+// the callbacks wgpu-native calls.
+type deviceState struct {
+	uncapturedError func(*Error)
+	lostCallback    DeviceLostCallback
+	lost            *deviceLost
+	mipmaps         *MipmapGenerator
+	pipelineCache   *PipelineCache
+	poller          *Poller
+
+	// submitMu is shared by the queues of the device, so that
+	// Queue.SubmitTracked tracks its own submission.
+	submitMu sync.Mutex
+}
+
+var (
+	devicesMu    sync.Mutex
+	devices      = map[uintptr]*deviceState{}
+	lastDeviceID uintptr
+)
+
+// newDeviceState registers the state of a device about to be created.
+func newDeviceState(lostCallback DeviceLostCallback) uintptr {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	lastDeviceID++
+	devices[lastDeviceID] = &deviceState{lostCallback: lostCallback, lost: newDeviceLost()}
+	return lastDeviceID
+}
+
+func lookupDeviceState(id uintptr) *deviceState {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	return devices[id]
+}
+
+// mipmapGenerator returns the generator GenerateMipmaps uses, creating
+// it on first use.
+func (p *Device) mipmapGenerator() *MipmapGenerator {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	s := devices[p.id]
+	if s == nil {
+		// The device was released; the generator's calls fail.
+		return NewMipmapGenerator(p)
+	}
+	if s.mipmaps == nil {
+		s.mipmaps = NewMipmapGenerator(p)
+	}
+	return s.mipmaps
+}
+
+// PipelineCache returns the cache of the device, creating it on first
+// use. It is released with the device. The cache of a released device is
+// released, so its requests fail.
+func (p *Device) PipelineCache() *PipelineCache {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	s := devices[p.id]
+	if s == nil {
+		// The device was released.
+		return releasedPipelineCache(p)
+	}
+	if s.pipelineCache == nil {
+		s.pipelineCache = NewPipelineCache(p)
+	}
+	return s.pipelineCache
+}
+
+func (p *Device) lostState() *deviceLost {
+	if s := lookupDeviceState(p.id); s != nil {
+		return s.lost
+	}
+	// The device was released.
+	return releasedDeviceLost()
 }
 
 type errorCallback func(typ ErrorType, message string)
 
+// newErrorCallback returns an errorCallback that stores the first error
+// reported for op in err.
+func newErrorCallback(err *error, op, label string) errorCallback {
+	return func(typ ErrorType, message string) {
+		if *err == nil {
+			*err = &Error{Op: op, Label: label, Type: typ, Message: message}
+		}
+	}
+}
+
+// scopeLocks holds a mutex per device, locked from the push to the pop
+// of the error scopes of a call or of PushErrorScope and PopErrorScope,
+// since wgpu-native keeps one stack of scopes per device: without it,
+// the calls of concurrent goroutines would pop each other's scopes and
+// get each other's errors. The mutexes are keyed by the device pointer
+// and kept after Release, since the objects of the device can still
+// make calls.
+//
+// The mutexes are not reentrant: Go code that wgpu-native calls back
+// during a wrapped call must not make another wrapped call on the same
+// device, or it deadlocks. The device lost callback, the only user code
+// wgpu-native can call there, runs on its own goroutine for that reason.
+var scopeLocks sync.Map
+
+func scopeLock(device C.WGPUDevice) *sync.Mutex {
+	m, _ := scopeLocks.LoadOrStore(uintptr(unsafe.Pointer(device)), new(sync.Mutex))
+	return m.(*sync.Mutex)
+}
+
+//export gowebgpu_lock_error_scopes
+func gowebgpu_lock_error_scopes(device C.WGPUDevice) {
+	scopeLock(device).Lock()
+}
+
+//export gowebgpu_unlock_error_scopes
+func gowebgpu_unlock_error_scopes(device C.WGPUDevice) {
+	scopeLock(device).Unlock()
+}
+
 //export gowebgpu_error_callback_go
 func gowebgpu_error_callback_go(_type C.WGPUErrorType, message *C.char, userdata unsafe.Pointer) {
 	handle := *(*cgo.Handle)(userdata)
@@ -120,9 +271,119 @@ func gowebgpu_error_callback_go(_type C.WGPUErrorType, message *C.char, userdata
 	}
 }
 
-func (p *Device) Release() { C.wgpuDeviceRelease(p.ref) }
+//export gowebgpu_uncaptured_error_callback_go
+func gowebgpu_uncaptured_error_callback_go(_type C.WGPUErrorType, message *C.char, id C.uintptr_t) {
+	s := lookupDeviceState(uintptr(id))
+	if s == nil {
+		return
+	}
+	devicesMu.Lock()
+	handler := s.uncapturedError
+	devicesMu.Unlock()
+
+	if handler != nil {
+		handler(&Error{Type: ErrorType(_type), Message: C.GoString(message)})
+	}
+}
+
+//export gowebgpu_device_lost_callback_go
+func gowebgpu_device_lost_callback_go(reason C.WGPUDeviceLostReason, message *C.char, id C.uintptr_t) {
+	s := lookupDeviceState(uintptr(id))
+	if s == nil {
+		return
+	}
+	// The loss can be reported during any call on the device, which may
+	// hold its scope lock.
+	go s.lose(DeviceLostInfo{Reason: DeviceLostReason(reason), Message: C.GoString(message)})
+}
+
+// lose records the loss of the device and calls the DeviceLostCallback of
+// its descriptor, once.
+func (s *deviceState) lose(info DeviceLostInfo) {
+	if s.lost.lose(info) && s.lostCallback != nil {
+		s.lostCallback(info.Reason, info.Message)
+	}
+}
+
+// Release releases the device. The DeviceLostCallback of its descriptor
+// is called and the channels returned by Lost receive
+// DeviceLostReasonDestroyed, unless the device was lost before, and the
+// context returned by Context is cancelled.
+func (p *Device) Release() {
+	if !p.refs.release("Device.Release", p.label) {
+		return
+	}
+
+	devicesMu.Lock()
+	s := devices[p.id]
+	delete(devices, p.id)
+	devicesMu.Unlock()
+
+	if s != nil && s.poller != nil {
+		s.poller.signal()
+	}
+	if s != nil && s.mipmaps != nil {
+		s.mipmaps.Release()
+	}
+	if s != nil && s.pipelineCache != nil {
+		s.pipelineCache.Release()
+	}
+	C.wgpuDeviceRelease(p.ref)
+	// The state is gone when wgpu-native reports the loss, so report it
+	// here.
+	if s != nil {
+		s.lose(DeviceLostInfo{Reason: DeviceLostReasonDestroyed, Message: "device released"})
+	}
+}
+
+// PushErrorScope pushes a scope that captures the errors matching filter
+// until the matching PopErrorScope. Calls that return an error capture
+// their own errors in inner scopes, so the scope sees the errors of
+// calls that do not, such as RenderPassEncoder.Draw.
+func (p *Device) PushErrorScope(filter ErrorFilter) {
+	p.refs.check("Device.PushErrorScope", p.label)
+	p.errorScopes.Add(1)
+	lock := scopeLock(p.ref)
+	lock.Lock()
+	defer lock.Unlock()
+	C.wgpuDevicePushErrorScope(p.ref, C.WGPUErrorFilter(filter))
+}
+
+// PopErrorScope pops the scope pushed by the last PushErrorScope and
+// returns the first error it captured, or nil.
+func (p *Device) PopErrorScope() (err error) {
+	p.refs.check("Device.PopErrorScope", p.label)
+	if p.errorScopes.Add(-1) < 0 {
+		p.errorScopes.Add(1)
+		return &Error{Op: "Device.PopErrorScope", Label: p.label, Type: ErrorTypeUnknown, Message: "no error scope to pop"}
+	}
+
+	cb := newErrorCallback(&err, "Device.PopErrorScope", p.label)
+	errorCallbackHandle := cgo.NewHandle(cb)
+	defer errorCallbackHandle.Delete()
+
+	lock := scopeLock(p.ref)
+	lock.Lock()
+	defer lock.Unlock()
+	C.wgpuDevicePopErrorScope(p.ref, C.WGPUErrorCallback(C.gowebgpu_error_callback_c), unsafe.Pointer(&errorCallbackHandle))
+	return
+}
+
+// SetUncapturedErrorHandler sets the function called with errors that
+// no error scope captures. A nil handler removes the previous one. The
+// handler may run on any goroutine, during the call that caused the
+// error.
+func (p *Device) SetUncapturedErrorHandler(handler func(*Error)) {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	if s := devices[p.id]; s != nil {
+		s.uncapturedError = handler
+	}
+}
 
 func (p *Device) CreateBindGroup(descriptor *BindGroupDescriptor) (*BindGroup, error) {
+	p.refs.check("Device.CreateBindGroup", p.label)
 	var desc C.WGPUBindGroupDescriptor
 
 	if descriptor != nil {
@@ -134,6 +395,7 @@ func (p *Device) CreateBindGroup(descriptor *BindGroupDescriptor) (*BindGroup, e
 		}
 
 		if descriptor.Layout != nil {
+			descriptor.Layout.refs.check("Device.CreateBindGroup", descriptor.Layout.label)
 			desc.layout = descriptor.Layout.ref
 		}
 
@@ -152,12 +414,15 @@ func (p *Device) CreateBindGroup(descriptor *BindGroupDescriptor) (*BindGroup, e
 				}
 
 				if v.Buffer != nil {
+					v.Buffer.refs.check("Device.CreateBindGroup", v.Buffer.label)
 					entry.buffer = v.Buffer.ref
 				}
 				if v.Sampler != nil {
+					v.Sampler.refs.check("Device.CreateBindGroup", v.Sampler.label)
 					entry.sampler = v.Sampler.ref
 				}
 				if v.TextureView != nil {
+					v.TextureView.refs.check("Device.CreateBindGroup", v.TextureView.label)
 					entry.textureView = v.TextureView.ref
 				}
 
@@ -170,9 +435,11 @@ func (p *Device) CreateBindGroup(descriptor *BindGroupDescriptor) (*BindGroup, e
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Device).CreateBindGroup(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, "Device.CreateBindGroup", label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -186,7 +453,9 @@ func (p *Device) CreateBindGroup(descriptor *BindGroupDescriptor) (*BindGroup, e
 		return nil, err
 	}
 
-	return &BindGroup{ref}, nil
+	group := &BindGroup{ref: ref, label: label}
+	track(group, "BindGroup", label)
+	return group, nil
 }
 
 type BufferBindingLayout struct {
@@ -220,7 +489,14 @@ type BindGroupLayoutEntry struct {
 	StorageTexture StorageTextureBindingLayout
 }
 
+// setExternalTextureLayout reports that texture_external bindings are
+// not available in wgpu-native.
+func setExternalTextureLayout(*BindGroupLayoutEntry) error {
+	return errors.New("texture_external is not supported by wgpu-native")
+}
+
 func (p *Device) CreateBindGroupLayout(descriptor *BindGroupLayoutDescriptor) (*BindGroupLayout, error) {
+	p.refs.check("Device.CreateBindGroupLayout", p.label)
 	var desc C.WGPUBindGroupLayoutDescriptor
 
 	if descriptor != nil {
@@ -274,9 +550,11 @@ func (p *Device) CreateBindGroupLayout(descriptor *BindGroupLayoutDescriptor) (*
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Device).CreateBindGroupLayout(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, "Device.CreateBindGroupLayout", label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -290,10 +568,13 @@ func (p *Device) CreateBindGroupLayout(descriptor *BindGroupLayoutDescriptor) (*
 		return nil, err
 	}
 
-	return &BindGroupLayout{ref}, nil
+	layout := &BindGroupLayout{ref: ref, label: label}
+	track(layout, "BindGroupLayout", label)
+	return layout, nil
 }
 
 func (p *Device) CreateBuffer(descriptor *BufferDescriptor) (*Buffer, error) {
+	p.refs.check("Device.CreateBuffer", p.label)
 	var desc C.WGPUBufferDescriptor
 
 	if descriptor != nil {
@@ -310,9 +591,11 @@ func (p *Device) CreateBuffer(descriptor *BufferDescriptor) (*Buffer, error) {
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Device).CreateBuffer(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, "Device.CreateBuffer", label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -327,10 +610,13 @@ func (p *Device) CreateBuffer(descriptor *BufferDescriptor) (*Buffer, error) {
 	}
 
 	C.wgpuDeviceReference(p.ref)
-	return &Buffer{deviceRef: p.ref, ref: ref}, nil
+	buffer := &Buffer{deviceRef: p.ref, ref: ref, device: p, label: label}
+	track(buffer, "Buffer", label)
+	return buffer, nil
 }
 
 func (p *Device) CreateCommandEncoder(descriptor *CommandEncoderDescriptor) (*CommandEncoder, error) {
+	p.refs.check("Device.CreateCommandEncoder", p.label)
 	var desc *C.WGPUCommandEncoderDescriptor
 
 	if descriptor != nil && descriptor.Label != "" {
@@ -343,9 +629,11 @@ func (p *Device) CreateCommandEncoder(descriptor *CommandEncoderDescriptor) (*Co
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Device).CreateCommandEncoder(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, "Device.CreateCommandEncoder", label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -360,12 +648,9 @@ func (p *Device) CreateCommandEncoder(descriptor *CommandEncoderDescriptor) (*Co
 	}
 
 	C.wgpuDeviceReference(p.ref)
-	return &CommandEncoder{deviceRef: p.ref, ref: ref}, nil
-}
-
-type ConstantEntry struct {
-	Key   string
-	Value float64
+	encoder := &CommandEncoder{deviceRef: p.ref, ref: ref, label: label}
+	track(encoder, "CommandEncoder", label)
+	return encoder, nil
 }
 
 type ComputePipelineDescriptor struct {
@@ -374,7 +659,45 @@ type ComputePipelineDescriptor struct {
 	Compute ProgrammableStageDescriptor
 }
 
+// cConstants converts constants to C. The keys are allocated with the
+// array, which is to be freed with C.free and is nil if constants is
+// empty.
+func cConstants(constants []ConstantEntry) (C.size_t, *C.WGPUConstantEntry) {
+	if len(constants) == 0 {
+		return 0, nil
+	}
+	entriesSize := len(constants) * int(unsafe.Sizeof(C.WGPUConstantEntry{}))
+	keysSize := 0
+	for _, c := range constants {
+		keysSize += len(c.Key) + 1
+	}
+	entries := C.malloc(C.size_t(entriesSize + keysSize))
+	entriesSlice := unsafe.Slice((*C.WGPUConstantEntry)(entries), len(constants))
+	keys := unsafe.Slice((*byte)(unsafe.Add(entries, entriesSize)), keysSize)
+	for i, c := range constants {
+		n := copy(keys, c.Key)
+		keys[n] = 0
+		entriesSlice[i] = C.WGPUConstantEntry{
+			key:   (*C.char)(unsafe.Pointer(&keys[0])),
+			value: C.double(c.Value),
+		}
+		keys = keys[n+1:]
+	}
+	return C.size_t(len(constants)), (*C.WGPUConstantEntry)(entries)
+}
+
 func (p *Device) CreateComputePipeline(descriptor *ComputePipelineDescriptor) (*ComputePipeline, error) {
+	return p.createComputePipeline("Device.CreateComputePipeline", descriptor, false)
+}
+
+// createComputePipeline creates a compute pipeline, without error scopes if
+// unscoped is set. See CreateComputePipelineAsync.
+func (p *Device) createComputePipeline(op string, descriptor *ComputePipelineDescriptor, unscoped bool) (*ComputePipeline, error) {
+	p.refs.check(op, p.label)
+	if err := validateComputeConstants(op, descriptor); err != nil {
+		return nil, err
+	}
+
 	var desc C.WGPUComputePipelineDescriptor
 
 	if descriptor != nil {
@@ -386,11 +709,13 @@ func (p *Device) CreateComputePipeline(descriptor *ComputePipelineDescriptor) (*
 		}
 
 		if descriptor.Layout != nil {
+			descriptor.Layout.refs.check(op, descriptor.Layout.label)
 			desc.layout = descriptor.Layout.ref
 		}
 
 		var compute C.WGPUProgrammableStageDescriptor
 		if descriptor.Compute.Module != nil {
+			descriptor.Compute.Module.refs.check(op, descriptor.Compute.Module.label)
 			compute.module = descriptor.Compute.Module.ref
 		}
 		if descriptor.Compute.EntryPoint != "" {
@@ -399,27 +724,44 @@ func (p *Device) CreateComputePipeline(descriptor *ComputePipelineDescriptor) (*
 
 			compute.entryPoint = entryPoint
 		}
+		constantCount, constants := cConstants(descriptor.Compute.Constants)
+		defer C.free(unsafe.Pointer(constants))
+		compute.constantCount = constantCount
+		compute.constants = constants
 		desc.compute = compute
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Device).CreateComputePipeline(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, op, label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
-	ref := C.gowebgpu_device_create_compute_pipeline(
-		p.ref,
-		&desc,
-		unsafe.Pointer(&errorCallbackHandle),
-	)
+	var ref C.WGPUComputePipeline
+	if unscoped {
+		ref = C.gowebgpu_device_create_compute_pipeline_unscoped(
+			p.ref,
+			&desc,
+			unsafe.Pointer(&errorCallbackHandle),
+		)
+	} else {
+		ref = C.gowebgpu_device_create_compute_pipeline(
+			p.ref,
+			&desc,
+			unsafe.Pointer(&errorCallbackHandle),
+		)
+	}
 	if err != nil {
 		C.wgpuComputePipelineRelease(ref)
 		return nil, err
 	}
 
-	return &ComputePipeline{ref}, nil
+	pipeline := &ComputePipeline{ref: ref, label: label}
+	track(pipeline, "ComputePipeline", label)
+	return pipeline, nil
 }
 
 type PushConstantRange struct {
@@ -435,6 +777,7 @@ type PipelineLayoutDescriptor struct {
 }
 
 func (p *Device) CreatePipelineLayout(descriptor *PipelineLayoutDescriptor) (*PipelineLayout, error) {
+	p.refs.check("Device.CreatePipelineLayout", p.label)
 	var desc C.WGPUPipelineLayoutDescriptor
 
 	if descriptor != nil {
@@ -453,6 +796,7 @@ func (p *Device) CreatePipelineLayout(descriptor *PipelineLayoutDescriptor) (*Pi
 			bindGroupLayoutsSlice := unsafe.Slice((*C.WGPUBindGroupLayout)(bindGroupLayouts), bindGroupLayoutCount)
 
 			for i, v := range descriptor.BindGroupLayouts {
+				v.refs.check("Device.CreatePipelineLayout", v.label)
 				bindGroupLayoutsSlice[i] = v.ref
 			}
 
@@ -491,9 +835,11 @@ func (p *Device) CreatePipelineLayout(descriptor *PipelineLayoutDescriptor) (*Pi
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Device).CreatePipelineLayout(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, "Device.CreatePipelineLayout", label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -507,7 +853,9 @@ func (p *Device) CreatePipelineLayout(descriptor *PipelineLayoutDescriptor) (*Pi
 		return nil, err
 	}
 
-	return &PipelineLayout{ref}, nil
+	layout := &PipelineLayout{ref: ref, label: label}
+	track(layout, "PipelineLayout", label)
+	return layout, nil
 }
 
 type QuerySetDescriptor struct {
@@ -518,6 +866,7 @@ type QuerySetDescriptor struct {
 }
 
 func (p *Device) CreateQuerySet(descriptor *QuerySetDescriptor) (*QuerySet, error) {
+	p.refs.check("Device.CreateQuerySet", p.label)
 	var desc C.WGPUQuerySetDescriptor
 
 	if descriptor != nil {
@@ -545,9 +894,11 @@ func (p *Device) CreateQuerySet(descriptor *QuerySetDescriptor) (*QuerySet, erro
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Device).CreateQuerySet(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, "Device.CreateQuerySet", label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -561,7 +912,9 @@ func (p *Device) CreateQuerySet(descriptor *QuerySetDescriptor) (*QuerySet, erro
 		return nil, err
 	}
 
-	return &QuerySet{ref: ref}, nil
+	querySet := &QuerySet{ref: ref, label: label}
+	track(querySet, "QuerySet", label)
+	return querySet, nil
 }
 
 type RenderBundleEncoderDescriptor struct {
@@ -574,6 +927,7 @@ type RenderBundleEncoderDescriptor struct {
 }
 
 func (p *Device) CreateRenderBundleEncoder(descriptor *RenderBundleEncoderDescriptor) (*RenderBundleEncoder, error) {
+	p.refs.check("Device.CreateRenderBundleEncoder", p.label)
 	var desc C.WGPURenderBundleEncoderDescriptor
 
 	if descriptor != nil {
@@ -603,7 +957,12 @@ func (p *Device) CreateRenderBundleEncoder(descriptor *RenderBundleEncoderDescri
 
 	ref := C.wgpuDeviceCreateRenderBundleEncoder(p.ref, &desc)
 
-	return &RenderBundleEncoder{ref}, nil
+	encoder := &RenderBundleEncoder{ref: ref}
+	if descriptor != nil {
+		encoder.label = descriptor.Label
+	}
+	track(encoder, "RenderBundleEncoder", encoder.label)
+	return encoder, nil
 }
 
 type BlendComponent struct {
@@ -627,9 +986,7 @@ type FragmentState struct {
 	Module     *ShaderModule
 	EntryPoint string
 	Targets    []ColorTargetState
-
-	// unused in wgpu
-	// Constants  []ConstantEntry
+	Constants  []ConstantEntry
 }
 
 type VertexAttribute struct {
@@ -648,9 +1005,7 @@ type VertexState struct {
 	Module     *ShaderModule
 	EntryPoint string
 	Buffers    []VertexBufferLayout
-
-	// unused in wgpu
-	// Constants  []ConstantEntry
+	Constants  []ConstantEntry
 }
 
 type PrimitiveState struct {
@@ -687,6 +1042,17 @@ type MultisampleState struct {
 }
 
 func (p *Device) CreateRenderPipeline(descriptor *RenderPipelineDescriptor) (*RenderPipeline, error) {
+	return p.createRenderPipeline("Device.CreateRenderPipeline", descriptor, false)
+}
+
+// createRenderPipeline creates a render pipeline, without error scopes if
+// unscoped is set. See CreateComputePipelineAsync.
+func (p *Device) createRenderPipeline(op string, descriptor *RenderPipelineDescriptor, unscoped bool) (*RenderPipeline, error) {
+	p.refs.check(op, p.label)
+	if err := validateRenderConstants(op, descriptor); err != nil {
+		return nil, err
+	}
+
 	var desc C.WGPURenderPipelineDescriptor
 
 	if descriptor != nil {
@@ -698,6 +1064,7 @@ func (p *Device) CreateRenderPipeline(descriptor *RenderPipelineDescriptor) (*Re
 		}
 
 		if descriptor.Layout != nil {
+			descriptor.Layout.refs.check(op, descriptor.Layout.label)
 			desc.layout = descriptor.Layout.ref
 		}
 
@@ -708,6 +1075,7 @@ func (p *Device) CreateRenderPipeline(descriptor *RenderPipelineDescriptor) (*Re
 			var vert C.WGPUVertexState
 
 			if vertex.Module != nil {
+				vertex.Module.refs.check(op, vertex.Module.label)
 				vert.module = vertex.Module.ref
 			}
 
@@ -757,6 +1125,11 @@ func (p *Device) CreateRenderPipeline(descriptor *RenderPipelineDescriptor) (*Re
 				vert.buffers = (*C.WGPUVertexBufferLayout)(buffers)
 			}
 
+			constantCount, constants := cConstants(vertex.Constants)
+			defer C.free(unsafe.Pointer(constants))
+			vert.constantCount = constantCount
+			vert.constants = constants
+
 			desc.vertex = vert
 		}
 
@@ -819,6 +1192,7 @@ func (p *Device) CreateRenderPipeline(descriptor *RenderPipelineDescriptor) (*Re
 			}
 
 			if fragment.Module != nil {
+				fragment.Module.refs.check(op, fragment.Module.label)
 				frag.module = fragment.Module.ref
 			}
 
@@ -862,34 +1236,50 @@ func (p *Device) CreateRenderPipeline(descriptor *RenderPipelineDescriptor) (*Re
 				frag.targetCount = 0
 				frag.targets = nil
 			}
-			frag.constantCount = 0 // note: crashes on linux arm64 without setting this to 0
-			frag.constants = nil // even though wgpu doesn't even support it.
+			constantCount, constants := cConstants(fragment.Constants)
+			defer C.free(unsafe.Pointer(constants))
+			frag.constantCount = constantCount
+			frag.constants = constants
 
 			desc.fragment = frag
 		}
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Device).CreateRenderPipeline(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, op, label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
-	ref := C.gowebgpu_device_create_render_pipeline(
-		p.ref,
-		&desc,
-		unsafe.Pointer(&errorCallbackHandle),
-	)
+	var ref C.WGPURenderPipeline
+	if unscoped {
+		ref = C.gowebgpu_device_create_render_pipeline_unscoped(
+			p.ref,
+			&desc,
+			unsafe.Pointer(&errorCallbackHandle),
+		)
+	} else {
+		ref = C.gowebgpu_device_create_render_pipeline(
+			p.ref,
+			&desc,
+			unsafe.Pointer(&errorCallbackHandle),
+		)
+	}
 	if err != nil {
 		C.wgpuRenderPipelineRelease(ref)
 		return nil, err
 	}
 
-	return &RenderPipeline{ref}, nil
+	pipeline := &RenderPipeline{ref: ref, label: label}
+	track(pipeline, "RenderPipeline", label)
+	return pipeline, nil
 }
 
 func (p *Device) CreateSampler(descriptor *SamplerDescriptor) (*Sampler, error) {
+	p.refs.check("Device.CreateSampler", p.label)
 	var desc *C.WGPUSamplerDescriptor
 
 	if descriptor != nil {
@@ -915,9 +1305,11 @@ func (p *Device) CreateSampler(descriptor *SamplerDescriptor) (*Sampler, error)
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Device).CreateSampler(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, "Device.CreateSampler", label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -931,7 +1323,9 @@ func (p *Device) CreateSampler(descriptor *SamplerDescriptor) (*Sampler, error)
 		return nil, err
 	}
 
-	return &Sampler{ref}, nil
+	sampler := &Sampler{ref: ref, label: label}
+	track(sampler, "Sampler", label)
+	return sampler, nil
 }
 
 type ShaderModuleSPIRVDescriptor struct {
@@ -951,7 +1345,37 @@ type ShaderModuleDescriptor struct {
 	GLSLDescriptor  *ShaderModuleGLSLDescriptor
 }
 
+// hash writes the contents of the descriptor, other than the label, for
+// PipelineCache.
+func (d *ShaderModuleDescriptor) hash(h *cacheHasher) {
+	if d.SPIRVDescriptor != nil {
+		h.uint(1)
+		h.bytes(d.SPIRVDescriptor.Code)
+	}
+	if d.WGSLDescriptor != nil {
+		h.uint(2)
+		h.string(d.WGSLDescriptor.Code)
+	}
+	if d.GLSLDescriptor != nil {
+		h.uint(3)
+		h.string(d.GLSLDescriptor.Code)
+		names := make([]string, 0, len(d.GLSLDescriptor.Defines))
+		for name := range d.GLSLDescriptor.Defines {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		h.uint(uint64(len(names)))
+		for _, name := range names {
+			h.string(name)
+			h.string(d.GLSLDescriptor.Defines[name])
+		}
+		h.uint(uint64(d.GLSLDescriptor.ShaderStage))
+	}
+	h.uint(0)
+}
+
 func (p *Device) CreateShaderModule(descriptor *ShaderModuleDescriptor) (*ShaderModule, error) {
+	p.refs.check("Device.CreateShaderModule", p.label)
 	var desc C.WGPUShaderModuleDescriptor
 
 	if descriptor != nil {
@@ -1052,9 +1476,11 @@ func (p *Device) CreateShaderModule(descriptor *ShaderModuleDescriptor) (*Shader
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Device).CreateShaderModule(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, "Device.CreateShaderModule", label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -1065,13 +1491,23 @@ func (p *Device) CreateShaderModule(descriptor *ShaderModuleDescriptor) (*Shader
 	)
 	if err != nil {
 		C.wgpuShaderModuleRelease(ref)
+		if e, ok := err.(*Error); ok && descriptor != nil && descriptor.WGSLDescriptor != nil {
+			return nil, newCompilationError(e, descriptor.WGSLDescriptor.Code)
+		}
 		return nil, err
 	}
 
-	return &ShaderModule{ref}, nil
+	var code string
+	if descriptor != nil && descriptor.WGSLDescriptor != nil {
+		code = descriptor.WGSLDescriptor.Code
+	}
+	module := &ShaderModule{ref: ref, label: label, code: code}
+	track(module, "ShaderModule", label)
+	return module, nil
 }
 
 func (p *Device) CreateTexture(descriptor *TextureDescriptor) (*Texture, error) {
+	p.refs.check("Device.CreateTexture", p.label)
 	var desc C.WGPUTextureDescriptor
 
 	if descriptor != nil {
@@ -1097,9 +1533,11 @@ func (p *Device) CreateTexture(descriptor *TextureDescriptor) (*Texture, error)
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Device).CreateTexture(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, "Device.CreateTexture", label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -1114,10 +1552,13 @@ func (p *Device) CreateTexture(descriptor *TextureDescriptor) (*Texture, error)
 	}
 
 	C.wgpuDeviceReference(p.ref)
-	return &Texture{deviceRef: p.ref, ref: ref}, nil
+	texture := &Texture{deviceRef: p.ref, ref: ref, device: p, label: label}
+	track(texture, "Texture", label)
+	return texture, nil
 }
 
 func (p *Device) EnumerateFeatures() []FeatureName {
+	p.refs.check("Device.EnumerateFeatures", p.label)
 	size := C.wgpuDeviceEnumerateFeatures(p.ref, nil)
 	if size == 0 {
 		return nil
@@ -1129,6 +1570,7 @@ func (p *Device) EnumerateFeatures() []FeatureName {
 }
 
 func (p *Device) GetLimits() SupportedLimits {
+	p.refs.check("Device.GetLimits", p.label)
 	var supportedLimits C.WGPUSupportedLimits
 
 	extras := (*C.WGPUSupportedLimitsExtras)(C.malloc(C.size_t(unsafe.Sizeof(C.WGPUSupportedLimitsExtras{}))))
@@ -1177,19 +1619,28 @@ func (p *Device) GetLimits() SupportedLimits {
 }
 
 func (p *Device) GetQueue() *Queue {
+	p.refs.check("Device.GetQueue", p.label)
 	ref := C.wgpuDeviceGetQueue(p.ref)
 	C.wgpuDeviceReference(p.ref)
-	return &Queue{deviceRef: p.ref, ref: ref}
+	queue := &Queue{deviceRef: p.ref, ref: ref, device: p, submitMu: new(sync.Mutex)}
+	if s := lookupDeviceState(p.id); s != nil {
+		queue.submitMu = &s.submitMu
+	}
+	track(queue, "Queue", "")
+	return queue
 }
 
 func (p *Device) HasFeature(feature FeatureName) bool {
+	p.refs.check("Device.HasFeature", p.label)
 	hasFeature := C.wgpuDeviceHasFeature(p.ref, C.WGPUFeatureName(feature))
 	return goBool(hasFeature)
 }
 
 func (p *Device) Poll(wait bool, wrappedSubmissionIndex *WrappedSubmissionIndex) (queueEmpty bool) {
+	p.refs.check("Device.Poll", p.label)
 	var index *C.WGPUWrappedSubmissionIndex
 	if wrappedSubmissionIndex != nil {
+		wrappedSubmissionIndex.Queue.refs.check("Device.Poll", wrappedSubmissionIndex.Queue.label)
 		index = &C.WGPUWrappedSubmissionIndex{
 			queue:           wrappedSubmissionIndex.Queue.ref,
 			submissionIndex: C.WGPUSubmissionIndex(wrappedSubmissionIndex.SubmissionIndex),
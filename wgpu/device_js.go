@@ -3,7 +3,10 @@
 package wgpu
 
 import (
+	"sync"
 	"syscall/js"
+
+	"github.com/openfluke/webgpu/jsx"
 )
 
 // NewDevice creates a new GPUDevice that uses the specified JavaScript
@@ -48,21 +51,47 @@ func (g Device) CreateBuffer(descriptor *BufferDescriptor) (*Buffer, error) {
 	jsBuffer := g.jsValue.Call("createBuffer", pointerToJS(descriptor))
 	return &Buffer{
 		jsValue: jsBuffer,
+		device:  &g,
 	}, nil
 }
 
 // CreateShaderModule as described:
 // https://gpuweb.github.io/gpuweb/#dom-gpudevice-createshadermodule
+// As on native, source that fails to compile returns a
+// *CompilationError holding the messages of the module.
 func (g Device) CreateShaderModule(desc *ShaderModuleDescriptor) (*ShaderModule, error) {
 	jsShader := g.jsValue.Call("createShaderModule", pointerToJS(desc))
-	return &ShaderModule{
+	var code, label string
+	if desc != nil {
+		label = desc.Label
+		if desc.WGSLDescriptor != nil {
+			code = desc.WGSLDescriptor.Code
+		}
+	}
+	module := &ShaderModule{
 		jsValue: jsShader,
-	}, nil
+		code:    code,
+	}
+
+	info, err := module.GetCompilationInfo()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range info.Messages {
+		if m.Type == CompilationMessageTypeError {
+			err := &Error{Op: "Device.CreateShaderModule", Label: label, Type: ErrorTypeValidation, Message: m.Message}
+			return nil, &CompilationError{Err: err, Source: code, Info: *info}
+		}
+	}
+	return module, nil
 }
 
 // CreateRenderPipeline as described:
 // https://gpuweb.github.io/gpuweb/#dom-gpudevice-createrenderpipeline
 func (g Device) CreateRenderPipeline(descriptor *RenderPipelineDescriptor) (*RenderPipeline, error) {
+	if err := validateRenderConstants("Device.CreateRenderPipeline", descriptor); err != nil {
+		return nil, err
+	}
 	jsPipeline := g.jsValue.Call("createRenderPipeline", pointerToJS(descriptor))
 	return &RenderPipeline{
 		jsValue: jsPipeline,
@@ -99,6 +128,9 @@ func (g Device) CreatePipelineLayout(descriptor *PipelineLayoutDescriptor) (*Pip
 // CreateComputePipeline as described:
 // https://gpuweb.github.io/gpuweb/#dom-gpudevice-createcomputepipeline
 func (g Device) CreateComputePipeline(descriptor *ComputePipelineDescriptor) (*ComputePipeline, error) {
+	if err := validateComputeConstants("Device.CreateComputePipeline", descriptor); err != nil {
+		return nil, err
+	}
 	jsPipeline := g.jsValue.Call("createComputePipeline", pointerToJS(descriptor))
 	return &ComputePipeline{
 		jsValue: jsPipeline,
@@ -111,6 +143,7 @@ func (g Device) CreateTexture(descriptor *TextureDescriptor) (*Texture, error) {
 	jsTexture := g.jsValue.Call("createTexture", pointerToJS(descriptor))
 	return &Texture{
 		jsValue: jsTexture,
+		device:  &g,
 	}, nil
 }
 
@@ -127,8 +160,194 @@ func (g Device) GetLimits() SupportedLimits {
 	return SupportedLimits{limitsFromJS(g.jsValue.Get("limits"))}
 }
 
+// HasFeature as described:
+// https://gpuweb.github.io/gpuweb/#dom-gpudevice-features
+func (g Device) HasFeature(feature FeatureName) bool {
+	return g.jsValue.Get("features").Call("has", feature.String()).Bool()
+}
+
 func (g Device) Poll(wait bool, wrappedSubmissionIndex *WrappedSubmissionIndex) (queueEmpty bool) {
 	return false // no-op
 }
 
-func (g Device) Release() {} // no-op
+// Release drops the Go-side state of the device. The DeviceLostCallback
+// of its descriptor is called and the channels returned by Lost receive
+// DeviceLostReasonDestroyed, unless the device was lost before, and the
+// context returned by Context is cancelled. The device stays lost
+// afterwards.
+func (g Device) Release() {
+	devicesMu.Lock()
+	g.jsValue.Set(releasedProperty, true)
+	var s *deviceState
+	for i, d := range devices {
+		if d.device.Equal(g.jsValue) {
+			s = d
+			devices = append(devices[:i], devices[i+1:]...)
+			break
+		}
+	}
+	devicesMu.Unlock()
+
+	if s != nil {
+		if s.uncapturedError.Truthy() {
+			g.jsValue.Set("onuncapturederror", js.Null())
+			s.uncapturedError.Release()
+		}
+		if s.pipelineCache != nil {
+			s.pipelineCache.Release()
+		}
+		s.lose(DeviceLostInfo{Reason: DeviceLostReasonDestroyed, Message: "device released"})
+	}
+}
+
+// PushErrorScope as described:
+// https://gpuweb.github.io/gpuweb/#dom-gpudevice-pusherrorscope
+func (g Device) PushErrorScope(filter ErrorFilter) {
+	g.jsValue.Call("pushErrorScope", enumToJS(filter))
+}
+
+// PopErrorScope as described:
+// https://gpuweb.github.io/gpuweb/#dom-gpudevice-poperrorscope
+//
+// It waits for the promise to settle, so it must not be called from a
+// JavaScript callback.
+func (g Device) PopErrorScope() error {
+	result, ok := jsx.Await(g.jsValue.Call("popErrorScope"))
+	if !ok {
+		return &Error{Op: "Device.PopErrorScope", Type: ErrorTypeUnknown, Message: result.Get("message").String()}
+	}
+	if result.IsNull() {
+		return nil
+	}
+	return errorFromJS("Device.PopErrorScope", result)
+}
+
+// deviceState is the Go-side state of a device, looked up by its
+// JavaScript value.
+type deviceState struct {
+	device          js.Value
+	uncapturedError js.Func
+	lostCallback    DeviceLostCallback
+	lost            *deviceLost
+	mipmaps         *MipmapGenerator
+	pipelineCache   *PipelineCache
+}
+
+var (
+	devicesMu sync.Mutex
+	devices   []*deviceState
+)
+
+// releasedProperty marks the JavaScript value of a released device, so
+// that its state is not created again.
+const releasedProperty = "gowebgpuReleased"
+
+// lose records the loss of the device and calls the DeviceLostCallback of
+// its descriptor, once.
+func (s *deviceState) lose(info DeviceLostInfo) {
+	if s.lost.lose(info) && s.lostCallback != nil {
+		s.lostCallback(info.Reason, info.Message)
+	}
+}
+
+// state returns the state of the device, creating it and subscribing to
+// the lost promise on first use.
+func (g Device) state() *deviceState {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	for _, s := range devices {
+		if s.device.Equal(g.jsValue) {
+			return s
+		}
+	}
+	if g.jsValue.Get(releasedProperty).Truthy() {
+		// The state is not kept, like the state of a released native
+		// device.
+		return &deviceState{device: g.jsValue, lost: releasedDeviceLost(), pipelineCache: releasedPipelineCache(&g)}
+	}
+	s := &deviceState{device: g.jsValue, lost: newDeviceLost()}
+	devices = append(devices, s)
+
+	var onLost js.Func
+	onLost = js.FuncOf(func(this js.Value, args []js.Value) any {
+		defer onLost.Release()
+		info := DeviceLostInfo{Reason: DeviceLostReasonUnknown, Message: args[0].Get("message").String()}
+		if args[0].Get("reason").String() == "destroyed" {
+			info.Reason = DeviceLostReasonDestroyed
+		}
+		s.lose(info)
+		return nil
+	})
+	g.jsValue.Get("lost").Call("then", onLost)
+	return s
+}
+
+func (g Device) lostState() *deviceLost {
+	return g.state().lost
+}
+
+// mipmapGenerator returns the generator GenerateMipmaps uses, creating
+// it on first use.
+func (g Device) mipmapGenerator() *MipmapGenerator {
+	s := g.state()
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	if s.mipmaps == nil {
+		s.mipmaps = NewMipmapGenerator(&g)
+	}
+	return s.mipmaps
+}
+
+// PipelineCache returns the cache of the device, creating it on first
+// use. It is released with the device. The cache of a released device is
+// released, so its requests fail.
+func (g Device) PipelineCache() *PipelineCache {
+	s := g.state()
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	if s.pipelineCache == nil {
+		s.pipelineCache = NewPipelineCache(&g)
+	}
+	return s.pipelineCache
+}
+
+// SetUncapturedErrorHandler sets the onuncapturederror event handler as
+// described:
+// https://gpuweb.github.io/gpuweb/#dom-gpudevice-onuncapturederror
+// A nil handler removes the previous one. The handler runs in a
+// JavaScript event handler and must not block.
+func (g Device) SetUncapturedErrorHandler(handler func(*Error)) {
+	s := g.state()
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	if s.uncapturedError.Truthy() {
+		s.uncapturedError.Release()
+		s.uncapturedError = js.Func{}
+	}
+	if handler == nil {
+		g.jsValue.Set("onuncapturederror", js.Null())
+		return
+	}
+
+	s.uncapturedError = js.FuncOf(func(this js.Value, args []js.Value) any {
+		handler(errorFromJS("", args[0].Get("error")))
+		return nil
+	})
+	g.jsValue.Set("onuncapturederror", s.uncapturedError)
+}
+
+// errorFromJS converts a GPUError to an *Error.
+func errorFromJS(op string, v js.Value) *Error {
+	typ := ErrorTypeUnknown
+	switch v.Get("constructor").Get("name").String() {
+	case "GPUValidationError":
+		typ = ErrorTypeValidation
+	case "GPUOutOfMemoryError":
+		typ = ErrorTypeOutOfMemory
+	case "GPUInternalError":
+		typ = ErrorTypeInternal
+	}
+	return &Error{Op: op, Type: typ, Message: v.Get("message").String()}
+}
@@ -0,0 +1,41 @@
+package wgpu
+
+import (
+	"context"
+	"errors"
+	"syscall/js"
+	"testing"
+)
+
+func TestReleasedDeviceIsLost(t *testing.T) {
+	var calls int
+	d := &Device{jsValue: js.Global().Get("Object").New()}
+	devicesMu.Lock()
+	devices = append(devices, &deviceState{
+		device:       d.jsValue,
+		lost:         newDeviceLost(),
+		lostCallback: func(DeviceLostReason, string) { calls++ },
+	})
+	devicesMu.Unlock()
+
+	d.Release()
+	d.Release()
+	if calls != 1 {
+		t.Errorf("DeviceLostCallback called %d times, want 1", calls)
+	}
+	select {
+	case info := <-d.Lost():
+		if info.Reason != DeviceLostReasonDestroyed {
+			t.Errorf("got reason %v, want DeviceLostReasonDestroyed", info.Reason)
+		}
+	default:
+		t.Error("Lost does not report the released device")
+	}
+	var err *Error
+	if !errors.As(context.Cause(d.Context()), &err) || err.Type != ErrorTypeDeviceLost {
+		t.Errorf("Context cause is %v, want a device lost *Error", context.Cause(d.Context()))
+	}
+	if _, err := d.PipelineCache().ShaderModule(&ShaderModuleDescriptor{}); err == nil {
+		t.Error("the pipeline cache of a released device creates objects")
+	}
+}
@@ -0,0 +1,132 @@
+package wgpu
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DeviceLostInfo describes why a device was lost.
+type DeviceLostInfo struct {
+	Reason  DeviceLostReason
+	Message string
+}
+
+// deviceLost tracks the loss of a device and notifies the channels
+// returned by Device.Lost and the context returned by Device.Context.
+type deviceLost struct {
+	mu     sync.Mutex
+	info   *DeviceLostInfo
+	chans  []chan DeviceLostInfo
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+func newDeviceLost() *deviceLost {
+	l := &deviceLost{}
+	l.ctx, l.cancel = context.WithCancelCause(context.Background())
+	return l
+}
+
+// releasedDeviceLost returns the loss of a released device.
+func releasedDeviceLost() *deviceLost {
+	l := newDeviceLost()
+	l.lose(DeviceLostInfo{Reason: DeviceLostReasonDestroyed, Message: "device released"})
+	return l
+}
+
+// lose records the loss of the device. Only the first call has an
+// effect, and it reports whether it was the first.
+func (l *deviceLost) lose(info DeviceLostInfo) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.info != nil {
+		return false
+	}
+	l.info = &info
+	l.cancel(info.error("Device.Lost"))
+	for _, ch := range l.chans {
+		ch <- info
+		close(ch)
+	}
+	l.chans = nil
+	return true
+}
+
+func (l *deviceLost) channel() <-chan DeviceLostInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch := make(chan DeviceLostInfo, 1)
+	if l.info != nil {
+		ch <- *l.info
+		close(ch)
+	} else {
+		l.chans = append(l.chans, ch)
+	}
+	return ch
+}
+
+// err returns the device-lost error for op, or nil if the device was
+// not lost.
+func (l *deviceLost) err(op string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.info == nil {
+		return nil
+	}
+	return l.info.error(op)
+}
+
+func (info DeviceLostInfo) error(op string) *Error {
+	msg := "device lost (" + info.Reason.String() + ")"
+	if info.Message != "" {
+		msg += ": " + info.Message
+	}
+	return &Error{Op: op, Type: ErrorTypeDeviceLost, Message: msg}
+}
+
+// Lost returns a channel that receives the DeviceLostInfo once the
+// device is lost and is closed afterwards. Every call returns a new
+// channel, so any number of goroutines can wait on it.
+func (p *Device) Lost() <-chan DeviceLostInfo {
+	return p.lostState().channel()
+}
+
+// Context returns a context that is cancelled when the device is lost.
+// context.Cause returns an *Error of type ErrorTypeDeviceLost then.
+func (p *Device) Context() context.Context {
+	return p.lostState().ctx
+}
+
+// PollUntil polls the device until done returns true. It returns
+// ctx.Err() if ctx is done first and a device-lost *Error if the device
+// is lost first, rather than waiting forever for work that will never
+// complete.
+func (p *Device) PollUntil(ctx context.Context, done func() bool) error {
+	lost := p.lostState()
+	const maxDelay = time.Millisecond
+	delay := 10 * time.Microsecond
+	for {
+		p.Poll(false, nil)
+		if done() {
+			return nil
+		}
+		if err := lost.err("Device.PollUntil"); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-lost.ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		delay = min(2*delay, maxDelay)
+	}
+}
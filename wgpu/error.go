@@ -1,12 +1,45 @@
 package wgpu
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
 
+// Error is the error returned by fallible calls. Use errors.As to
+// inspect it, for example to retry with smaller allocations when Type
+// is ErrorTypeOutOfMemory.
 type Error struct {
+	// Op is the operation that failed, such as "Device.CreateBuffer".
+	Op string
+	// Label is the label of the resource being created or, for calls on
+	// an existing one, of the receiver, if any.
+	Label   string
 	Type    ErrorType
 	Message string
 }
 
+// ErrDeviceLost matches, with errors.Is, every *Error of type
+// ErrorTypeDeviceLost.
+var ErrDeviceLost = errors.New("wgpu: device lost")
+
+// Is reports whether target is ErrDeviceLost and v is a device-lost
+// error.
+func (v *Error) Is(target error) bool {
+	return target == ErrDeviceLost && v.Type == ErrorTypeDeviceLost
+}
+
 func (v *Error) Error() string {
-	return fmt.Sprintf("%s : %s", v.Type.String(), v.Message)
+	if v.Op == "" {
+		return fmt.Sprintf("%s : %s", v.Type.String(), v.Message)
+	}
+	var label string
+	if v.Label != "" {
+		label = strconv.Quote(v.Label)
+	}
+	if recv, method, ok := strings.Cut(v.Op, "."); ok {
+		return "wgpu.(*" + recv + ")." + method + "(" + label + "): " + v.Message
+	}
+	return "wgpu." + v.Op + "(" + label + "): " + v.Message
 }
@@ -0,0 +1,54 @@
+package wgpu
+
+import "context"
+
+// Future is the result of an asynchronous operation, such as
+// Device.CreateRenderPipelineAsync.
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+func newFuture[T any]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+// resolve sets the result. It must be called once.
+func (f *Future[T]) resolve(value T, err error) {
+	f.value, f.err = value, err
+	close(f.done)
+}
+
+// Done returns a channel that is closed when the result is ready.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the result and returns it. If ctx is done first, Wait
+// returns ctx.Err(); the operation carries on and its result can still
+// be waited for.
+func (f *Future[T]) Wait(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// createPipelineAsyncError returns the error for a failed asynchronous
+// pipeline creation.
+func createPipelineAsyncError(op, label string, status CreatePipelineAsyncStatus, message string) *Error {
+	typ := ErrorTypeUnknown
+	switch status {
+	case CreatePipelineAsyncStatusValidationError:
+		typ = ErrorTypeValidation
+	case CreatePipelineAsyncStatusInternalError:
+		typ = ErrorTypeInternal
+	case CreatePipelineAsyncStatusDeviceLost, CreatePipelineAsyncStatusDeviceDestroyed:
+		typ = ErrorTypeDeviceLost
+	}
+	return &Error{Op: op, Label: label, Type: typ, Message: message}
+}
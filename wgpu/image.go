@@ -0,0 +1,310 @@
+package wgpu
+
+import (
+	"context"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// ImageTextureOptions configures CreateTextureFromImage.
+type ImageTextureOptions struct {
+	Label string
+	// Format is the format of the texture, one of the formats
+	// ImageFormatSupported accepts. If undefined, it is R8Unorm for
+	// *image.Gray and RGBA8UnormSrgb otherwise.
+	Format TextureFormat
+	// Usage is added to TextureUsageCopyDst and TextureUsageTextureBinding.
+	Usage TextureUsage
+	// MipLevelCount is the number of mip levels of the texture, 1 if
+	// zero. Only the first level is written.
+	MipLevelCount uint32
+}
+
+// ImageFormatSupported reports whether CreateTextureFromImage and
+// Texture.ReadImage can convert between images and textures of the
+// given format.
+func ImageFormatSupported(format TextureFormat) bool {
+	switch format {
+	case TextureFormatRGBA8Unorm, TextureFormatRGBA8UnormSrgb,
+		TextureFormatBGRA8Unorm, TextureFormatBGRA8UnormSrgb,
+		TextureFormatR8Unorm, TextureFormatRGBA16Float, TextureFormatRGBA32Float:
+		return true
+	}
+	return false
+}
+
+// CreateTextureFromImage creates a 2D texture the size of img and
+// writes the pixels of img to it, converting them to the format. Colors
+// are stored with straight alpha. Float formats hold the color values
+// of img as they are, in [0, 1], without converting from sRGB.
+func (p *Device) CreateTextureFromImage(img image.Image, opts *ImageTextureOptions) (*Texture, error) {
+	var o ImageTextureOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Format == TextureFormatUndefined {
+		o.Format = TextureFormatRGBA8UnormSrgb
+		if _, ok := img.(*image.Gray); ok {
+			o.Format = TextureFormatR8Unorm
+		}
+	}
+	if !ImageFormatSupported(o.Format) {
+		return nil, &Error{Op: "Device.CreateTextureFromImage", Label: o.Label, Type: ErrorTypeValidation, Message: "unsupported format " + o.Format.String()}
+	}
+
+	size := Extent3D{
+		Width:              uint32(img.Bounds().Dx()),
+		Height:             uint32(img.Bounds().Dy()),
+		DepthOrArrayLayers: 1,
+	}
+	texture, err := p.CreateTexture(&TextureDescriptor{
+		Label:         o.Label,
+		Usage:         o.Usage | TextureUsageCopyDst | TextureUsageTextureBinding,
+		Dimension:     TextureDimension2D,
+		Size:          size,
+		Format:        o.Format,
+		MipLevelCount: max(o.MipLevelCount, 1),
+		SampleCount:   1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	queue := p.GetQueue()
+	defer queue.Release()
+	err = queue.WriteTexture(
+		texture.AsImageCopy(),
+		imageToPixels(img, o.Format),
+		&TextureDataLayout{
+			BytesPerRow:  size.Width * o.Format.BlockSize(),
+			RowsPerImage: size.Height,
+		},
+		&size,
+	)
+	if err != nil {
+		texture.Release()
+		return nil, err
+	}
+	return texture, nil
+}
+
+// ReadImage copies the first mip level of the texture, or of its first
+// array layer, to a buffer and returns it as an image: *image.NRGBA for
+// 8-bit color formats, *image.Gray for R8Unorm and *image.NRGBA64 for
+// float formats, whose values are clamped to [0, 1]. The texture needs
+// TextureUsageCopySrc and one of the formats ImageFormatSupported
+// accepts. ReadImage waits for the copy as Buffer.Read does.
+func (p *Texture) ReadImage(ctx context.Context) (image.Image, error) {
+	fail := func(msg string) (image.Image, error) {
+		return nil, &Error{Op: "Texture.ReadImage", Label: p.Label(), Type: ErrorTypeValidation, Message: msg}
+	}
+	format := p.GetFormat()
+	if !ImageFormatSupported(format) {
+		return fail("unsupported format " + format.String())
+	}
+	if p.device == nil {
+		return fail("texture was not created by Device.CreateTexture")
+	}
+	width, height := p.GetWidth(), p.GetHeight()
+	layout, err := TextureCopyLayout(format, TextureDimension2D, Extent3D{Width: width, Height: height, DepthOrArrayLayers: 1}, 0, TextureAspectAll)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer, err := p.device.CreateBuffer(&BufferDescriptor{
+		Label: "Texture.ReadImage",
+		Size:  layout.Size,
+		Usage: BufferUsageMapRead | BufferUsageCopyDst,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer buffer.Release()
+
+	encoder, err := p.device.CreateCommandEncoder(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer encoder.Release()
+	err = encoder.CopyTextureToBuffer(p.AsImageCopy(), &ImageCopyBuffer{Buffer: buffer, Layout: layout.Layout}, &layout.Extent)
+	if err != nil {
+		return nil, err
+	}
+	commands, err := encoder.Finish(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer commands.Release()
+	queue := p.device.GetQueue()
+	defer queue.Release()
+	queue.Submit(commands)
+
+	data, err := buffer.Read(ctx, 0, layout.Size)
+	if err != nil {
+		return nil, err
+	}
+	pixels, err := layout.Unpad(data)
+	if err != nil {
+		return nil, err
+	}
+	return pixelsToImage(pixels, int(width), int(height), format), nil
+}
+
+// imageToPixels converts img to tightly packed rows of texels of the
+// given format.
+func imageToPixels(img image.Image, format TextureFormat) []byte {
+	b := img.Bounds()
+	switch format {
+	case TextureFormatR8Unorm:
+		gray, ok := img.(*image.Gray)
+		if !ok {
+			gray = image.NewGray(b)
+			draw.Draw(gray, b, img, b.Min, draw.Src)
+		}
+		return tightPixels(gray.Pix, gray.Stride, b.Dx(), b.Dy())
+
+	case TextureFormatRGBA16Float, TextureFormatRGBA32Float:
+		nrgba, ok := img.(*image.NRGBA64)
+		if !ok {
+			nrgba = image.NewNRGBA64(b)
+			draw.Draw(nrgba, b, img, b.Min, draw.Src)
+		}
+		src := tightPixels(nrgba.Pix, nrgba.Stride, b.Dx()*8, b.Dy())
+		componentSize := int(format.BlockSize() / 4)
+		pixels := make([]byte, len(src)/2*componentSize)
+		for i := 0; i < len(src)/2; i++ {
+			v := float32(binary.BigEndian.Uint16(src[2*i:])) / math.MaxUint16
+			if componentSize == 2 {
+				binary.LittleEndian.PutUint16(pixels[2*i:], float32ToHalf(v))
+			} else {
+				binary.LittleEndian.PutUint32(pixels[4*i:], math.Float32bits(v))
+			}
+		}
+		return pixels
+	}
+
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		nrgba = image.NewNRGBA(b)
+		draw.Draw(nrgba, b, img, b.Min, draw.Src)
+	}
+	pixels := tightPixels(nrgba.Pix, nrgba.Stride, b.Dx()*4, b.Dy())
+	if format == TextureFormatBGRA8Unorm || format == TextureFormatBGRA8UnormSrgb {
+		if ok {
+			// Don't swap the channels of the caller's image.
+			pixels = append([]byte(nil), pixels...)
+		}
+		swapRedBlue(pixels)
+	}
+	return pixels
+}
+
+// pixelsToImage converts tightly packed texels of the given format to
+// an image.
+func pixelsToImage(pixels []byte, width, height int, format TextureFormat) image.Image {
+	rect := image.Rect(0, 0, width, height)
+	switch format {
+	case TextureFormatR8Unorm:
+		return &image.Gray{Pix: pixels, Stride: width, Rect: rect}
+
+	case TextureFormatRGBA16Float, TextureFormatRGBA32Float:
+		componentSize := int(format.BlockSize() / 4)
+		img := image.NewNRGBA64(rect)
+		for i := 0; i < len(pixels)/componentSize; i++ {
+			var v float32
+			if componentSize == 2 {
+				v = halfToFloat32(binary.LittleEndian.Uint16(pixels[2*i:]))
+			} else {
+				v = math.Float32frombits(binary.LittleEndian.Uint32(pixels[4*i:]))
+			}
+			if !(v > 0) { // Also NaN.
+				v = 0
+			} else if v > 1 {
+				v = 1
+			}
+			binary.BigEndian.PutUint16(img.Pix[2*i:], uint16(v*math.MaxUint16+0.5))
+		}
+		return img
+
+	case TextureFormatBGRA8Unorm, TextureFormatBGRA8UnormSrgb:
+		swapRedBlue(pixels)
+	}
+	return &image.NRGBA{Pix: pixels, Stride: 4 * width, Rect: rect}
+}
+
+// tightPixels returns height rows of rowBytes bytes from pix, whose rows
+// are stride bytes apart, without the bytes in between.
+func tightPixels(pix []byte, stride, rowBytes, height int) []byte {
+	if stride == rowBytes {
+		return pix[:rowBytes*height]
+	}
+	tight := make([]byte, 0, rowBytes*height)
+	for y := 0; y < height; y++ {
+		tight = append(tight, pix[y*stride:y*stride+rowBytes]...)
+	}
+	return tight
+}
+
+func swapRedBlue(pixels []byte) {
+	for i := 0; i+3 < len(pixels); i += 4 {
+		pixels[i], pixels[i+2] = pixels[i+2], pixels[i]
+	}
+}
+
+// float32ToHalf converts f to an IEEE 754 half-precision float,
+// rounding to nearest even.
+func float32ToHalf(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23&0xff) - 127 + 15
+	mant := bits & 0x7fffff
+
+	switch {
+	case exp >= 0x1f:
+		if bits&0x7fffffff > 0x7f800000 {
+			return sign | 0x7e00 // NaN
+		}
+		return sign | 0x7c00 // Overflow to infinity.
+	case exp <= 0:
+		if exp < -10 {
+			return sign
+		}
+		// Subnormal.
+		mant |= 0x800000
+		shift := uint32(14 - exp)
+		h := mant >> shift
+		rem, half := mant&(1<<shift-1), uint32(1)<<(shift-1)
+		if rem > half || rem == half && h&1 != 0 {
+			h++
+		}
+		return sign | uint16(h)
+	}
+
+	// A carry out of the mantissa correctly rounds up the exponent.
+	h := uint32(exp)<<10 | mant>>13
+	if rem := mant & 0x1fff; rem > 0x1000 || rem == 0x1000 && h&1 != 0 {
+		h++
+	}
+	return sign | uint16(h)
+}
+
+// halfToFloat32 converts an IEEE 754 half-precision float to a float32.
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		v := float32(mant) / (1 << 24)
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | mant<<13)
+}
@@ -12,13 +12,17 @@ extern void gowebgpu_request_adapter_callback_c(WGPURequestAdapterStatus status,
 */
 import "C"
 import (
-	"errors"
 	"runtime/cgo"
+	"sync"
 	"unsafe"
 )
 
 type Instance struct {
-	ref C.WGPUInstance
+	ref  C.WGPUInstance
+	refs refCount
+
+	pollerMu sync.Mutex
+	poller   *Poller
 }
 
 func CreateInstance(descriptor *InstanceDescriptor) *Instance {
@@ -53,7 +57,9 @@ func CreateInstance(descriptor *InstanceDescriptor) *Instance {
 		panic("Failed to acquire Instance")
 	}
 
-	return &Instance{ref}
+	instance := &Instance{ref: ref}
+	track(instance, "Instance", "")
+	return instance
 }
 
 type SurfaceDescriptorFromWindowsHWND struct {
@@ -96,6 +102,7 @@ type SurfaceDescriptor struct {
 }
 
 func (p *Instance) CreateSurface(descriptor *SurfaceDescriptor) *Surface {
+	p.refs.check("Instance.CreateSurface", "")
 	var desc C.WGPUSurfaceDescriptor
 
 	if descriptor != nil {
@@ -181,7 +188,9 @@ func (p *Instance) CreateSurface(descriptor *SurfaceDescriptor) *Surface {
 	if ref == nil {
 		panic("Failed to acquire Surface")
 	}
-	return &Surface{ref: ref}
+	surface := &Surface{ref: ref}
+	track(surface, "Surface", "")
+	return surface
 }
 
 type requestAdapterCb func(status RequestAdapterStatus, adapter *Adapter, message string)
@@ -198,12 +207,14 @@ func gowebgpu_request_adapter_callback_go(status C.WGPURequestAdapterStatus, ada
 }
 
 func (p *Instance) RequestAdapter(options *RequestAdapterOptions) (*Adapter, error) {
+	p.refs.check("Instance.RequestAdapter", "")
 	var opts *C.WGPURequestAdapterOptions
 
 	if options != nil {
 		opts = &C.WGPURequestAdapterOptions{}
 
 		if options.CompatibleSurface != nil {
+			options.CompatibleSurface.refs.check("Instance.RequestAdapter", "")
 			opts.compatibleSurface = options.CompatibleSurface.ref
 		}
 		opts.powerPreference = C.WGPUPowerPreference(options.PowerPreference)
@@ -214,20 +225,28 @@ func (p *Instance) RequestAdapter(options *RequestAdapterOptions) (*Adapter, err
 	var status RequestAdapterStatus
 	var adapter *Adapter
 
-	var cb requestAdapterCb = func(s RequestAdapterStatus, a *Adapter, _ string) {
+	var message string
+
+	var cb requestAdapterCb = func(s RequestAdapterStatus, a *Adapter, m string) {
 		status = s
 		adapter = a
+		message = m
 	}
 	handle := cgo.NewHandle(cb)
 	C.wgpuInstanceRequestAdapter(p.ref, opts, C.WGPUInstanceRequestAdapterCallback(C.gowebgpu_request_adapter_callback_c), unsafe.Pointer(&handle))
 
 	if status != RequestAdapterStatusSuccess {
-		return nil, errors.New("failed to request adapter")
+		if message == "" {
+			message = "failed to request adapter"
+		}
+		return nil, &Error{Op: "Instance.RequestAdapter", Type: ErrorTypeUnknown, Message: message}
 	}
+	track(adapter, "Adapter", "")
 	return adapter, nil
 }
 
 func (p *Instance) EnumerateAdapters(options *InstanceEnumerateAdapterOptons) []*Adapter {
+	p.refs.check("Instance.EnumerateAdapters", "")
 	var opts *C.WGPUInstanceEnumerateAdapterOptions
 	if options != nil {
 		opts = &C.WGPUInstanceEnumerateAdapterOptions{
@@ -245,47 +264,14 @@ func (p *Instance) EnumerateAdapters(options *InstanceEnumerateAdapterOptons) []
 
 	adapters := make([]*Adapter, size)
 	for i, ref := range adapterRefs {
-		adapters[i] = &Adapter{ref}
+		adapters[i] = &Adapter{ref: ref}
+		track(adapters[i], "Adapter", "")
 	}
 	return adapters
 }
 
-type RegistryReport struct {
-	NumAllocated        uint64
-	NumKeptFromUser     uint64
-	NumReleasedFromUser uint64
-	NumError            uint64
-	ElementSize         uint64
-}
-
-type HubReport struct {
-	Adapters         RegistryReport
-	Devices          RegistryReport
-	PipelineLayouts  RegistryReport
-	ShaderModules    RegistryReport
-	BindGroupLayouts RegistryReport
-	BindGroups       RegistryReport
-	CommandBuffers   RegistryReport
-	RenderBundles    RegistryReport
-	RenderPipelines  RegistryReport
-	ComputePipelines RegistryReport
-	QuerySets        RegistryReport
-	Buffers          RegistryReport
-	Textures         RegistryReport
-	TextureViews     RegistryReport
-	Samplers         RegistryReport
-}
-
-type GlobalReport struct {
-	Surfaces RegistryReport
-	Vulkan   *HubReport
-	Metal    *HubReport
-	Dx12     *HubReport
-	Dx11     *HubReport
-	Gl       *HubReport
-}
-
 func (p *Instance) GenerateReport() GlobalReport {
+	p.refs.check("Instance.GenerateReport", "")
 	var r C.WGPUGlobalReport
 	C.wgpuGenerateReport(p.ref, &r)
 
@@ -341,5 +327,8 @@ func (p *Instance) GenerateReport() GlobalReport {
 }
 
 func (p *Instance) Release() {
-	C.wgpuInstanceRelease(p.ref)
+	if p.refs.release("Instance.Release", "") {
+		p.stopPoller()
+		C.wgpuInstanceRelease(p.ref)
+	}
 }
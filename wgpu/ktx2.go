@@ -0,0 +1,139 @@
+package wgpu
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// ktx2Identifier starts every KTX2 file.
+var ktx2Identifier = [12]byte{0xAB, 'K', 'T', 'X', ' ', '2', '0', 0xBB, '\r', '\n', 0x1A, '\n'}
+
+// ParseKTX2 parses a KTX2 file as specified by
+// https://registry.khronos.org/KTX/specs/2.0/ktxspec.v2.html
+// The file must not use supercompression, and its Vulkan format must
+// have a WebGPU equivalent.
+func ParseKTX2(data []byte) (*TextureData, error) {
+	fail := func(msg string) (*TextureData, error) {
+		return nil, &Error{Op: "ParseKTX2", Type: ErrorTypeValidation, Message: msg}
+	}
+	const headerSize = 80
+	if len(data) < headerSize || [12]byte(data[:12]) != ktx2Identifier {
+		return fail("not a KTX2 file")
+	}
+	u32 := func(offset int) uint32 { return binary.LittleEndian.Uint32(data[offset:]) }
+	vkFormat, width, height, depth := u32(12), u32(20), u32(24), u32(28)
+	layers, faces, levels, supercompression := u32(32), u32(36), u32(40), u32(44)
+
+	if supercompression != 0 {
+		return fail("supercompression scheme " + strconv.FormatUint(uint64(supercompression), 10) + " is not supported")
+	}
+	format := textureFormatFromVk(vkFormat)
+	if format == TextureFormatUndefined {
+		return fail("Vulkan format " + strconv.FormatUint(uint64(vkFormat), 10) + " has no WebGPU equivalent")
+	}
+	if faces != 1 && faces != 6 {
+		return fail("invalid face count " + strconv.FormatUint(uint64(faces), 10))
+	}
+	if layers > math.MaxUint32/faces {
+		return fail("invalid layer count " + strconv.FormatUint(uint64(layers), 10))
+	}
+
+	d := &TextureData{
+		Format:        format,
+		Dimension:     TextureDimension2D,
+		Size:          Extent3D{Width: width, Height: max(height, 1), DepthOrArrayLayers: max(layers, 1) * faces},
+		MipLevelCount: max(levels, 1),
+		Cube:          faces == 6,
+	}
+	switch {
+	case depth > 0:
+		if layers > 0 || faces != 1 {
+			return fail("3D textures cannot have layers or faces")
+		}
+		d.Dimension = TextureDimension3D
+		d.Size.DepthOrArrayLayers = depth
+	case height == 0:
+		d.Dimension = TextureDimension1D
+	}
+
+	if err := d.checkHeader("ParseKTX2"); err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) < headerSize+24*uint64(d.MipLevelCount) {
+		return fail("truncated level index")
+	}
+	d.Levels = make([][]byte, d.MipLevelCount)
+	for level := range d.Levels {
+		index := data[headerSize+24*level:]
+		offset, length := binary.LittleEndian.Uint64(index), binary.LittleEndian.Uint64(index[8:])
+		if offset > uint64(len(data)) || length > uint64(len(data))-offset {
+			return fail("level " + strconv.Itoa(level) + " is out of bounds")
+		}
+		d.Levels[level] = data[offset : offset+length]
+	}
+	if err := d.checkLevels("ParseKTX2"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// textureFormatFromVk returns the texture format of a VkFormat, or
+// TextureFormatUndefined if it has none.
+func textureFormatFromVk(vkFormat uint32) TextureFormat {
+	// The compressed formats are in the same order in both enums.
+	switch {
+	case vkFormat >= 131 && vkFormat <= 132: // VK_FORMAT_BC1_RGB_*_BLOCK
+		return TextureFormatBC1RGBAUnorm + TextureFormat(vkFormat-131)
+	case vkFormat >= 133 && vkFormat <= 146:
+		return TextureFormatBC1RGBAUnorm + TextureFormat(vkFormat-133)
+	case vkFormat >= 147 && vkFormat <= 156:
+		return TextureFormatETC2RGB8Unorm + TextureFormat(vkFormat-147)
+	case vkFormat >= 157 && vkFormat <= 184:
+		return TextureFormatASTC4x4Unorm + TextureFormat(vkFormat-157)
+	}
+	return vkFormats[vkFormat]
+}
+
+var vkFormats = map[uint32]TextureFormat{
+	9:   TextureFormatR8Unorm,
+	10:  TextureFormatR8Snorm,
+	13:  TextureFormatR8Uint,
+	14:  TextureFormatR8Sint,
+	16:  TextureFormatRG8Unorm,
+	17:  TextureFormatRG8Snorm,
+	20:  TextureFormatRG8Uint,
+	21:  TextureFormatRG8Sint,
+	37:  TextureFormatRGBA8Unorm,
+	38:  TextureFormatRGBA8Snorm,
+	41:  TextureFormatRGBA8Uint,
+	42:  TextureFormatRGBA8Sint,
+	43:  TextureFormatRGBA8UnormSrgb,
+	44:  TextureFormatBGRA8Unorm,
+	50:  TextureFormatBGRA8UnormSrgb,
+	64:  TextureFormatRGB10A2Unorm,
+	68:  TextureFormatRGB10A2Uint,
+	74:  TextureFormatR16Uint,
+	75:  TextureFormatR16Sint,
+	76:  TextureFormatR16Float,
+	81:  TextureFormatRG16Uint,
+	82:  TextureFormatRG16Sint,
+	83:  TextureFormatRG16Float,
+	95:  TextureFormatRGBA16Uint,
+	96:  TextureFormatRGBA16Sint,
+	97:  TextureFormatRGBA16Float,
+	98:  TextureFormatR32Uint,
+	99:  TextureFormatR32Sint,
+	100: TextureFormatR32Float,
+	101: TextureFormatRG32Uint,
+	102: TextureFormatRG32Sint,
+	103: TextureFormatRG32Float,
+	107: TextureFormatRGBA32Uint,
+	108: TextureFormatRGBA32Sint,
+	109: TextureFormatRGBA32Float,
+	122: TextureFormatRG11B10Ufloat,
+	123: TextureFormatRGB9E5Ufloat,
+	124: TextureFormatDepth16Unorm,
+	126: TextureFormatDepth32Float,
+	127: TextureFormatStencil8,
+}
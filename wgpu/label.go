@@ -0,0 +1,122 @@
+//go:build !js
+
+package wgpu
+
+// wgpu-native does not implement the SetLabel functions, so labels set
+// after creation are only kept on the Go side, for error messages and
+// leak reports; wgpu's own messages keep the label given at creation.
+
+func (p *BindGroup) Label() string           { return p.label }
+func (p *BindGroupLayout) Label() string     { return p.label }
+func (p *Buffer) Label() string              { return p.label }
+func (p *CommandBuffer) Label() string       { return p.label }
+func (p *CommandEncoder) Label() string      { return p.label }
+func (p *ComputePassEncoder) Label() string  { return p.label }
+func (p *ComputePipeline) Label() string     { return p.label }
+func (p *Device) Label() string              { return p.label }
+func (p *PipelineLayout) Label() string      { return p.label }
+func (p *QuerySet) Label() string            { return p.label }
+func (p *Queue) Label() string               { return p.label }
+func (p *RenderBundle) Label() string        { return p.label }
+func (p *RenderBundleEncoder) Label() string { return p.label }
+func (p *RenderPassEncoder) Label() string   { return p.label }
+func (p *RenderPipeline) Label() string      { return p.label }
+func (p *Sampler) Label() string             { return p.label }
+func (p *ShaderModule) Label() string        { return p.label }
+func (p *Texture) Label() string             { return p.label }
+func (p *TextureView) Label() string         { return p.label }
+
+func (p *BindGroup) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *BindGroupLayout) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *Buffer) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *CommandBuffer) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *CommandEncoder) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *ComputePassEncoder) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *ComputePipeline) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *Device) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *PipelineLayout) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *QuerySet) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *Queue) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *RenderBundle) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *RenderBundleEncoder) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *RenderPassEncoder) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *RenderPipeline) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *Sampler) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *ShaderModule) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *Texture) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
+
+func (p *TextureView) SetLabel(label string) {
+	p.label = label
+	relabelHandle(p.refs.id, label)
+}
@@ -0,0 +1,40 @@
+//go:build js
+
+package wgpu
+
+// Label and SetLabel get and set the label attribute as described:
+// https://gpuweb.github.io/gpuweb/#dom-gpuobjectbase-label
+
+func (g BindGroup) Label() string          { return g.jsValue.Get("label").String() }
+func (g BindGroupLayout) Label() string    { return g.jsValue.Get("label").String() }
+func (g Buffer) Label() string             { return g.jsValue.Get("label").String() }
+func (g CommandBuffer) Label() string      { return g.jsValue.Get("label").String() }
+func (g CommandEncoder) Label() string     { return g.jsValue.Get("label").String() }
+func (g ComputePassEncoder) Label() string { return g.jsValue.Get("label").String() }
+func (g ComputePipeline) Label() string    { return g.jsValue.Get("label").String() }
+func (g Device) Label() string             { return g.jsValue.Get("label").String() }
+func (g PipelineLayout) Label() string     { return g.jsValue.Get("label").String() }
+func (g Queue) Label() string              { return g.jsValue.Get("label").String() }
+func (g RenderPassEncoder) Label() string  { return g.jsValue.Get("label").String() }
+func (g RenderPipeline) Label() string     { return g.jsValue.Get("label").String() }
+func (g Sampler) Label() string            { return g.jsValue.Get("label").String() }
+func (g ShaderModule) Label() string       { return g.jsValue.Get("label").String() }
+func (g Texture) Label() string            { return g.jsValue.Get("label").String() }
+func (g TextureView) Label() string        { return g.jsValue.Get("label").String() }
+
+func (g BindGroup) SetLabel(label string)          { g.jsValue.Set("label", label) }
+func (g BindGroupLayout) SetLabel(label string)    { g.jsValue.Set("label", label) }
+func (g Buffer) SetLabel(label string)             { g.jsValue.Set("label", label) }
+func (g CommandBuffer) SetLabel(label string)      { g.jsValue.Set("label", label) }
+func (g CommandEncoder) SetLabel(label string)     { g.jsValue.Set("label", label) }
+func (g ComputePassEncoder) SetLabel(label string) { g.jsValue.Set("label", label) }
+func (g ComputePipeline) SetLabel(label string)    { g.jsValue.Set("label", label) }
+func (g Device) SetLabel(label string)             { g.jsValue.Set("label", label) }
+func (g PipelineLayout) SetLabel(label string)     { g.jsValue.Set("label", label) }
+func (g Queue) SetLabel(label string)              { g.jsValue.Set("label", label) }
+func (g RenderPassEncoder) SetLabel(label string)  { g.jsValue.Set("label", label) }
+func (g RenderPipeline) SetLabel(label string)     { g.jsValue.Set("label", label) }
+func (g Sampler) SetLabel(label string)            { g.jsValue.Set("label", label) }
+func (g ShaderModule) SetLabel(label string)       { g.jsValue.Set("label", label) }
+func (g Texture) SetLabel(label string)            { g.jsValue.Set("label", label) }
+func (g TextureView) SetLabel(label string)        { g.jsValue.Set("label", label) }
@@ -0,0 +1,228 @@
+package wgpu
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// The leak tracker records the handles created through the package
+// while it is enabled, with their type, label and creation stack, until
+// their last reference is released. Only native handles are tracked; in
+// the browser the garbage collector owns the objects.
+var leaks struct {
+	enabled atomic.Bool
+
+	mu      sync.Mutex
+	lastID  uint64
+	handles map[uint64]*trackedHandle
+	// byUser is the state set by EnableLeakTracking and checkers the
+	// number of CheckLeaks calls whose test is running. Tracking is
+	// enabled while either is set.
+	byUser   bool
+	checkers int
+}
+
+type trackedHandle struct {
+	kind  string
+	label string
+	stack []uintptr
+}
+
+// EnableLeakTracking starts or stops recording handles. Stopping keeps
+// the handles already recorded until they are released, and takes effect
+// once the tests calling CheckLeaks finish.
+func EnableLeakTracking(enabled bool) {
+	leaks.mu.Lock()
+	leaks.byUser = enabled
+	leaks.enabled.Store(leaks.byUser || leaks.checkers > 0)
+	leaks.mu.Unlock()
+}
+
+// LeakedHandle is a handle that was created while the leak tracker was
+// enabled and is not released yet.
+type LeakedHandle struct {
+	// Type is the handle type, such as "BindGroup".
+	Type  string
+	Label string
+	// Stack is the call stack that created the handle, one
+	// "function\n\tfile:line" frame per line pair.
+	Stack string
+}
+
+// LeakReport lists unreleased handles in creation order.
+type LeakReport struct {
+	Handles []LeakedHandle
+}
+
+// Leaks returns the tracked handles that are not released yet.
+func Leaks() LeakReport {
+	return leaksSince(0)
+}
+
+func leaksSince(id uint64) LeakReport {
+	leaks.mu.Lock()
+	ids := make([]uint64, 0, len(leaks.handles))
+	for k := range leaks.handles {
+		if k > id {
+			ids = append(ids, k)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	tracked := make([]trackedHandle, len(ids))
+	for i, k := range ids {
+		tracked[i] = *leaks.handles[k]
+	}
+	leaks.mu.Unlock()
+
+	var r LeakReport
+	for _, h := range tracked {
+		r.Handles = append(r.Handles, LeakedHandle{
+			Type:  h.kind,
+			Label: h.label,
+			Stack: formatStack(h.stack),
+		})
+	}
+	return r
+}
+
+// Count returns the number of unreleased handles of each type.
+func (r LeakReport) Count() map[string]int {
+	count := make(map[string]int)
+	for _, h := range r.Handles {
+		count[h.Type]++
+	}
+	return count
+}
+
+// String groups the handles created with the same type, label and stack,
+// most numerous first.
+func (r LeakReport) String() string {
+	type group struct {
+		handle LeakedHandle
+		count  int
+	}
+	var groups []*group
+	index := make(map[LeakedHandle]*group)
+	for _, h := range r.Handles {
+		g, ok := index[h]
+		if !ok {
+			g = &group{handle: h}
+			index[h] = g
+			groups = append(groups, g)
+		}
+		g.count++
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].count > groups[j].count })
+
+	var b strings.Builder
+	for _, g := range groups {
+		fmt.Fprintf(&b, "%d %s", g.count, g.handle.Type)
+		if g.handle.Label != "" {
+			fmt.Fprintf(&b, " %q", g.handle.Label)
+		}
+		b.WriteString(" created at:\n")
+		for _, line := range strings.Split(g.handle.Stack, "\n") {
+			if line != "" {
+				fmt.Fprintf(&b, "\t%s\n", line)
+			}
+		}
+	}
+	return b.String()
+}
+
+// TB is the part of testing.TB used by CheckLeaks.
+type TB interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...any)
+}
+
+// CheckLeaks enables the leak tracker until the test and the other tests
+// calling CheckLeaks finish, and fails the test, when it finishes, if a
+// handle created since the call is not released. Handles created by
+// parallel tests are attributed to every test checking for leaks at the
+// time.
+func CheckLeaks(t TB) {
+	t.Helper()
+	leaks.mu.Lock()
+	leaks.checkers++
+	leaks.enabled.Store(true)
+	start := leaks.lastID
+	leaks.mu.Unlock()
+
+	t.Cleanup(func() {
+		t.Helper()
+		leaks.mu.Lock()
+		leaks.checkers--
+		leaks.enabled.Store(leaks.byUser || leaks.checkers > 0)
+		leaks.mu.Unlock()
+		if r := leaksSince(start); len(r.Handles) > 0 {
+			t.Errorf("wgpu: %d handles not released:\n%s", len(r.Handles), r)
+		}
+	})
+}
+
+// trackHandle records a handle created by the caller of track, in
+// autorelease.go, and returns its id, or 0 if the tracker is disabled.
+func trackHandle(kind, label string) uint64 {
+	if !leaks.enabled.Load() {
+		return 0
+	}
+	// Skip runtime.Callers, trackHandle and track, so that the stack
+	// starts at the function creating the handle, such as
+	// Device.CreateBuffer, followed by the user's call.
+	var pcs [32]uintptr
+	n := runtime.Callers(3, pcs[:])
+
+	leaks.mu.Lock()
+	defer leaks.mu.Unlock()
+	if leaks.handles == nil {
+		leaks.handles = make(map[uint64]*trackedHandle)
+	}
+	leaks.lastID++
+	leaks.handles[leaks.lastID] = &trackedHandle{
+		kind:  kind,
+		label: label,
+		stack: append([]uintptr(nil), pcs[:n]...),
+	}
+	return leaks.lastID
+}
+
+func relabelHandle(id uint64, label string) {
+	if id == 0 {
+		return
+	}
+	leaks.mu.Lock()
+	if h, ok := leaks.handles[id]; ok {
+		h.label = label
+	}
+	leaks.mu.Unlock()
+}
+
+func untrackHandle(id uint64) {
+	if id == 0 {
+		return
+	}
+	leaks.mu.Lock()
+	delete(leaks.handles, id)
+	leaks.mu.Unlock()
+}
+
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
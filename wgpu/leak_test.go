@@ -0,0 +1,129 @@
+package wgpu
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeTB records the failures and cleanups of a CheckLeaks call.
+type fakeTB struct {
+	cleanups []func()
+	errors   []string
+}
+
+func (t *fakeTB) Helper() {}
+
+func (t *fakeTB) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+
+func (t *fakeTB) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeTB) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func TestCheckLeaks(t *testing.T) {
+	tb := &fakeTB{}
+	CheckLeaks(tb)
+	released := trackHandle("Buffer", "released")
+	leaked := trackHandle("Texture", "leaked")
+	if released == 0 || leaked == 0 {
+		t.Fatal("CheckLeaks does not enable tracking")
+	}
+	untrackHandle(released)
+	tb.finish()
+	defer untrackHandle(leaked)
+
+	if len(tb.errors) != 1 {
+		t.Fatalf("got %d errors, want 1", len(tb.errors))
+	}
+	if !strings.Contains(tb.errors[0], `1 Texture "leaked"`) || strings.Contains(tb.errors[0], "Buffer") {
+		t.Errorf("error does not report the leaked texture only:\n%s", tb.errors[0])
+	}
+	if trackHandle("Buffer", "") != 0 {
+		t.Error("tracking is still enabled after the test")
+	}
+}
+
+func TestCheckLeaksNested(t *testing.T) {
+	outer, inner := &fakeTB{}, &fakeTB{}
+	CheckLeaks(outer)
+	CheckLeaks(inner)
+	inner.finish()
+	id := trackHandle("Sampler", "")
+	if id == 0 {
+		t.Fatal("the end of a check disables tracking for another running check")
+	}
+	untrackHandle(id)
+	outer.finish()
+	if trackHandle("Sampler", "") != 0 {
+		t.Error("tracking is still enabled after every check")
+	}
+
+	EnableLeakTracking(true)
+	check := &fakeTB{}
+	CheckLeaks(check)
+	check.finish()
+	id = trackHandle("Sampler", "")
+	EnableLeakTracking(false)
+	untrackHandle(id)
+	if id == 0 {
+		t.Error("the end of a check disables tracking enabled by EnableLeakTracking")
+	}
+	if len(outer.errors)+len(inner.errors)+len(check.errors) != 0 {
+		t.Error("released handles are reported")
+	}
+}
+
+func TestLeakReport(t *testing.T) {
+	r := LeakReport{Handles: []LeakedHandle{
+		{Type: "Buffer", Label: "a", Stack: "f\n\tf.go:1\n"},
+		{Type: "BindGroup", Stack: "g\n\tg.go:2\n"},
+		{Type: "BindGroup", Stack: "g\n\tg.go:2\n"},
+		{Type: "Buffer", Label: "b", Stack: "f\n\tf.go:1\n"},
+	}}
+	count := r.Count()
+	if len(count) != 2 || count["Buffer"] != 2 || count["BindGroup"] != 2 {
+		t.Errorf("Count() = %v", count)
+	}
+	want := "2 BindGroup created at:\n\tg\n\t\tg.go:2\n" +
+		"1 Buffer \"a\" created at:\n\tf\n\t\tf.go:1\n" +
+		"1 Buffer \"b\" created at:\n\tf\n\t\tf.go:1\n"
+	if got := r.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGlobalReportDiff(t *testing.T) {
+	before := GlobalReport{
+		Surfaces: RegistryReport{NumAllocated: 1},
+		Vulkan:   &HubReport{Buffers: RegistryReport{NumAllocated: 4, NumKeptFromUser: 4}},
+	}
+	after := GlobalReport{
+		Surfaces: RegistryReport{NumAllocated: 1},
+		Vulkan: &HubReport{
+			Buffers:    RegistryReport{NumAllocated: 3, NumKeptFromUser: 3, NumReleasedFromUser: 1},
+			BindGroups: RegistryReport{NumAllocated: 2, NumKeptFromUser: 2},
+		},
+		Metal: &HubReport{Devices: RegistryReport{NumError: 1}},
+	}
+	want := []RegistryDelta{
+		{Registry: "Vulkan.BindGroups", NumAllocated: 2, NumKeptFromUser: 2},
+		{Registry: "Vulkan.Buffers", NumAllocated: -1, NumKeptFromUser: -1, NumReleasedFromUser: 1},
+		{Registry: "Metal.Devices", NumError: 1},
+	}
+	got := after.Diff(before)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+	if d := after.Diff(after); len(d) != 0 {
+		t.Errorf("Diff of the same report = %v", d)
+	}
+	if s := want[1].String(); s != "Vulkan.Buffers: allocated -1, kept -1, released +1, error +0" {
+		t.Errorf("String() = %q", s)
+	}
+}
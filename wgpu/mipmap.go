@@ -0,0 +1,476 @@
+package wgpu
+
+import (
+	"strings"
+	"sync"
+)
+
+// mipmapRenderShader draws a triangle covering the target and samples
+// the previous level at the center of each 2×2 block of texels. The
+// sampler filters in linear space: sRGB views decode when sampled and
+// encode when rendered to.
+const mipmapRenderShader = `
+struct VertexOutput {
+	@builtin(position) position: vec4f,
+	@location(0) uv: vec2f,
+}
+
+@vertex
+fn vs_main(@builtin(vertex_index) index: u32) -> VertexOutput {
+	var positions = array<vec2f, 3>(vec2f(-1.0, -1.0), vec2f(3.0, -1.0), vec2f(-1.0, 3.0));
+	let position = positions[index];
+	var out: VertexOutput;
+	out.position = vec4f(position, 0.0, 1.0);
+	out.uv = position * vec2f(0.5, -0.5) + vec2f(0.5);
+	return out;
+}
+
+@group(0) @binding(0) var src: texture_2d<f32>;
+@group(0) @binding(1) var src_sampler: sampler;
+
+@fragment
+fn fs_main(in: VertexOutput) -> @location(0) vec4f {
+	return textureSample(src, src_sampler, in.uv);
+}
+`
+
+// mipmapComputeShader averages 2×2 blocks of texels of the previous
+// level, clamping at the edge of odd-sized levels. FORMAT is replaced by
+// the storage texel format.
+const mipmapComputeShader = `
+@group(0) @binding(0) var src: texture_2d<f32>;
+@group(0) @binding(1) var dst: texture_storage_2d<FORMAT, write>;
+
+@compute @workgroup_size(8, 8)
+fn cs_main(@builtin(global_invocation_id) id: vec3u) {
+	let size = textureDimensions(dst);
+	if (id.x >= size.x || id.y >= size.y) {
+		return;
+	}
+	let last = textureDimensions(src) - vec2u(1u);
+	let p = id.xy * 2u;
+	let q = min(p + vec2u(1u), last);
+	let sum = textureLoad(src, p, 0) + textureLoad(src, vec2u(q.x, p.y), 0) +
+		textureLoad(src, vec2u(p.x, q.y), 0) + textureLoad(src, q, 0);
+	textureStore(dst, id.xy, sum * 0.25);
+}
+`
+
+// mipmapWorkgroupSize is the workgroup size of mipmapComputeShader in
+// each dimension.
+const mipmapWorkgroupSize = 8
+
+// MipmapGenerator fills the mip levels of 2D and 2D-array textures from
+// their first level. Formats that can be rendered to and filtered are
+// downsampled with a render pass, sRGB formats in linear space; float
+// formats that can only be used as storage textures, such as
+// TextureFormatRGBA8Snorm, with a compute pass. The pipelines are
+// created on first use and cached per format.
+//
+// A MipmapGenerator is safe for concurrent use.
+type MipmapGenerator struct {
+	device *Device
+
+	mu           sync.Mutex
+	sampler      *Sampler
+	renderShader *ShaderModule
+	pipelines    map[mipmapKey]*mipmapPipeline
+}
+
+// mipmapKey identifies a cached pipeline. Textures of one format may
+// take either path depending on their usage.
+type mipmapKey struct {
+	format  TextureFormat
+	compute bool
+}
+
+type mipmapPipeline struct {
+	bindGroupLayout *BindGroupLayout
+	pipelineLayout  *PipelineLayout
+	shader          *ShaderModule // compute only
+	render          *RenderPipeline
+	compute         *ComputePipeline
+}
+
+// NewMipmapGenerator returns a generator for textures of device. Most
+// programs can use Device.GenerateMipmaps instead, which shares one
+// generator per device.
+func NewMipmapGenerator(device *Device) *MipmapGenerator {
+	return &MipmapGenerator{device: device, pipelines: map[mipmapKey]*mipmapPipeline{}}
+}
+
+// GenerateMipmaps fills the mip levels of texture after the first, in
+// every array layer, and submits the work to the device's queue. See
+// MipmapGenerator for the supported formats. The texture needs
+// TextureUsageTextureBinding, and TextureUsageRenderAttachment or, for
+// formats that cannot be rendered to, TextureUsageStorageBinding.
+func (p *Device) GenerateMipmaps(texture *Texture) error {
+	return p.mipmapGenerator().Generate(texture)
+}
+
+// Generate is like Device.GenerateMipmaps, using the pipelines of g.
+func (g *MipmapGenerator) Generate(texture *Texture) error {
+	encoder, err := g.device.CreateCommandEncoder(&CommandEncoderDescriptor{Label: "MipmapGenerator"})
+	if err != nil {
+		return err
+	}
+	defer encoder.Release()
+	if err := g.Encode(encoder, texture); err != nil {
+		return err
+	}
+	commands, err := encoder.Finish(nil)
+	if err != nil {
+		return err
+	}
+	defer commands.Release()
+	queue := g.device.GetQueue()
+	defer queue.Release()
+	queue.Submit(commands)
+	return nil
+}
+
+// Encode records the passes filling the mip levels of texture after the
+// first into encoder, for submission with other commands.
+func (g *MipmapGenerator) Encode(encoder *CommandEncoder, texture *Texture) error {
+	fail := func(msg string) error {
+		return &Error{Op: "MipmapGenerator.Encode", Type: ErrorTypeValidation, Message: msg}
+	}
+	if texture.GetDimension() != TextureDimension2D {
+		return fail("texture dimension " + texture.GetDimension().String() + " is not 2d")
+	}
+	if texture.GetSampleCount() != 1 {
+		return fail("multisampled textures have no mip levels")
+	}
+	levels := texture.GetMipLevelCount()
+	if levels <= 1 {
+		return nil
+	}
+
+	format := texture.GetFormat()
+	pipeline, err := g.pipeline(format, texture.GetUsage())
+	if err != nil {
+		return err
+	}
+
+	width, height := texture.GetWidth(), texture.GetHeight()
+	for layer := uint32(0); layer < texture.GetDepthOrArrayLayers(); layer++ {
+		for level := uint32(1); level < levels; level++ {
+			err := g.encodeLevel(encoder, texture, pipeline, layer, level, max(width>>level, 1), max(height>>level, 1))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeLevel records the pass filling level of layer from the level
+// before it.
+func (g *MipmapGenerator) encodeLevel(encoder *CommandEncoder, texture *Texture, pipeline *mipmapPipeline, layer, level, width, height uint32) error {
+	view := func(level uint32) (*TextureView, error) {
+		return texture.CreateView(&TextureViewDescriptor{
+			Format:          texture.GetFormat(),
+			Dimension:       TextureViewDimension2D,
+			BaseMipLevel:    level,
+			MipLevelCount:   1,
+			BaseArrayLayer:  layer,
+			ArrayLayerCount: 1,
+			Aspect:          TextureAspectAll,
+		})
+	}
+	src, err := view(level - 1)
+	if err != nil {
+		return err
+	}
+	defer src.Release()
+	dst, err := view(level)
+	if err != nil {
+		return err
+	}
+	defer dst.Release()
+
+	entries := []BindGroupEntry{{Binding: 0, TextureView: src}}
+	if pipeline.render != nil {
+		entries = append(entries, BindGroupEntry{Binding: 1, Sampler: g.sampler})
+	} else {
+		entries = append(entries, BindGroupEntry{Binding: 1, TextureView: dst})
+	}
+	bindGroup, err := g.device.CreateBindGroup(&BindGroupDescriptor{
+		Layout:  pipeline.bindGroupLayout,
+		Entries: entries,
+	})
+	if err != nil {
+		return err
+	}
+	defer bindGroup.Release()
+
+	if pipeline.render != nil {
+		pass := encoder.BeginRenderPass(&RenderPassDescriptor{
+			ColorAttachments: []RenderPassColorAttachment{{
+				View:    dst,
+				LoadOp:  LoadOpClear,
+				StoreOp: StoreOpStore,
+			}},
+		})
+		defer pass.Release()
+		pass.SetPipeline(pipeline.render)
+		pass.SetBindGroup(0, bindGroup, nil)
+		pass.Draw(3, 1, 0, 0)
+		return pass.End()
+	}
+
+	pass := encoder.BeginComputePass(nil)
+	defer pass.Release()
+	pass.SetPipeline(pipeline.compute)
+	pass.SetBindGroup(0, bindGroup, nil)
+	pass.DispatchWorkgroups((width+mipmapWorkgroupSize-1)/mipmapWorkgroupSize, (height+mipmapWorkgroupSize-1)/mipmapWorkgroupSize, 1)
+	return pass.End()
+}
+
+// pipeline returns the cached pipeline for format, creating it if
+// needed. It renders when the format and usage allow it and computes
+// otherwise.
+func (g *MipmapGenerator) pipeline(format TextureFormat, usage TextureUsage) (*mipmapPipeline, error) {
+	fail := func(msg string) (*mipmapPipeline, error) {
+		return nil, &Error{Op: "MipmapGenerator.Encode", Type: ErrorTypeValidation, Message: msg}
+	}
+	filterable := format.FilterableFeature() == FeatureNameUndefined || g.device.HasFeature(format.FilterableFeature())
+	renderable := mipmapRenderable(format) && filterable
+	storageFeature := format.RequiredFeature(TextureUsageStorageBinding)
+	storable := mipmapStorable(format) && (storageFeature == FeatureNameUndefined || g.device.HasFeature(storageFeature))
+	var compute bool
+	switch {
+	case renderable && usage&(TextureUsageTextureBinding|TextureUsageRenderAttachment) == TextureUsageTextureBinding|TextureUsageRenderAttachment:
+	case storable && usage&(TextureUsageTextureBinding|TextureUsageStorageBinding) == TextureUsageTextureBinding|TextureUsageStorageBinding:
+		compute = true
+	case renderable:
+		return fail("texture needs TextureUsageTextureBinding and TextureUsageRenderAttachment")
+	case storable:
+		return fail("texture needs TextureUsageTextureBinding and TextureUsageStorageBinding")
+	default:
+		return fail("cannot generate mipmaps for format " + format.String())
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := mipmapKey{format, compute}
+	if pipeline := g.pipelines[key]; pipeline != nil {
+		return pipeline, nil
+	}
+
+	var pipeline *mipmapPipeline
+	var err error
+	if compute {
+		pipeline, err = g.createComputePipeline(format)
+	} else {
+		pipeline, err = g.createRenderPipeline(format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	g.pipelines[key] = pipeline
+	return pipeline, nil
+}
+
+func (g *MipmapGenerator) createRenderPipeline(format TextureFormat) (*mipmapPipeline, error) {
+	if g.sampler == nil {
+		sampler, err := g.device.CreateSampler(&SamplerDescriptor{
+			Label:         "MipmapGenerator",
+			AddressModeU:  AddressModeClampToEdge,
+			AddressModeV:  AddressModeClampToEdge,
+			AddressModeW:  AddressModeClampToEdge,
+			MagFilter:     FilterModeLinear,
+			MinFilter:     FilterModeLinear,
+			MipmapFilter:  MipmapFilterModeNearest,
+			LodMaxClamp:   32,
+			MaxAnisotropy: 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+		g.sampler = sampler
+	}
+	if g.renderShader == nil {
+		shader, err := g.device.CreateShaderModule(&ShaderModuleDescriptor{
+			Label:          "MipmapGenerator",
+			WGSLDescriptor: &ShaderModuleWGSLDescriptor{Code: mipmapRenderShader},
+		})
+		if err != nil {
+			return nil, err
+		}
+		g.renderShader = shader
+	}
+
+	pipeline := &mipmapPipeline{}
+	err := pipeline.createLayout(g.device, "MipmapGenerator "+format.String(), []BindGroupLayoutEntry{
+		{
+			Binding:    0,
+			Visibility: ShaderStageFragment,
+			Texture:    TextureBindingLayout{SampleType: TextureSampleTypeFloat, ViewDimension: TextureViewDimension2D},
+		},
+		{
+			Binding:    1,
+			Visibility: ShaderStageFragment,
+			Sampler:    SamplerBindingLayout{Type: SamplerBindingTypeFiltering},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	pipeline.render, err = g.device.CreateRenderPipeline(&RenderPipelineDescriptor{
+		Label:  "MipmapGenerator " + format.String(),
+		Layout: pipeline.pipelineLayout,
+		Vertex: VertexState{
+			Module:     g.renderShader,
+			EntryPoint: "vs_main",
+		},
+		Primitive: PrimitiveState{
+			Topology:  PrimitiveTopologyTriangleList,
+			FrontFace: FrontFaceCCW,
+			CullMode:  CullModeNone,
+		},
+		Multisample: MultisampleState{
+			Count: 1,
+			Mask:  0xFFFFFFFF,
+		},
+		Fragment: &FragmentState{
+			Module:     g.renderShader,
+			EntryPoint: "fs_main",
+			Targets: []ColorTargetState{{
+				Format:    format,
+				WriteMask: ColorWriteMaskAll,
+			}},
+		},
+	})
+	if err != nil {
+		pipeline.release()
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+func (g *MipmapGenerator) createComputePipeline(format TextureFormat) (*mipmapPipeline, error) {
+	label := "MipmapGenerator " + format.String()
+	pipeline := &mipmapPipeline{}
+	err := pipeline.createLayout(g.device, label, []BindGroupLayoutEntry{
+		{
+			Binding:    0,
+			Visibility: ShaderStageCompute,
+			Texture:    TextureBindingLayout{SampleType: TextureSampleTypeUnfilterableFloat, ViewDimension: TextureViewDimension2D},
+		},
+		{
+			Binding:        1,
+			Visibility:     ShaderStageCompute,
+			StorageTexture: StorageTextureBindingLayout{Access: StorageTextureAccessWriteOnly, Format: format, ViewDimension: TextureViewDimension2D},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	pipeline.shader, err = g.device.CreateShaderModule(&ShaderModuleDescriptor{
+		Label:          label,
+		WGSLDescriptor: &ShaderModuleWGSLDescriptor{Code: strings.Replace(mipmapComputeShader, "FORMAT", format.String(), 1)},
+	})
+	if err != nil {
+		pipeline.release()
+		return nil, err
+	}
+	pipeline.compute, err = g.device.CreateComputePipeline(&ComputePipelineDescriptor{
+		Label:  label,
+		Layout: pipeline.pipelineLayout,
+		Compute: ProgrammableStageDescriptor{
+			Module:     pipeline.shader,
+			EntryPoint: "cs_main",
+		},
+	})
+	if err != nil {
+		pipeline.release()
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+func (p *mipmapPipeline) createLayout(device *Device, label string, entries []BindGroupLayoutEntry) error {
+	var err error
+	p.bindGroupLayout, err = device.CreateBindGroupLayout(&BindGroupLayoutDescriptor{
+		Label:   label,
+		Entries: entries,
+	})
+	if err != nil {
+		return err
+	}
+	p.pipelineLayout, err = device.CreatePipelineLayout(&PipelineLayoutDescriptor{
+		Label:            label,
+		BindGroupLayouts: []*BindGroupLayout{p.bindGroupLayout},
+	})
+	if err != nil {
+		p.bindGroupLayout.Release()
+		return err
+	}
+	return nil
+}
+
+func (p *mipmapPipeline) release() {
+	if p.render != nil {
+		p.render.Release()
+	}
+	if p.compute != nil {
+		p.compute.Release()
+	}
+	if p.shader != nil {
+		p.shader.Release()
+	}
+	p.pipelineLayout.Release()
+	p.bindGroupLayout.Release()
+}
+
+// Release releases the cached pipelines. Device.Release releases the
+// generator of Device.GenerateMipmaps.
+func (g *MipmapGenerator) Release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for key, pipeline := range g.pipelines {
+		pipeline.release()
+		delete(g.pipelines, key)
+	}
+	if g.renderShader != nil {
+		g.renderShader.Release()
+		g.renderShader = nil
+	}
+	if g.sampler != nil {
+		g.sampler.Release()
+		g.sampler = nil
+	}
+}
+
+// mipmapRenderable reports whether format is a float color format that
+// can be rendered to.
+func mipmapRenderable(format TextureFormat) bool {
+	switch format {
+	case TextureFormatR8Unorm, TextureFormatRG8Unorm,
+		TextureFormatRGBA8Unorm, TextureFormatRGBA8UnormSrgb,
+		TextureFormatBGRA8Unorm, TextureFormatBGRA8UnormSrgb,
+		TextureFormatRGB10A2Unorm,
+		TextureFormatR16Float, TextureFormatRG16Float, TextureFormatRGBA16Float,
+		TextureFormatR32Float, TextureFormatRG32Float, TextureFormatRGBA32Float:
+		return true
+	case TextureFormatRG11B10Ufloat:
+		// Needs FeatureNameRG11B10UfloatRenderable, without which the
+		// texture cannot have TextureUsageRenderAttachment.
+		return true
+	}
+	return false
+}
+
+// mipmapStorable reports whether format is a float color format that
+// can be used as a write-only storage texture, given the feature it
+// requires for TextureUsageStorageBinding, if any.
+func mipmapStorable(format TextureFormat) bool {
+	switch format {
+	case TextureFormatRGBA8Unorm, TextureFormatRGBA8Snorm, TextureFormatBGRA8Unorm,
+		TextureFormatRGBA16Float,
+		TextureFormatR32Float, TextureFormatRG32Float, TextureFormatRGBA32Float:
+		return true
+	}
+	return false
+}
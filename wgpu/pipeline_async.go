@@ -0,0 +1,44 @@
+//go:build !js
+
+package wgpu
+
+// wgpu-native does not implement wgpuDeviceCreateComputePipelineAsync and
+// wgpuDeviceCreateRenderPipelineAsync, so the pipelines are created on
+// goroutines instead, so the caller does not block. The creation does not
+// push error scopes, so it does not hold the device's scope lock while
+// the shaders compile: pipelines compile in parallel, and the calls of
+// other goroutines on the device do not wait for them. Its errors reach
+// the future through the uncaptured error callback, which wgpu-native
+// calls on the creating thread. An error scope another goroutine has
+// open on the device meanwhile, with PushErrorScope or during a call
+// that returns an error, captures them instead.
+
+// CreateComputePipelineAsync creates a compute pipeline in the
+// background, without blocking the caller while the shaders compile.
+// The descriptor must not be modified until the future is done.
+func (p *Device) CreateComputePipelineAsync(descriptor *ComputePipelineDescriptor) *Future[*ComputePipeline] {
+	f := newFuture[*ComputePipeline]()
+	go func() {
+		if err := p.lostState().err("Device.CreateComputePipelineAsync"); err != nil {
+			f.resolve(nil, err)
+			return
+		}
+		f.resolve(p.createComputePipeline("Device.CreateComputePipelineAsync", descriptor, true))
+	}()
+	return f
+}
+
+// CreateRenderPipelineAsync creates a render pipeline in the background,
+// without blocking the caller while the shaders compile. The descriptor
+// must not be modified until the future is done.
+func (p *Device) CreateRenderPipelineAsync(descriptor *RenderPipelineDescriptor) *Future[*RenderPipeline] {
+	f := newFuture[*RenderPipeline]()
+	go func() {
+		if err := p.lostState().err("Device.CreateRenderPipelineAsync"); err != nil {
+			f.resolve(nil, err)
+			return
+		}
+		f.resolve(p.createRenderPipeline("Device.CreateRenderPipelineAsync", descriptor, true))
+	}()
+	return f
+}
@@ -0,0 +1,81 @@
+//go:build js
+
+package wgpu
+
+import "syscall/js"
+
+// CreateComputePipelineAsync as described:
+// https://gpuweb.github.io/gpuweb/#dom-gpudevice-createcomputepipelineasync
+func (g Device) CreateComputePipelineAsync(descriptor *ComputePipelineDescriptor) *Future[*ComputePipeline] {
+	f := newFuture[*ComputePipeline]()
+	if err := validateComputeConstants("Device.CreateComputePipelineAsync", descriptor); err != nil {
+		f.resolve(nil, err)
+		return f
+	}
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
+	}
+	promise := g.jsValue.Call("createComputePipelineAsync", pointerToJS(descriptor))
+	awaitPipeline(promise, "Device.CreateComputePipelineAsync", label, func(v js.Value, err error) {
+		if err != nil {
+			f.resolve(nil, err)
+			return
+		}
+		f.resolve(&ComputePipeline{jsValue: v}, nil)
+	})
+	return f
+}
+
+// CreateRenderPipelineAsync as described:
+// https://gpuweb.github.io/gpuweb/#dom-gpudevice-createrenderpipelineasync
+func (g Device) CreateRenderPipelineAsync(descriptor *RenderPipelineDescriptor) *Future[*RenderPipeline] {
+	f := newFuture[*RenderPipeline]()
+	if err := validateRenderConstants("Device.CreateRenderPipelineAsync", descriptor); err != nil {
+		f.resolve(nil, err)
+		return f
+	}
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
+	}
+	promise := g.jsValue.Call("createRenderPipelineAsync", pointerToJS(descriptor))
+	awaitPipeline(promise, "Device.CreateRenderPipelineAsync", label, func(v js.Value, err error) {
+		if err != nil {
+			f.resolve(nil, err)
+			return
+		}
+		f.resolve(&RenderPipeline{jsValue: v}, nil)
+	})
+	return f
+}
+
+// awaitPipeline calls done when the promise of a pipeline settles,
+// without blocking. A rejection with a GPUPipelineError is converted as
+// described:
+// https://gpuweb.github.io/gpuweb/#gpupipelineerror
+func awaitPipeline(promise js.Value, op, label string, done func(js.Value, error)) {
+	var onResolve, onReject js.Func
+	release := func() {
+		onResolve.Release()
+		onReject.Release()
+	}
+	onResolve = js.FuncOf(func(this js.Value, args []js.Value) any {
+		defer release()
+		done(args[0], nil)
+		return nil
+	})
+	onReject = js.FuncOf(func(this js.Value, args []js.Value) any {
+		defer release()
+		status := CreatePipelineAsyncStatusUnknown
+		switch args[0].Get("reason").String() {
+		case "validation":
+			status = CreatePipelineAsyncStatusValidationError
+		case "internal":
+			status = CreatePipelineAsyncStatusInternalError
+		}
+		done(js.Undefined(), createPipelineAsyncError(op, label, status, args[0].Get("message").String()))
+		return nil
+	})
+	promise.Call("then", onResolve, onReject)
+}
@@ -0,0 +1,498 @@
+package wgpu
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"unsafe"
+)
+
+// CacheKey identifies the contents of a descriptor given to a
+// PipelineCache: the SHA-256 of its fields other than the label.
+type CacheKey [sha256.Size]byte
+
+func (k CacheKey) String() string {
+	return hex.EncodeToString(k[:])
+}
+
+// CacheObjectKind is the kind of an object in a PipelineCache.
+type CacheObjectKind uint32
+
+const (
+	CacheObjectKindShaderModule CacheObjectKind = iota
+	CacheObjectKindComputePipeline
+	CacheObjectKindRenderPipeline
+)
+
+func (v CacheObjectKind) String() string {
+	switch v {
+	case CacheObjectKindShaderModule:
+		return "shader-module"
+	case CacheObjectKindComputePipeline:
+		return "compute-pipeline"
+	case CacheObjectKindRenderPipeline:
+		return "render-pipeline"
+	default:
+		return ""
+	}
+}
+
+// CacheEviction describes an object evicted from a PipelineCache.
+type CacheEviction struct {
+	Kind  CacheObjectKind
+	Key   CacheKey
+	Label string
+}
+
+// CacheStats are the statistics of a PipelineCache.
+type CacheStats struct {
+	// Hits counts the requests served by an existing object, and Misses
+	// those that created one.
+	Hits   uint64
+	Misses uint64
+	// Evictions counts the objects evicted and released.
+	Evictions uint64
+	// Entries is the number of objects in the cache, and Idle the number
+	// of them without references.
+	Entries int
+	Idle    int
+}
+
+// PipelineCache shares the shader modules and pipelines created from
+// descriptors with identical contents. The objects it returns are
+// reference-counted: each call takes a reference, to be dropped with
+// Unref rather than by releasing the object. Objects without references
+// stay in the cache, to be reused, until they are evicted by Trim or by
+// the limit set with SetMaxIdle.
+//
+// Descriptors are compared by value, except for labels, which are
+// ignored: an object has the label of the descriptor that created it.
+// Pipeline layouts are compared by identity, and shader modules from the
+// cache by their contents, so pipelines created from identical WGSL share
+// an object even if the module was evicted in between.
+//
+// A PipelineCache is safe for concurrent use.
+type PipelineCache struct {
+	device *Device
+
+	mu       sync.Mutex
+	entries  map[CacheKey]*cacheEntry
+	objects  map[any]*cacheEntry
+	idle     list.List // of *cacheEntry, least recently used first
+	maxIdle  int
+	onEvict  func(CacheEviction)
+	stats    CacheStats
+	released bool
+}
+
+type cacheEntry struct {
+	kind  CacheObjectKind
+	key   CacheKey
+	label string
+
+	// ready is closed when the object is created, or creating it failed
+	// with err. object is set under the cache's lock.
+	ready  chan struct{}
+	object any
+	err    error
+
+	refs int
+	idle *list.Element
+
+	// deps are the objects the key holds the address of, kept alive so
+	// the address is not reused.
+	deps []any
+}
+
+// NewPipelineCache returns an empty cache of objects created on device.
+// It keeps all objects without references until Trim is called.
+func NewPipelineCache(device *Device) *PipelineCache {
+	return &PipelineCache{
+		device:  device,
+		entries: map[CacheKey]*cacheEntry{},
+		objects: map[any]*cacheEntry{},
+		maxIdle: -1,
+	}
+}
+
+// ShaderModule returns a shader module created from descriptor, taking a
+// reference to it.
+func (c *PipelineCache) ShaderModule(descriptor *ShaderModuleDescriptor) (*ShaderModule, error) {
+	if descriptor == nil {
+		panic("got nil descriptor")
+	}
+	object, err := c.get("PipelineCache.ShaderModule", CacheObjectKindShaderModule, descriptor.Label, descriptor.hash, func() (any, error) {
+		return c.device.CreateShaderModule(descriptor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return object.(*ShaderModule), nil
+}
+
+// ComputePipeline returns a compute pipeline created from descriptor,
+// taking a reference to it.
+func (c *PipelineCache) ComputePipeline(descriptor *ComputePipelineDescriptor) (*ComputePipeline, error) {
+	if descriptor == nil {
+		panic("got nil descriptor")
+	}
+	object, err := c.get("PipelineCache.ComputePipeline", CacheObjectKindComputePipeline, descriptor.Label, func(h *cacheHasher) {
+		hashObject(h, descriptor.Layout)
+		h.programmableStage(&descriptor.Compute)
+	}, func() (any, error) {
+		return c.device.CreateComputePipeline(descriptor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return object.(*ComputePipeline), nil
+}
+
+// RenderPipeline returns a render pipeline created from descriptor,
+// taking a reference to it.
+func (c *PipelineCache) RenderPipeline(descriptor *RenderPipelineDescriptor) (*RenderPipeline, error) {
+	if descriptor == nil {
+		panic("got nil descriptor")
+	}
+	object, err := c.get("PipelineCache.RenderPipeline", CacheObjectKindRenderPipeline, descriptor.Label, func(h *cacheHasher) {
+		h.renderPipeline(descriptor)
+	}, func() (any, error) {
+		return c.device.CreateRenderPipeline(descriptor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return object.(*RenderPipeline), nil
+}
+
+// get returns the object with the contents written by hash, taking a
+// reference to it. The first request for the contents creates the object;
+// concurrent requests wait for it.
+func (c *PipelineCache) get(op string, kind CacheObjectKind, label string, hash func(*cacheHasher), create func() (any, error)) (any, error) {
+	c.mu.Lock()
+	if c.released {
+		c.mu.Unlock()
+		return nil, &Error{Op: op, Label: label, Type: ErrorTypeValidation, Message: "cache released"}
+	}
+	h := &cacheHasher{hash: sha256.New(), cache: c}
+	h.uint(uint64(kind))
+	hash(h)
+	var key CacheKey
+	h.hash.Sum(key[:0])
+
+	if e := c.entries[key]; e != nil {
+		e.refs++
+		if e.idle != nil {
+			c.idle.Remove(e.idle)
+			e.idle = nil
+		}
+		c.stats.Hits++
+		c.mu.Unlock()
+
+		<-e.ready
+		return e.object, e.err
+	}
+
+	e := &cacheEntry{kind: kind, key: key, label: label, ready: make(chan struct{}), refs: 1, deps: h.deps}
+	c.entries[key] = e
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	object, err := create()
+
+	c.mu.Lock()
+	if err == nil && c.released {
+		releaseCacheObject(object)
+		err = &Error{Op: op, Label: label, Type: ErrorTypeValidation, Message: "cache released"}
+	}
+	if err != nil {
+		// The waiting requests fail too, and the next one tries again.
+		delete(c.entries, key)
+		e.err = err
+	} else {
+		e.object = object
+		c.objects[object] = e
+	}
+	c.mu.Unlock()
+	close(e.ready)
+	return e.object, e.err
+}
+
+// Unref drops a reference to an object returned by the cache. It panics
+// if the object has no references, and does nothing once the cache is
+// released.
+func (c *PipelineCache) Unref(object any) {
+	c.mu.Lock()
+	if c.released {
+		c.mu.Unlock()
+		return
+	}
+	e := c.objects[object]
+	if e == nil || e.refs == 0 {
+		c.mu.Unlock()
+		panic("wgpu: PipelineCache.Unref of an object without references")
+	}
+	e.refs--
+	var evicted []*cacheEntry
+	if e.refs == 0 {
+		e.idle = c.idle.PushBack(e)
+		evicted = c.trim(c.maxIdle)
+	}
+	onEvict := c.onEvict
+	c.mu.Unlock()
+
+	evict(evicted, onEvict)
+}
+
+// SetMaxIdle sets the number of objects without references the cache
+// keeps, evicting the least recently used ones beyond it. A negative n
+// keeps them all.
+func (c *PipelineCache) SetMaxIdle(n int) {
+	c.mu.Lock()
+	c.maxIdle = n
+	evicted := c.trim(n)
+	onEvict := c.onEvict
+	c.mu.Unlock()
+
+	evict(evicted, onEvict)
+}
+
+// Trim evicts all objects without references.
+func (c *PipelineCache) Trim() {
+	c.mu.Lock()
+	evicted := c.trim(0)
+	onEvict := c.onEvict
+	c.mu.Unlock()
+
+	evict(evicted, onEvict)
+}
+
+// SetEvictionHandler sets the function called after an object is
+// evicted and released. A nil handler removes the previous one.
+func (c *PipelineCache) SetEvictionHandler(handler func(CacheEviction)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEvict = handler
+}
+
+// Stats returns the statistics of the cache.
+func (c *PipelineCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	stats.Idle = c.idle.Len()
+	return stats
+}
+
+// releasedPipelineCache returns a released cache for a released device,
+// so that its requests fail instead of using the device.
+func releasedPipelineCache(device *Device) *PipelineCache {
+	c := NewPipelineCache(device)
+	c.Release()
+	return c
+}
+
+// Release evicts all objects, including those with references, which
+// must no longer be used. Later requests fail.
+func (c *PipelineCache) Release() {
+	c.mu.Lock()
+	c.released = true
+	var evicted []*cacheEntry
+	for key, e := range c.entries {
+		// Objects being created are released when they are.
+		if e.object != nil {
+			delete(c.entries, key)
+			delete(c.objects, e.object)
+			evicted = append(evicted, e)
+		}
+	}
+	c.idle.Init()
+	c.stats.Evictions += uint64(len(evicted))
+	onEvict := c.onEvict
+	c.mu.Unlock()
+
+	evict(evicted, onEvict)
+}
+
+// trim removes the least recently used objects without references
+// beyond n from the cache and returns them. A negative n removes none.
+func (c *PipelineCache) trim(n int) []*cacheEntry {
+	if n < 0 {
+		return nil
+	}
+	var evicted []*cacheEntry
+	for c.idle.Len() > n {
+		e := c.idle.Remove(c.idle.Front()).(*cacheEntry)
+		e.idle = nil
+		delete(c.entries, e.key)
+		delete(c.objects, e.object)
+		evicted = append(evicted, e)
+	}
+	c.stats.Evictions += uint64(len(evicted))
+	return evicted
+}
+
+// evict releases the objects removed from a cache and calls onEvict, if
+// not nil, for each.
+func evict(evicted []*cacheEntry, onEvict func(CacheEviction)) {
+	for _, e := range evicted {
+		releaseCacheObject(e.object)
+		if onEvict != nil {
+			onEvict(CacheEviction{Kind: e.kind, Key: e.key, Label: e.label})
+		}
+	}
+}
+
+func releaseCacheObject(object any) {
+	switch object := object.(type) {
+	case *ShaderModule:
+		object.Release()
+	case *ComputePipeline:
+		object.Release()
+	case *RenderPipeline:
+		object.Release()
+	}
+}
+
+// cacheHasher writes the contents of descriptors to a hash. Values are
+// written with their length, so that different contents cannot write the
+// same bytes.
+type cacheHasher struct {
+	hash  hash.Hash
+	cache *PipelineCache
+	deps  []any
+}
+
+func (h *cacheHasher) uint(v uint64) {
+	h.hash.Write(binary.LittleEndian.AppendUint64(nil, v))
+}
+
+func (h *cacheHasher) bool(v bool) {
+	if v {
+		h.uint(1)
+	} else {
+		h.uint(0)
+	}
+}
+
+func (h *cacheHasher) float32(v float32) {
+	h.uint(uint64(math.Float32bits(v)))
+}
+
+func (h *cacheHasher) bytes(b []byte) {
+	h.uint(uint64(len(b)))
+	h.hash.Write(b)
+}
+
+func (h *cacheHasher) string(s string) {
+	h.uint(uint64(len(s)))
+	io.WriteString(h.hash, s)
+}
+
+// hashObject writes the identity of an object: the key of objects from
+// the cache, and else the address.
+func hashObject[T any](h *cacheHasher, object *T) {
+	if object == nil {
+		h.uint(0)
+		return
+	}
+	if e := h.cache.objects[object]; e != nil {
+		h.uint(1)
+		h.hash.Write(e.key[:])
+		return
+	}
+	h.uint(2)
+	h.uint(uint64(uintptr(unsafe.Pointer(object))))
+	h.deps = append(h.deps, object)
+}
+
+func (h *cacheHasher) programmableStage(stage *ProgrammableStageDescriptor) {
+	hashObject(h, stage.Module)
+	h.string(stage.EntryPoint)
+	h.constants(stage.Constants)
+}
+
+// constants writes the constants sorted by key, as their order does not
+// matter.
+func (h *cacheHasher) constants(constants []ConstantEntry) {
+	sorted := append([]ConstantEntry(nil), constants...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+	h.uint(uint64(len(sorted)))
+	for _, c := range sorted {
+		h.string(c.Key)
+		h.uint(math.Float64bits(c.Value))
+	}
+}
+
+func (h *cacheHasher) renderPipeline(d *RenderPipelineDescriptor) {
+	hashObject(h, d.Layout)
+
+	hashObject(h, d.Vertex.Module)
+	h.string(d.Vertex.EntryPoint)
+	h.constants(d.Vertex.Constants)
+	h.uint(uint64(len(d.Vertex.Buffers)))
+	for _, buffer := range d.Vertex.Buffers {
+		h.uint(buffer.ArrayStride)
+		h.uint(uint64(buffer.StepMode))
+		h.uint(uint64(len(buffer.Attributes)))
+		for _, attribute := range buffer.Attributes {
+			h.uint(uint64(attribute.Format))
+			h.uint(attribute.Offset)
+			h.uint(uint64(attribute.ShaderLocation))
+		}
+	}
+
+	h.uint(uint64(d.Primitive.Topology))
+	h.uint(uint64(d.Primitive.StripIndexFormat))
+	h.uint(uint64(d.Primitive.FrontFace))
+	h.uint(uint64(d.Primitive.CullMode))
+
+	h.bool(d.DepthStencil != nil)
+	if ds := d.DepthStencil; ds != nil {
+		h.uint(uint64(ds.Format))
+		h.bool(ds.DepthWriteEnabled)
+		h.uint(uint64(ds.DepthCompare))
+		for _, face := range []StencilFaceState{ds.StencilFront, ds.StencilBack} {
+			h.uint(uint64(face.Compare))
+			h.uint(uint64(face.FailOp))
+			h.uint(uint64(face.DepthFailOp))
+			h.uint(uint64(face.PassOp))
+		}
+		h.uint(uint64(ds.StencilReadMask))
+		h.uint(uint64(ds.StencilWriteMask))
+		h.uint(uint64(uint32(ds.DepthBias)))
+		h.float32(ds.DepthBiasSlopeScale)
+		h.float32(ds.DepthBiasClamp)
+	}
+
+	h.uint(uint64(d.Multisample.Count))
+	h.uint(uint64(d.Multisample.Mask))
+	h.bool(d.Multisample.AlphaToCoverageEnabled)
+
+	h.bool(d.Fragment != nil)
+	if f := d.Fragment; f != nil {
+		hashObject(h, f.Module)
+		h.string(f.EntryPoint)
+		h.constants(f.Constants)
+		h.uint(uint64(len(f.Targets)))
+		for _, target := range f.Targets {
+			h.uint(uint64(target.Format))
+			h.bool(target.Blend != nil)
+			if b := target.Blend; b != nil {
+				for _, component := range []BlendComponent{b.Color, b.Alpha} {
+					h.uint(uint64(component.Operation))
+					h.uint(uint64(component.SrcFactor))
+					h.uint(uint64(component.DstFactor))
+				}
+			}
+			h.uint(uint64(target.WriteMask))
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package wgpu
+
+import (
+	"crypto/sha256"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+type fakeCacheObject struct{ name string }
+
+// getFake requests the object named name from c, creating it with create
+// on a miss.
+func getFake(c *PipelineCache, name string, create func() (any, error)) (*fakeCacheObject, error) {
+	object, err := c.get("test", CacheObjectKindShaderModule, name, func(h *cacheHasher) {
+		h.string(name)
+	}, create)
+	if err != nil {
+		return nil, err
+	}
+	return object.(*fakeCacheObject), nil
+}
+
+func createFake(name string) func() (any, error) {
+	return func() (any, error) { return &fakeCacheObject{name}, nil }
+}
+
+func cacheKeyOf(c *PipelineCache, hash func(*cacheHasher)) CacheKey {
+	h := &cacheHasher{hash: sha256.New(), cache: c}
+	hash(h)
+	var key CacheKey
+	h.hash.Sum(key[:0])
+	return key
+}
+
+func TestPipelineCacheRefs(t *testing.T) {
+	c := NewPipelineCache(nil)
+	a, err := getFake(c, "a", createFake("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := getFake(c, "a", func() (any, error) {
+		t.Fatal("created twice")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("requests for the same contents got different objects")
+	}
+
+	c.Unref(a)
+	if stats := c.Stats(); stats.Idle != 0 {
+		t.Fatalf("object idle with a reference left: %+v", stats)
+	}
+	c.Unref(a)
+	if stats := c.Stats(); stats.Idle != 1 || stats.Entries != 1 {
+		t.Fatalf("got %+v, want one idle entry", stats)
+	}
+
+	// A hit takes the object out of the idle list.
+	if _, err := getFake(c, "a", nil); err != nil {
+		t.Fatal(err)
+	}
+	want := CacheStats{Hits: 2, Misses: 1, Entries: 1}
+	if stats := c.Stats(); stats != want {
+		t.Fatalf("got %+v, want %+v", stats, want)
+	}
+	c.Unref(a)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Unref of an object without references does not panic")
+		}
+	}()
+	c.Unref(a)
+}
+
+func TestPipelineCacheLRU(t *testing.T) {
+	c := NewPipelineCache(nil)
+	var evicted []string
+	c.SetEvictionHandler(func(e CacheEviction) { evicted = append(evicted, e.Label) })
+
+	objects := map[string]*fakeCacheObject{}
+	for _, name := range []string{"a", "b", "c"} {
+		o, err := getFake(c, name, createFake(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects[name] = o
+	}
+	c.Unref(objects["a"])
+	c.Unref(objects["b"])
+	c.Unref(objects["c"])
+
+	// Using a makes b the least recently used.
+	if _, err := getFake(c, "a", nil); err != nil {
+		t.Fatal(err)
+	}
+	c.Unref(objects["a"])
+
+	c.SetMaxIdle(2)
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("SetMaxIdle(2) evicted %q, want [b]", evicted)
+	}
+	c.Trim()
+	if len(evicted) != 3 || evicted[1] != "c" || evicted[2] != "a" {
+		t.Fatalf("Trim evicted %q, want [b c a]", evicted)
+	}
+	if stats := c.Stats(); stats.Entries != 0 || stats.Evictions != 3 {
+		t.Fatalf("got %+v after Trim", stats)
+	}
+
+	// Evicted contents are created again.
+	created := false
+	if _, err := getFake(c, "a", func() (any, error) {
+		created = true
+		return &fakeCacheObject{"a"}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Fatal("evicted object served from the cache")
+	}
+}
+
+func TestPipelineCacheConcurrentMiss(t *testing.T) {
+	c := NewPipelineCache(nil)
+	release := make(chan struct{})
+	var creates int
+	create := func() (any, error) {
+		creates++
+		<-release
+		return &fakeCacheObject{"a"}, nil
+	}
+
+	const n = 8
+	results := make([]*fakeCacheObject, n)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			o, err := getFake(c, "a", create)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = o
+		}(i)
+	}
+	for {
+		if stats := c.Stats(); stats.Hits+stats.Misses == n {
+			break
+		}
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	if creates != 1 {
+		t.Fatalf("created %d objects, want 1", creates)
+	}
+	for _, o := range results {
+		if o != results[0] {
+			t.Fatal("concurrent requests got different objects")
+		}
+	}
+}
+
+func TestPipelineCacheError(t *testing.T) {
+	c := NewPipelineCache(nil)
+	failure := errors.New("failed")
+	if _, err := getFake(c, "a", func() (any, error) { return nil, failure }); err != failure {
+		t.Fatalf("got %v, want %v", err, failure)
+	}
+	if stats := c.Stats(); stats.Entries != 0 {
+		t.Fatalf("failed object kept: %+v", stats)
+	}
+	if _, err := getFake(c, "a", createFake("a")); err != nil {
+		t.Fatalf("failure not retried: %v", err)
+	}
+}
+
+func TestPipelineCacheRelease(t *testing.T) {
+	c := NewPipelineCache(nil)
+	var evicted int
+	c.SetEvictionHandler(func(CacheEviction) { evicted++ })
+	a, err := getFake(c, "a", createFake("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Release()
+	if evicted != 1 {
+		t.Fatalf("Release evicted %d objects, want 1", evicted)
+	}
+	// Dropping the reference after the release does nothing.
+	c.Unref(a)
+	if _, err := getFake(c, "a", createFake("a")); err == nil {
+		t.Fatal("request after Release succeeded")
+	}
+}
+
+func TestPipelineCacheKeys(t *testing.T) {
+	c := NewPipelineCache(nil)
+	layout, otherLayout := &PipelineLayout{}, &PipelineLayout{}
+	compute := func(d *ComputePipelineDescriptor) CacheKey {
+		return cacheKeyOf(c, func(h *cacheHasher) {
+			hashObject(h, d.Layout)
+			h.programmableStage(&d.Compute)
+		})
+	}
+	render := func(d *RenderPipelineDescriptor) CacheKey {
+		return cacheKeyOf(c, func(h *cacheHasher) { h.renderPipeline(d) })
+	}
+
+	base := compute(&ComputePipelineDescriptor{
+		Label:  "a",
+		Layout: layout,
+		Compute: ProgrammableStageDescriptor{
+			EntryPoint: "main",
+			Constants:  []ConstantEntry{{Key: "x", Value: 1}, {Key: "y", Value: 2}},
+		},
+	})
+	tests := []struct {
+		name       string
+		descriptor ComputePipelineDescriptor
+		same       bool
+	}{
+		{"label", ComputePipelineDescriptor{Label: "b", Layout: layout, Compute: ProgrammableStageDescriptor{EntryPoint: "main", Constants: []ConstantEntry{{Key: "x", Value: 1}, {Key: "y", Value: 2}}}}, true},
+		{"constant order", ComputePipelineDescriptor{Layout: layout, Compute: ProgrammableStageDescriptor{EntryPoint: "main", Constants: []ConstantEntry{{Key: "y", Value: 2}, {Key: "x", Value: 1}}}}, true},
+		{"constant value", ComputePipelineDescriptor{Layout: layout, Compute: ProgrammableStageDescriptor{EntryPoint: "main", Constants: []ConstantEntry{{Key: "x", Value: 1}, {Key: "y", Value: 3}}}}, false},
+		{"constant key", ComputePipelineDescriptor{Layout: layout, Compute: ProgrammableStageDescriptor{EntryPoint: "main", Constants: []ConstantEntry{{Key: "x", Value: 1}, {Key: "z", Value: 2}}}}, false},
+		{"entry point", ComputePipelineDescriptor{Layout: layout, Compute: ProgrammableStageDescriptor{EntryPoint: "main2", Constants: []ConstantEntry{{Key: "x", Value: 1}, {Key: "y", Value: 2}}}}, false},
+		{"layout", ComputePipelineDescriptor{Layout: otherLayout, Compute: ProgrammableStageDescriptor{EntryPoint: "main", Constants: []ConstantEntry{{Key: "x", Value: 1}, {Key: "y", Value: 2}}}}, false},
+		{"no layout", ComputePipelineDescriptor{Compute: ProgrammableStageDescriptor{EntryPoint: "main", Constants: []ConstantEntry{{Key: "x", Value: 1}, {Key: "y", Value: 2}}}}, false},
+	}
+	for _, test := range tests {
+		if same := compute(&test.descriptor) == base; same != test.same {
+			t.Errorf("%s: same key = %v, want %v", test.name, same, test.same)
+		}
+	}
+
+	// Strings are written with their length.
+	ab := cacheKeyOf(c, func(h *cacheHasher) { h.string("ab"); h.string("c") })
+	if ab == cacheKeyOf(c, func(h *cacheHasher) { h.string("a"); h.string("bc") }) {
+		t.Error("strings split differently share a key")
+	}
+
+	plain := render(&RenderPipelineDescriptor{Vertex: VertexState{EntryPoint: "vs"}})
+	for name, d := range map[string]*RenderPipelineDescriptor{
+		"depth stencil": {Vertex: VertexState{EntryPoint: "vs"}, DepthStencil: &DepthStencilState{}},
+		"fragment":      {Vertex: VertexState{EntryPoint: "vs"}, Fragment: &FragmentState{}},
+		"blend":         {Vertex: VertexState{EntryPoint: "vs"}, Fragment: &FragmentState{Targets: []ColorTargetState{{Blend: &BlendState{}}}}},
+		"topology":      {Vertex: VertexState{EntryPoint: "vs"}, Primitive: PrimitiveState{Topology: PrimitiveTopologyLineList}},
+	} {
+		if render(d) == plain {
+			t.Errorf("%s: same key as the plain render pipeline", name)
+		}
+	}
+	if render(&RenderPipelineDescriptor{Label: "x", Vertex: VertexState{EntryPoint: "vs"}}) != plain {
+		t.Error("label changes the render pipeline key")
+	}
+}
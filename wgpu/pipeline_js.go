@@ -15,6 +15,7 @@ type PipelineLayoutDescriptor struct {
 
 func (g PipelineLayoutDescriptor) toJS() any {
 	return map[string]any{
+		"label": g.Label,
 		"bindGroupLayouts": mapSlice(g.BindGroupLayouts, func(layout *BindGroupLayout) any {
 			return pointerToJS(layout)
 		}),
@@ -73,6 +74,7 @@ type VertexState struct {
 	Module     *ShaderModule
 	EntryPoint string
 	Buffers    []VertexBufferLayout
+	Constants  []ConstantEntry
 }
 
 func (g VertexState) toJS() any {
@@ -82,6 +84,7 @@ func (g VertexState) toJS() any {
 		"buffers": mapSlice(g.Buffers, func(layout VertexBufferLayout) any {
 			return layout.toJS()
 		}),
+		"constants": constantsToJS(g.Constants),
 	}
 }
 
@@ -219,6 +222,7 @@ type FragmentState struct {
 	Module     *ShaderModule
 	EntryPoint string
 	Targets    []ColorTargetState
+	Constants  []ConstantEntry
 }
 
 func (g FragmentState) toJS() any {
@@ -228,6 +232,7 @@ func (g FragmentState) toJS() any {
 		"targets": mapSlice(g.Targets, func(target ColorTargetState) any {
 			return target.toJS()
 		}),
+		"constants": constantsToJS(g.Constants),
 	}
 }
 
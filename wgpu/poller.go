@@ -0,0 +1,154 @@
+//go:build !js
+
+package wgpu
+
+/*
+
+#include "./lib/wgpu.h"
+
+*/
+import "C"
+import (
+	"sync"
+	"time"
+)
+
+// Callbacks
+//
+// wgpu-native runs the callbacks of asynchronous operations, such as
+// Buffer.MapAsync and Queue.OnSubmittedWorkDone, inside Device.Poll and
+// Instance.ProcessEvents, on the calling goroutine. A poll runs the
+// callbacks it finds ready one at a time, in the order the operations
+// completed. Device.PollUntil, Submission.Wait and the helpers built on
+// them, such as Buffer.Read, poll the same way.
+//
+// A Poller polls on its own goroutine, so that callbacks fire without
+// the application calling Poll. Polls are not serialized: when several
+// goroutines poll a device at once, such as a Poller and Buffer.Read,
+// each callback runs once, on whichever goroutine's poll found its
+// operation complete, and callbacks found by different polls can run
+// concurrently. Callbacks that share state must synchronize, and only a
+// single polling goroutine, such as a lone Poller, runs them all in
+// order.
+
+// defaultPollInterval is the cadence of a Poller started with a
+// non-positive interval.
+const defaultPollInterval = time.Millisecond
+
+// Poller polls a device or an instance on a goroutine.
+type Poller struct {
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// startPoller calls poll every interval until the poller is stopped,
+// then release, which drops the reference the poller holds so that the
+// object outlives its polls.
+func startPoller(interval time.Duration, poll, release func()) *Poller {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	p := &Poller{stop: make(chan struct{}), done: make(chan struct{})}
+	go p.run(interval, poll, release)
+	return p
+}
+
+func (p *Poller) run(interval time.Duration, poll, release func()) {
+	defer close(p.done)
+	defer release()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		poll()
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop stops polling and waits for the poll in progress, if any, to
+// return. It must not be called from a callback the poller runs.
+// Calling it again does nothing.
+func (p *Poller) Stop() {
+	p.signal()
+	<-p.done
+}
+
+// signal stops polling without waiting, for Release and StartPoller,
+// which can be called from a callback the poller runs.
+func (p *Poller) signal() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// StartPoller polls the device every interval, or every millisecond if
+// interval is not positive, until the poller is stopped or the device
+// released. It stops the poller started before, if any. Release and
+// StartPoller do not wait for the poll in progress, so they can be
+// called from a callback the poller runs; the poller keeps the device
+// alive until its last poll returns.
+func (p *Device) StartPoller(interval time.Duration) *Poller {
+	p.refs.check("Device.StartPoller", p.label)
+	// Poll is not called, so that polling until Release stops the poller
+	// does not panic.
+	ref := p.ref
+	C.wgpuDeviceReference(ref)
+	poller := startPoller(interval,
+		func() { C.wgpuDevicePoll(ref, cBool(false), nil) },
+		func() { C.wgpuDeviceRelease(ref) },
+	)
+
+	// The device is released if its state is gone.
+	old := poller
+	devicesMu.Lock()
+	if s := devices[p.id]; s != nil {
+		old, s.poller = s.poller, poller
+	}
+	devicesMu.Unlock()
+	if old != nil {
+		old.signal()
+	}
+	return poller
+}
+
+// ProcessEvents runs the callbacks of the operations completed on every
+// device of the instance, for applications with their own event loop.
+func (p *Instance) ProcessEvents() {
+	p.refs.check("Instance.ProcessEvents", "")
+	C.wgpuInstanceProcessEvents(p.ref)
+}
+
+// StartPoller calls ProcessEvents every interval, or every millisecond
+// if interval is not positive, until the poller is stopped or the
+// instance released. It stops the poller started before, if any,
+// without waiting, as Device.StartPoller does.
+func (p *Instance) StartPoller(interval time.Duration) *Poller {
+	p.refs.check("Instance.StartPoller", "")
+	ref := p.ref
+	C.wgpuInstanceReference(ref)
+	poller := startPoller(interval,
+		func() { C.wgpuInstanceProcessEvents(ref) },
+		func() { C.wgpuInstanceRelease(ref) },
+	)
+
+	p.pollerMu.Lock()
+	old := p.poller
+	p.poller = poller
+	p.pollerMu.Unlock()
+	if old != nil {
+		old.signal()
+	}
+	return poller
+}
+
+func (p *Instance) stopPoller() {
+	p.pollerMu.Lock()
+	poller := p.poller
+	p.poller = nil
+	p.pollerMu.Unlock()
+	if poller != nil {
+		poller.signal()
+	}
+}
@@ -0,0 +1,21 @@
+//go:build js
+
+package wgpu
+
+import "time"
+
+// Poller does nothing in the browser, where callbacks run on the
+// JavaScript event loop.
+type Poller struct{}
+
+func (p *Poller) Stop() {} // no-op
+
+func (g Device) StartPoller(interval time.Duration) *Poller {
+	return &Poller{} // no-op
+}
+
+func (g Instance) ProcessEvents() {} // no-op
+
+func (g Instance) StartPoller(interval time.Duration) *Poller {
+	return &Poller{} // no-op
+}
@@ -7,19 +7,20 @@ package wgpu
 #include <stdlib.h>
 #include "./lib/wgpu.h"
 
-extern void gowebgpu_error_callback_c(WGPUErrorType type, char const * message, void * userdata);
+extern void gowebgpu_push_error_scopes(WGPUDevice device);
+extern void gowebgpu_pop_error_scopes(WGPUDevice device, void * userdata);
 extern void gowebgpu_queue_work_done_callback_c(WGPUQueueWorkDoneStatus status, void * userdata);
 
 static inline void gowebgpu_queue_write_buffer(WGPUQueue queue, WGPUBuffer buffer, uint64_t bufferOffset, void const * data, size_t size, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuQueueWriteBuffer(queue, buffer, bufferOffset, data, size);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_queue_write_texture(WGPUQueue queue, WGPUImageCopyTexture const * destination, void const * data, size_t dataSize, WGPUTextureDataLayout const * dataLayout, WGPUExtent3D const * writeSize, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuQueueWriteTexture(queue, destination, data, dataSize, dataLayout, writeSize);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_queue_release(WGPUQueue queue, WGPUDevice device) {
@@ -30,14 +31,20 @@ static inline void gowebgpu_queue_release(WGPUQueue queue, WGPUDevice device) {
 */
 import "C"
 import (
-	"errors"
 	"runtime/cgo"
+	"sync"
+	"time"
 	"unsafe"
 )
 
 type Queue struct {
 	deviceRef C.WGPUDevice
 	ref       C.WGPUQueue
+	label     string
+	refs      refCount
+
+	device   *Device
+	submitMu *sync.Mutex
 }
 
 //export gowebgpu_queue_work_done_callback_go
@@ -52,12 +59,20 @@ func gowebgpu_queue_work_done_callback_go(status C.WGPUQueueWorkDoneStatus, user
 }
 
 func (p *Queue) OnSubmittedWorkDone(callback QueueWorkDoneCallback) {
+	p.refs.check("Queue.OnSubmittedWorkDone", p.label)
 	handle := cgo.NewHandle(callback)
 
 	C.wgpuQueueOnSubmittedWorkDone(p.ref, C.WGPUQueueOnSubmittedWorkDoneCallback(C.gowebgpu_queue_work_done_callback_c), unsafe.Pointer(&handle))
 }
 
 func (p *Queue) Submit(commands ...*CommandBuffer) (submissionIndex SubmissionIndex) {
+	p.refs.check("Queue.Submit", p.label)
+	p.submitMu.Lock()
+	defer p.submitMu.Unlock()
+	return p.submit("Queue.Submit", commands)
+}
+
+func (p *Queue) submit(op string, commands []*CommandBuffer) SubmissionIndex {
 	commandCount := len(commands)
 	if commandCount == 0 {
 		r := C.wgpuQueueSubmitForIndex(p.ref, 0, nil)
@@ -69,6 +84,7 @@ func (p *Queue) Submit(commands ...*CommandBuffer) (submissionIndex SubmissionIn
 
 	commandRefsSlice := unsafe.Slice((*C.WGPUCommandBuffer)(commandRefs), commandCount)
 	for i, v := range commands {
+		v.refs.check(op, v.label)
 		commandRefsSlice[i] = v.ref
 	}
 
@@ -80,10 +96,69 @@ func (p *Queue) Submit(commands ...*CommandBuffer) (submissionIndex SubmissionIn
 	return SubmissionIndex(r)
 }
 
-func (p *Queue) WriteBuffer(buffer *Buffer, bufferOffset uint64, data []byte) (err error) {
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Queue).WriteBuffer(): " + message)
+// SubmitTracked submits commands like Submit and returns a Submission
+// that completes with them.
+func (p *Queue) SubmitTracked(commands ...*CommandBuffer) *Submission {
+	p.refs.check("Queue.SubmitTracked", p.label)
+	label := p.label
+
+	// The lock keeps the submissions of other goroutines from landing
+	// between the submission and the callback, which would then wait for
+	// them too.
+	p.submitMu.Lock()
+	s := newSubmission(p.submit("Queue.SubmitTracked", commands))
+	p.OnSubmittedWorkDone(func(status QueueWorkDoneStatus) {
+		s.complete(submissionError(label, status))
+	})
+	p.submitMu.Unlock()
+
+	queueRef, deviceRef := p.ref, p.deviceRef
+	var deviceID uintptr
+	if p.device != nil {
+		deviceID = p.device.id
 	}
+	s.poll = func() {
+		// The references keep the queue and device alive if they are
+		// released while a poll blocks.
+		C.wgpuQueueReference(queueRef)
+		C.wgpuDeviceReference(deviceRef)
+		defer C.wgpuQueueRelease(queueRef)
+		defer C.wgpuDeviceRelease(deviceRef)
+
+		index := C.WGPUWrappedSubmissionIndex{
+			queue:           queueRef,
+			submissionIndex: C.WGPUSubmissionIndex(s.Index),
+		}
+		const maxDelay = time.Millisecond
+		delay := 10 * time.Microsecond
+		for {
+			C.wgpuDevicePoll(deviceRef, cBool(true), &index)
+			// Another goroutine's poll may be running the callback.
+			timer := time.NewTimer(delay)
+			select {
+			case <-s.done:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			// A released device is not lost: the references keep it
+			// working until the submission completes.
+			if state := lookupDeviceState(deviceID); state != nil {
+				if err := state.lost.err("Queue.SubmitTracked"); err != nil {
+					s.complete(err)
+					return
+				}
+			}
+			delay = min(2*delay, maxDelay)
+		}
+	}
+	return s
+}
+
+func (p *Queue) WriteBuffer(buffer *Buffer, bufferOffset uint64, data []byte) (err error) {
+	p.refs.check("Queue.WriteBuffer", p.label)
+	buffer.refs.check("Queue.WriteBuffer", buffer.label)
+	cb := newErrorCallback(&err, "Queue.WriteBuffer", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -114,6 +189,7 @@ func (p *Queue) WriteBuffer(buffer *Buffer, bufferOffset uint64, data []byte) (e
 }
 
 func (p *Queue) WriteTexture(destination *ImageCopyTexture, data []byte, dataLayout *TextureDataLayout, writeSize *Extent3D) (err error) {
+	p.refs.check("Queue.WriteTexture", p.label)
 	var dst C.WGPUImageCopyTexture
 	if destination != nil {
 		dst = C.WGPUImageCopyTexture{
@@ -126,6 +202,7 @@ func (p *Queue) WriteTexture(destination *ImageCopyTexture, data []byte, dataLay
 			aspect: C.WGPUTextureAspect(destination.Aspect),
 		}
 		if destination.Texture != nil {
+			destination.Texture.refs.check("Queue.WriteTexture", destination.Texture.label)
 			dst.texture = destination.Texture.ref
 		}
 	}
@@ -148,9 +225,7 @@ func (p *Queue) WriteTexture(destination *ImageCopyTexture, data []byte, dataLay
 		}
 	}
 
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Queue).WriteTexture(): " + message)
-	}
+	cb := newErrorCallback(&err, "Queue.WriteTexture", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -183,5 +258,7 @@ func (p *Queue) WriteTexture(destination *ImageCopyTexture, data []byte, dataLay
 }
 
 func (p *Queue) Release() {
-	C.gowebgpu_queue_release(p.ref, p.deviceRef)
+	if p.refs.release("Queue.Release", p.label) {
+		C.gowebgpu_queue_release(p.ref, p.deviceRef)
+	}
 }
@@ -27,6 +27,17 @@ func (g Queue) Submit(commandBuffers ...*CommandBuffer) {
 	g.jsValue.Call("submit", jsSequence)
 }
 
+// SubmitTracked submits commands like Submit and returns a Submission
+// that completes when the promise of onSubmittedWorkDone settles.
+func (g Queue) SubmitTracked(commandBuffers ...*CommandBuffer) *Submission {
+	g.Submit(commandBuffers...)
+	s := newSubmission(0)
+	g.OnSubmittedWorkDone(func(status QueueWorkDoneStatus) {
+		s.complete(submissionError(g.Label(), status))
+	})
+	return s
+}
+
 // WriteBuffer as described:
 // https://gpuweb.github.io/gpuweb/#dom-gpuqueue-writebuffer
 func (g Queue) WriteBuffer(buffer *Buffer, offset uint64, data []byte) (err error) {
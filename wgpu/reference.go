@@ -0,0 +1,187 @@
+//go:build !js
+
+package wgpu
+
+import "sync/atomic"
+
+// Ownership
+//
+// Each handle the package returns, such as a *Buffer, holds one
+// reference to its wgpu object, which the owner drops with Release.
+// To share a handle between owners that each call Release, give every
+// other owner the result of Reference. The object is released with the
+// last reference, along with the device reference that buffers,
+// textures, queues, encoders and passes hold.
+//
+// Calling a method of a handle whose references were all released,
+// passing it to a method, as with Queue.WriteBuffer or
+// RenderPassEncoder.SetBindGroup, or in a descriptor, as with
+// Device.CreateBindGroup, or releasing it once more, panics with an
+// *Error rather than crashing in wgpu-native.
+//
+// Built with the wgpu_autorelease tag, handles the garbage collector
+// finds unreachable before their last Release are released by the
+// package. Explicit Release stays the way to free an object promptly,
+// and it is idempotent in that mode: releasing a released handle does
+// nothing instead of panicking, so code written for either mode works.
+
+// refCount counts the references of a handle beyond the first, so that
+// its zero value holds one. It is negative once the handle is released.
+type refCount struct {
+	extra atomic.Int32
+	// id identifies the handle in the leak tracker, if it is tracked.
+	id uint64
+}
+
+// reference takes another reference, panicking if the handle is
+// released.
+func (r *refCount) reference(op, label string) {
+	for {
+		n := r.extra.Load()
+		if n < 0 {
+			panic(releasedError(op, label))
+		}
+		if r.extra.CompareAndSwap(n, n+1) {
+			return
+		}
+	}
+}
+
+// release drops a reference and reports whether it was the last one,
+// panicking if the handle is already released, unless auto-release is
+// built in.
+func (r *refCount) release(op, label string) bool {
+	n := r.extra.Add(-1)
+	if n < -1 {
+		if autoRelease {
+			return false
+		}
+		panic(releasedError(op, label))
+	}
+	if n == -1 {
+		untrackHandle(r.id)
+		return true
+	}
+	return false
+}
+
+// check panics if the handle is released.
+func (r *refCount) check(op, label string) {
+	if r.extra.Load() < 0 {
+		panic(releasedError(op, label))
+	}
+}
+
+func releasedError(op, label string) *Error {
+	return &Error{Op: op, Label: label, Type: ErrorTypeValidation, Message: "handle used after Release"}
+}
+
+func (p *Adapter) Reference() *Adapter {
+	p.refs.reference("Adapter.Reference", "")
+	return p
+}
+
+func (p *BindGroup) Reference() *BindGroup {
+	p.refs.reference("BindGroup.Reference", p.label)
+	return p
+}
+
+func (p *BindGroupLayout) Reference() *BindGroupLayout {
+	p.refs.reference("BindGroupLayout.Reference", p.label)
+	return p
+}
+
+func (p *Buffer) Reference() *Buffer {
+	p.refs.reference("Buffer.Reference", p.label)
+	return p
+}
+
+func (p *CommandBuffer) Reference() *CommandBuffer {
+	p.refs.reference("CommandBuffer.Reference", p.label)
+	return p
+}
+
+func (p *CommandEncoder) Reference() *CommandEncoder {
+	p.refs.reference("CommandEncoder.Reference", p.label)
+	return p
+}
+
+func (p *ComputePassEncoder) Reference() *ComputePassEncoder {
+	p.refs.reference("ComputePassEncoder.Reference", p.label)
+	return p
+}
+
+func (p *ComputePipeline) Reference() *ComputePipeline {
+	p.refs.reference("ComputePipeline.Reference", p.label)
+	return p
+}
+
+func (p *Device) Reference() *Device {
+	p.refs.reference("Device.Reference", p.label)
+	return p
+}
+
+func (p *Instance) Reference() *Instance {
+	p.refs.reference("Instance.Reference", "")
+	return p
+}
+
+func (p *PipelineLayout) Reference() *PipelineLayout {
+	p.refs.reference("PipelineLayout.Reference", p.label)
+	return p
+}
+
+func (p *QuerySet) Reference() *QuerySet {
+	p.refs.reference("QuerySet.Reference", p.label)
+	return p
+}
+
+func (p *Queue) Reference() *Queue {
+	p.refs.reference("Queue.Reference", p.label)
+	return p
+}
+
+func (p *RenderBundle) Reference() *RenderBundle {
+	p.refs.reference("RenderBundle.Reference", p.label)
+	return p
+}
+
+func (p *RenderBundleEncoder) Reference() *RenderBundleEncoder {
+	p.refs.reference("RenderBundleEncoder.Reference", p.label)
+	return p
+}
+
+func (p *RenderPassEncoder) Reference() *RenderPassEncoder {
+	p.refs.reference("RenderPassEncoder.Reference", p.label)
+	return p
+}
+
+func (p *RenderPipeline) Reference() *RenderPipeline {
+	p.refs.reference("RenderPipeline.Reference", p.label)
+	return p
+}
+
+func (p *Sampler) Reference() *Sampler {
+	p.refs.reference("Sampler.Reference", p.label)
+	return p
+}
+
+func (p *ShaderModule) Reference() *ShaderModule {
+	p.refs.reference("ShaderModule.Reference", p.label)
+	return p
+}
+
+func (p *Surface) Reference() *Surface {
+	p.refs.reference("Surface.Reference", "")
+	return p
+}
+
+func (p *Texture) Reference() *Texture {
+	p.refs.reference("Texture.Reference", p.label)
+	return p
+}
+
+func (p *TextureView) Reference() *TextureView {
+	p.refs.reference("TextureView.Reference", p.label)
+	return p
+}
@@ -0,0 +1,27 @@
+//go:build js
+
+package wgpu
+
+// Reference returns the handle, for an owner that releases it separately.
+// The browser collects the objects, so Release is a no-op and does not
+// need to be balanced.
+
+func (g Adapter) Reference() *Adapter                       { return &g }
+func (g BindGroup) Reference() *BindGroup                   { return &g }
+func (g BindGroupLayout) Reference() *BindGroupLayout       { return &g }
+func (g Buffer) Reference() *Buffer                         { return &g }
+func (g CommandBuffer) Reference() *CommandBuffer           { return &g }
+func (g CommandEncoder) Reference() *CommandEncoder         { return &g }
+func (g ComputePassEncoder) Reference() *ComputePassEncoder { return &g }
+func (g ComputePipeline) Reference() *ComputePipeline       { return &g }
+func (g Device) Reference() *Device                         { return &g }
+func (g Instance) Reference() *Instance                     { return &g }
+func (g PipelineLayout) Reference() *PipelineLayout         { return &g }
+func (g Queue) Reference() *Queue                           { return &g }
+func (g RenderPassEncoder) Reference() *RenderPassEncoder   { return &g }
+func (g RenderPipeline) Reference() *RenderPipeline         { return &g }
+func (g Sampler) Reference() *Sampler                       { return &g }
+func (g ShaderModule) Reference() *ShaderModule             { return &g }
+func (g Surface) Reference() *Surface                       { return &g }
+func (g Texture) Reference() *Texture                       { return &g }
+func (g TextureView) Reference() *TextureView               { return &g }
@@ -0,0 +1,283 @@
+package wgpu
+
+import (
+	"fmt"
+
+	"github.com/openfluke/webgpu/wgsl"
+)
+
+// ReflectBindGroupLayouts derives bind group layout descriptors from the
+// resource declarations of one or more WGSL modules, such as the vertex
+// and fragment modules of a render pipeline. The result is indexed by
+// group number; groups without bindings get an empty descriptor.
+//
+// The visibility of an entry is the set of stages whose entry points use
+// it. Declarations that no entry point uses are kept with
+// ShaderStageNone, so bind groups still have to provide them. A sampler
+// is non-filtering if no module samples a filterable texture through it.
+func ReflectBindGroupLayouts(modules ...*wgsl.Reflection) ([]BindGroupLayoutDescriptor, error) {
+	var descriptors []BindGroupLayoutDescriptor
+	index := map[[2]uint32]int{}
+	sampled := map[[2]uint32][][2]uint32{}
+
+	for _, module := range modules {
+		for _, b := range module.Bindings {
+			entry, err := BindGroupLayoutEntryFromWGSL(b)
+			if err != nil {
+				return nil, err
+			}
+			key := [2]uint32{b.Group, b.Binding}
+			for _, t := range b.Textures {
+				sampled[key] = append(sampled[key], [2]uint32{t.Group, t.Binding})
+			}
+			for int(b.Group) >= len(descriptors) {
+				descriptors = append(descriptors, BindGroupLayoutDescriptor{})
+			}
+			desc := &descriptors[b.Group]
+
+			if i, ok := index[key]; ok {
+				merged, err := mergeLayoutEntries(desc.Entries[i], entry)
+				if err != nil {
+					return nil, fmt.Errorf("wgpu.ReflectBindGroupLayouts(): @group(%d) @binding(%d): %w", b.Group, b.Binding, err)
+				}
+				desc.Entries[i] = merged
+				continue
+			}
+			index[key] = len(desc.Entries)
+			desc.Entries = append(desc.Entries, entry)
+		}
+	}
+
+	// Decide the type of samplers from the merged texture entries.
+	for key, textures := range sampled {
+		entry := &descriptors[key[0]].Entries[index[key]]
+		if entry.Sampler.Type == SamplerBindingTypeComparison {
+			continue
+		}
+		entry.Sampler.Type = SamplerBindingTypeNonFiltering
+		for _, t := range textures {
+			if descriptors[t[0]].Entries[index[t]].Texture.SampleType == TextureSampleTypeFloat {
+				entry.Sampler.Type = SamplerBindingTypeFiltering
+			}
+		}
+	}
+	return descriptors, nil
+}
+
+// BindGroupLayoutEntryFromWGSL converts a single reflected binding to a
+// BindGroupLayoutEntry.
+//
+// Textures with an f32 sampled type bind as TextureSampleTypeFloat when
+// the shader samples them through a sampler and as
+// TextureSampleTypeUnfilterableFloat otherwise. Samplers bind as
+// SamplerBindingTypeComparison when declared as sampler_comparison, as
+// SamplerBindingTypeNonFiltering when the shader samples only depth or
+// unfilterable textures through them, and as SamplerBindingTypeFiltering
+// otherwise.
+func BindGroupLayoutEntryFromWGSL(b *wgsl.Binding) (BindGroupLayoutEntry, error) {
+	entry := BindGroupLayoutEntry{
+		Binding:    b.Binding,
+		Visibility: ShaderStage(b.Visibility),
+	}
+	fail := func(msg string) (BindGroupLayoutEntry, error) {
+		return BindGroupLayoutEntry{}, fmt.Errorf("wgpu.BindGroupLayoutEntryFromWGSL(): %s (@group(%d) @binding(%d)): %s", b.Name, b.Group, b.Binding, msg)
+	}
+
+	switch t := b.Type.(type) {
+	case *wgsl.Sampler:
+		switch {
+		case t.Comparison:
+			entry.Sampler.Type = SamplerBindingTypeComparison
+		case len(b.Textures) > 0 && !samplesFilterable(b.Textures):
+			entry.Sampler.Type = SamplerBindingTypeNonFiltering
+		default:
+			entry.Sampler.Type = SamplerBindingTypeFiltering
+		}
+
+	case *wgsl.Texture:
+		dim, ok := textureViewDimensions[t.Dim]
+		if !ok {
+			return fail("unsupported texture dimension " + string(t.Dim))
+		}
+		switch t.Kind {
+		case wgsl.TextureSampled, wgsl.TextureMultisampled:
+			entry.Texture.ViewDimension = dim
+			entry.Texture.Multisampled = t.Kind == wgsl.TextureMultisampled
+			switch t.Sampled {
+			case wgsl.F32:
+				entry.Texture.SampleType = TextureSampleTypeUnfilterableFloat
+				if b.Filtered && !entry.Texture.Multisampled {
+					entry.Texture.SampleType = TextureSampleTypeFloat
+				}
+			case wgsl.I32:
+				entry.Texture.SampleType = TextureSampleTypeSint
+			case wgsl.U32:
+				entry.Texture.SampleType = TextureSampleTypeUint
+			default:
+				return fail("unsupported sampled type " + t.Sampled.String())
+			}
+		case wgsl.TextureDepth, wgsl.TextureDepthMultisampled:
+			entry.Texture.SampleType = TextureSampleTypeDepth
+			entry.Texture.ViewDimension = dim
+			entry.Texture.Multisampled = t.Kind == wgsl.TextureDepthMultisampled
+		case wgsl.TextureStorage:
+			format, ok := textureFormatFromWGSL(t.Format)
+			if !ok {
+				return fail("unknown texel format " + t.Format)
+			}
+			access, ok := storageTextureAccesses[t.Access]
+			if !ok {
+				return fail("unknown access mode " + t.Access)
+			}
+			entry.StorageTexture = StorageTextureBindingLayout{
+				Access:        access,
+				Format:        format,
+				ViewDimension: dim,
+			}
+		case wgsl.TextureExternal:
+			if err := setExternalTextureLayout(&entry); err != nil {
+				return fail(err.Error())
+			}
+		}
+
+	default:
+		switch b.AddressSpace {
+		case wgsl.Uniform:
+			entry.Buffer.Type = BufferBindingTypeUniform
+		case wgsl.Storage:
+			entry.Buffer.Type = BufferBindingTypeReadOnlyStorage
+			if b.Access == "read_write" {
+				entry.Buffer.Type = BufferBindingTypeStorage
+			}
+		default:
+			return fail("unsupported address space " + string(b.AddressSpace))
+		}
+		entry.Buffer.MinBindingSize = wgsl.SizeOf(b.Type)
+	}
+	return entry, nil
+}
+
+// CreateReflectedPipelineLayout parses the given WGSL sources, derives
+// their bind group layouts with ReflectBindGroupLayouts and creates the
+// bind group layouts and the pipeline layout in one call.
+func (p *Device) CreateReflectedPipelineLayout(label string, sources ...string) (*ReflectedPipelineLayout, error) {
+	modules := make([]*wgsl.Reflection, len(sources))
+	for i, src := range sources {
+		r, err := wgsl.ReflectSource(src)
+		if err != nil {
+			return nil, fmt.Errorf("wgpu.(*Device).CreateReflectedPipelineLayout(): %w", err)
+		}
+		modules[i] = r
+	}
+	descriptors, err := ReflectBindGroupLayouts(modules...)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &ReflectedPipelineLayout{}
+	for i := range descriptors {
+		if label != "" {
+			descriptors[i].Label = fmt.Sprintf("%s group %d", label, i)
+		}
+		bgl, err := p.CreateBindGroupLayout(&descriptors[i])
+		if err != nil {
+			layout.Release()
+			return nil, err
+		}
+		layout.BindGroupLayouts = append(layout.BindGroupLayouts, bgl)
+	}
+
+	layout.Layout, err = p.CreatePipelineLayout(&PipelineLayoutDescriptor{
+		Label:            label,
+		BindGroupLayouts: layout.BindGroupLayouts,
+	})
+	if err != nil {
+		layout.Release()
+		return nil, err
+	}
+	layout.Descriptors = descriptors
+	return layout, nil
+}
+
+// ReflectedPipelineLayout is a pipeline layout created from WGSL
+// reflection together with the bind group layouts it is made of, in
+// group order.
+type ReflectedPipelineLayout struct {
+	Layout           *PipelineLayout
+	BindGroupLayouts []*BindGroupLayout
+	Descriptors      []BindGroupLayoutDescriptor
+}
+
+// Release releases the pipeline layout and all bind group layouts.
+func (l *ReflectedPipelineLayout) Release() {
+	if l.Layout != nil {
+		l.Layout.Release()
+		l.Layout = nil
+	}
+	for _, bgl := range l.BindGroupLayouts {
+		bgl.Release()
+	}
+	l.BindGroupLayouts = nil
+}
+
+var textureViewDimensions = map[wgsl.TextureDim]TextureViewDimension{
+	wgsl.Dim1D:        TextureViewDimension1D,
+	wgsl.Dim2D:        TextureViewDimension2D,
+	wgsl.Dim2DArray:   TextureViewDimension2DArray,
+	wgsl.Dim3D:        TextureViewDimension3D,
+	wgsl.DimCube:      TextureViewDimensionCube,
+	wgsl.DimCubeArray: TextureViewDimensionCubeArray,
+}
+
+var storageTextureAccesses = map[string]StorageTextureAccess{
+	"write":      StorageTextureAccessWriteOnly,
+	"read":       StorageTextureAccessReadOnly,
+	"read_write": StorageTextureAccessReadWrite,
+}
+
+// textureFormatFromWGSL maps a WGSL texel format name such as
+// "rgba8unorm" to its TextureFormat.
+func textureFormatFromWGSL(name string) (TextureFormat, bool) {
+	for f := TextureFormatR8Unorm; f <= TextureFormatASTC12x12UnormSrgb; f++ {
+		if f.String() == name {
+			return f, true
+		}
+	}
+	return TextureFormatUndefined, false
+}
+
+// samplesFilterable reports whether one of textures binds as
+// TextureSampleTypeFloat.
+func samplesFilterable(textures []*wgsl.Binding) bool {
+	for _, t := range textures {
+		if entry, err := BindGroupLayoutEntryFromWGSL(t); err == nil && entry.Texture.SampleType == TextureSampleTypeFloat {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeLayoutEntries combines the entries two modules declare for the
+// same binding. They must agree on everything but visibility.
+func mergeLayoutEntries(a, b BindGroupLayoutEntry) (BindGroupLayoutEntry, error) {
+	visibility := a.Visibility | b.Visibility
+	a.Visibility, b.Visibility = 0, 0
+	if a.Buffer.Type != BufferBindingTypeUndefined && a.Buffer.Type == b.Buffer.Type {
+		a.Buffer.MinBindingSize = max(a.Buffer.MinBindingSize, b.Buffer.MinBindingSize)
+		b.Buffer.MinBindingSize = a.Buffer.MinBindingSize
+	}
+	// Samplers are decided once all modules are merged.
+	if a.Sampler.Type != b.Sampler.Type && a.Sampler.Type != SamplerBindingTypeComparison && b.Sampler.Type != SamplerBindingTypeComparison {
+		b.Sampler.Type = a.Sampler.Type
+	}
+	if a.Texture.SampleType == TextureSampleTypeUnfilterableFloat && b.Texture.SampleType == TextureSampleTypeFloat {
+		a.Texture.SampleType = TextureSampleTypeFloat
+	} else if b.Texture.SampleType == TextureSampleTypeUnfilterableFloat && a.Texture.SampleType == TextureSampleTypeFloat {
+		b.Texture.SampleType = TextureSampleTypeFloat
+	}
+	if a.Buffer != b.Buffer || a.Sampler != b.Sampler || a.Texture != b.Texture || a.StorageTexture != b.StorageTexture {
+		return BindGroupLayoutEntry{}, fmt.Errorf("conflicting declarations")
+	}
+	a.Visibility = visibility
+	return a, nil
+}
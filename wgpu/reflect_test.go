@@ -0,0 +1,117 @@
+package wgpu
+
+import (
+	"testing"
+
+	"github.com/openfluke/webgpu/wgsl"
+)
+
+func TestReflectSamplerTypes(t *testing.T) {
+	const src = `
+@group(0) @binding(0) var color: texture_2d<f32>;
+@group(0) @binding(1) var depth: texture_depth_2d;
+@group(0) @binding(2) var linear: sampler;
+@group(0) @binding(3) var nearest: sampler;
+@group(0) @binding(4) var shadow: sampler_comparison;
+@group(0) @binding(5) var unused: sampler;
+
+fn sampleDepth(t: texture_depth_2d, s: sampler, uv: vec2<f32>) -> f32 {
+	return textureSample(t, s, uv);
+}
+
+@fragment
+fn main(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+	let c = textureSample(color, linear, uv);
+	let d = sampleDepth(depth, nearest, uv) + textureSampleCompare(depth, shadow, uv, 0.5);
+	return c * d;
+}
+`
+	r, err := wgsl.ReflectSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptors, err := ReflectBindGroupLayouts(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint32]SamplerBindingType{
+		2: SamplerBindingTypeFiltering,
+		3: SamplerBindingTypeNonFiltering,
+		4: SamplerBindingTypeComparison,
+		5: SamplerBindingTypeFiltering,
+	}
+	for _, entry := range descriptors[0].Entries {
+		if typ, ok := want[entry.Binding]; ok && entry.Sampler.Type != typ {
+			t.Errorf("binding %d: got %v, want %v", entry.Binding, entry.Sampler.Type, typ)
+		}
+	}
+	if got := descriptors[0].Entries[0].Texture.SampleType; got != TextureSampleTypeFloat {
+		t.Errorf("color texture: got %v, want TextureSampleTypeFloat", got)
+	}
+}
+
+func TestReflectSamplerTypesMerged(t *testing.T) {
+	// The vertex module samples a depth texture through the sampler and
+	// the fragment module a filterable one, so the sampler must filter.
+	const vertex = `
+@group(0) @binding(0) var depth: texture_depth_2d;
+@group(0) @binding(2) var samp: sampler;
+@vertex
+fn main() -> @builtin(position) vec4<f32> {
+	return vec4<f32>(textureSampleLevel(depth, samp, vec2<f32>(0.0), 0));
+}
+`
+	const fragment = `
+@group(0) @binding(1) var color: texture_2d<f32>;
+@group(0) @binding(2) var samp: sampler;
+@fragment
+fn main() -> @location(0) vec4<f32> {
+	return textureSample(color, samp, vec2<f32>(0.0));
+}
+`
+	const depthOnly = `
+@group(0) @binding(0) var depth: texture_depth_2d;
+@group(0) @binding(2) var samp: sampler;
+@fragment
+fn main() -> @location(0) vec4<f32> {
+	return vec4<f32>(textureSample(depth, samp, vec2<f32>(0.0)));
+}
+`
+	const unused = `
+@group(0) @binding(2) var samp: sampler;
+@vertex
+fn main() -> @builtin(position) vec4<f32> {
+	return vec4<f32>(0.0);
+}
+`
+	tests := []struct {
+		name    string
+		sources []string
+		want    SamplerBindingType
+	}{
+		{"depth and color", []string{vertex, fragment}, SamplerBindingTypeFiltering},
+		{"depth only", []string{unused, depthOnly}, SamplerBindingTypeNonFiltering},
+		{"depth only reversed", []string{depthOnly, unused}, SamplerBindingTypeNonFiltering},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var modules []*wgsl.Reflection
+			for _, src := range tt.sources {
+				r, err := wgsl.ReflectSource(src)
+				if err != nil {
+					t.Fatal(err)
+				}
+				modules = append(modules, r)
+			}
+			descriptors, err := ReflectBindGroupLayouts(modules...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range descriptors[0].Entries {
+				if entry.Binding == 2 && entry.Sampler.Type != tt.want {
+					t.Errorf("got %v, want %v", entry.Sampler.Type, tt.want)
+				}
+			}
+		})
+	}
+}
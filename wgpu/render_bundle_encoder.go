@@ -12,10 +12,13 @@ import "C"
 import "unsafe"
 
 type RenderBundleEncoder struct {
-	ref C.WGPURenderBundleEncoder
+	ref   C.WGPURenderBundleEncoder
+	label string
+	refs  refCount
 }
 
 func (p *RenderBundleEncoder) Draw(vertexCount, instanceCount, firstVertex, firstInstance uint32) {
+	p.refs.check("RenderBundleEncoder.Draw", p.label)
 	C.wgpuRenderBundleEncoderDraw(
 		p.ref,
 		C.uint32_t(vertexCount),
@@ -26,6 +29,7 @@ func (p *RenderBundleEncoder) Draw(vertexCount, instanceCount, firstVertex, firs
 }
 
 func (p *RenderBundleEncoder) DrawIndexed(indexCount, instanceCount, firstIndex, baseVertex, firstInstance uint32) {
+	p.refs.check("RenderBundleEncoder.DrawIndexed", p.label)
 	C.wgpuRenderBundleEncoderDrawIndexed(
 		p.ref,
 		C.uint32_t(indexCount),
@@ -37,6 +41,8 @@ func (p *RenderBundleEncoder) DrawIndexed(indexCount, instanceCount, firstIndex,
 }
 
 func (p *RenderBundleEncoder) DrawIndexedIndirect(indirectBuffer *Buffer, indirectOffset uint64) {
+	p.refs.check("RenderBundleEncoder.DrawIndexedIndirect", p.label)
+	indirectBuffer.refs.check("RenderBundleEncoder.DrawIndexedIndirect", indirectBuffer.label)
 	C.wgpuRenderBundleEncoderDrawIndexedIndirect(
 		p.ref,
 		indirectBuffer.ref,
@@ -45,6 +51,8 @@ func (p *RenderBundleEncoder) DrawIndexedIndirect(indirectBuffer *Buffer, indire
 }
 
 func (p *RenderBundleEncoder) DrawIndirect(indirectBuffer *Buffer, indirectOffset uint64) {
+	p.refs.check("RenderBundleEncoder.DrawIndirect", p.label)
+	indirectBuffer.refs.check("RenderBundleEncoder.DrawIndirect", indirectBuffer.label)
 	C.wgpuRenderBundleEncoderDrawIndirect(
 		p.ref,
 		indirectBuffer.ref,
@@ -57,6 +65,7 @@ type RenderBundleDescriptor struct {
 }
 
 func (p *RenderBundleEncoder) Finish(descriptor *RenderBundleDescriptor) *RenderBundle {
+	p.refs.check("RenderBundleEncoder.Finish", p.label)
 	var desc *C.WGPURenderBundleDescriptor
 
 	if descriptor != nil {
@@ -72,10 +81,16 @@ func (p *RenderBundleEncoder) Finish(descriptor *RenderBundleDescriptor) *Render
 	if ref == nil {
 		panic("Failed to accquire RenderBundle")
 	}
-	return &RenderBundle{ref}
+	bundle := &RenderBundle{ref: ref}
+	if descriptor != nil {
+		bundle.label = descriptor.Label
+	}
+	track(bundle, "RenderBundle", bundle.label)
+	return bundle
 }
 
 func (p *RenderBundleEncoder) InsertDebugMarker(markerLabel string) {
+	p.refs.check("RenderBundleEncoder.InsertDebugMarker", p.label)
 	markerLabelStr := C.CString(markerLabel)
 	defer C.free(unsafe.Pointer(markerLabelStr))
 
@@ -83,10 +98,12 @@ func (p *RenderBundleEncoder) InsertDebugMarker(markerLabel string) {
 }
 
 func (p *RenderBundleEncoder) PopDebugGroup() {
+	p.refs.check("RenderBundleEncoder.PopDebugGroup", p.label)
 	C.wgpuRenderBundleEncoderPopDebugGroup(p.ref)
 }
 
 func (p *RenderBundleEncoder) PushDebugGroup(groupLabel string) {
+	p.refs.check("RenderBundleEncoder.PushDebugGroup", p.label)
 	groupLabelStr := C.CString(groupLabel)
 	defer C.free(unsafe.Pointer(groupLabelStr))
 
@@ -94,6 +111,8 @@ func (p *RenderBundleEncoder) PushDebugGroup(groupLabel string) {
 }
 
 func (p *RenderBundleEncoder) SetBindGroup(groupIndex uint32, group *BindGroup, dynamicOffsets []uint32) {
+	p.refs.check("RenderBundleEncoder.SetBindGroup", p.label)
+	group.refs.check("RenderBundleEncoder.SetBindGroup", group.label)
 	dynamicOffsetCount := len(dynamicOffsets)
 	if dynamicOffsetCount == 0 {
 		C.wgpuRenderBundleEncoderSetBindGroup(p.ref, C.uint32_t(groupIndex), group.ref, 0, nil)
@@ -106,6 +125,8 @@ func (p *RenderBundleEncoder) SetBindGroup(groupIndex uint32, group *BindGroup,
 }
 
 func (p *RenderBundleEncoder) SetIndexBuffer(buffer *Buffer, format IndexFormat, offset uint64, size uint64) {
+	p.refs.check("RenderBundleEncoder.SetIndexBuffer", p.label)
+	buffer.refs.check("RenderBundleEncoder.SetIndexBuffer", buffer.label)
 	C.wgpuRenderBundleEncoderSetIndexBuffer(
 		p.ref,
 		buffer.ref,
@@ -116,10 +137,14 @@ func (p *RenderBundleEncoder) SetIndexBuffer(buffer *Buffer, format IndexFormat,
 }
 
 func (p *RenderBundleEncoder) SetPipeline(pipeline *RenderPipeline) {
+	p.refs.check("RenderBundleEncoder.SetPipeline", p.label)
+	pipeline.refs.check("RenderBundleEncoder.SetPipeline", pipeline.label)
 	C.wgpuRenderBundleEncoderSetPipeline(p.ref, pipeline.ref)
 }
 
 func (p *RenderBundleEncoder) SetVertexBuffer(slot uint32, buffer *Buffer, offset uint64, size uint64) {
+	p.refs.check("RenderBundleEncoder.SetVertexBuffer", p.label)
+	buffer.refs.check("RenderBundleEncoder.SetVertexBuffer", buffer.label)
 	C.wgpuRenderBundleEncoderSetVertexBuffer(
 		p.ref,
 		C.uint32_t(slot),
@@ -130,5 +155,7 @@ func (p *RenderBundleEncoder) SetVertexBuffer(slot uint32, buffer *Buffer, offse
 }
 
 func (p *RenderBundleEncoder) Release() {
-	C.wgpuRenderBundleEncoderRelease(p.ref)
+	if p.refs.release("RenderBundleEncoder.Release", p.label) {
+		C.wgpuRenderBundleEncoderRelease(p.ref)
+	}
 }
@@ -7,12 +7,13 @@ package wgpu
 #include <stdlib.h>
 #include "./lib/wgpu.h"
 
-extern void gowebgpu_error_callback_c(WGPUErrorType type, char const * message, void * userdata);
+extern void gowebgpu_push_error_scopes(WGPUDevice device);
+extern void gowebgpu_pop_error_scopes(WGPUDevice device, void * userdata);
 
 static inline void gowebgpu_render_pass_encoder_end(WGPURenderPassEncoder renderPassEncoder, WGPUDevice device, void * error_userdata) {
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuRenderPassEncoderEnd(renderPassEncoder);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 }
 
 static inline void gowebgpu_render_pass_encoder_release(WGPURenderPassEncoder renderPassEncoder, WGPUDevice device) {
@@ -23,7 +24,6 @@ static inline void gowebgpu_render_pass_encoder_release(WGPURenderPassEncoder re
 */
 import "C"
 import (
-	"errors"
 	"runtime/cgo"
 	"unsafe"
 )
@@ -31,17 +31,23 @@ import (
 type RenderPassEncoder struct {
 	deviceRef C.WGPUDevice
 	ref       C.WGPURenderPassEncoder
+	label     string
+	refs      refCount
 }
 
 func (p *RenderPassEncoder) BeginOcclusionQuery(queryIndex uint32) {
+	p.refs.check("RenderPassEncoder.BeginOcclusionQuery", p.label)
 	C.wgpuRenderPassEncoderBeginOcclusionQuery(p.ref, C.uint32_t(queryIndex))
 }
 
 func (p *RenderPassEncoder) BeginPipelineStatisticsQuery(querySet *QuerySet, queryIndex uint32) {
+	p.refs.check("RenderPassEncoder.BeginPipelineStatisticsQuery", p.label)
+	querySet.refs.check("RenderPassEncoder.BeginPipelineStatisticsQuery", querySet.label)
 	C.wgpuRenderPassEncoderBeginPipelineStatisticsQuery(p.ref, querySet.ref, C.uint32_t(queryIndex))
 }
 
 func (p *RenderPassEncoder) Draw(vertexCount, instanceCount, firstVertex, firstInstance uint32) {
+	p.refs.check("RenderPassEncoder.Draw", p.label)
 	C.wgpuRenderPassEncoderDraw(p.ref,
 		C.uint32_t(vertexCount),
 		C.uint32_t(instanceCount),
@@ -51,6 +57,7 @@ func (p *RenderPassEncoder) Draw(vertexCount, instanceCount, firstVertex, firstI
 }
 
 func (p *RenderPassEncoder) DrawIndexed(indexCount uint32, instanceCount uint32, firstIndex uint32, baseVertex int32, firstInstance uint32) {
+	p.refs.check("RenderPassEncoder.DrawIndexed", p.label)
 	C.wgpuRenderPassEncoderDrawIndexed(p.ref,
 		C.uint32_t(indexCount),
 		C.uint32_t(instanceCount),
@@ -61,17 +68,20 @@ func (p *RenderPassEncoder) DrawIndexed(indexCount uint32, instanceCount uint32,
 }
 
 func (p *RenderPassEncoder) DrawIndexedIndirect(indirectBuffer *Buffer, indirectOffset uint64) {
+	p.refs.check("RenderPassEncoder.DrawIndexedIndirect", p.label)
+	indirectBuffer.refs.check("RenderPassEncoder.DrawIndexedIndirect", indirectBuffer.label)
 	C.wgpuRenderPassEncoderDrawIndexedIndirect(p.ref, indirectBuffer.ref, C.uint64_t(indirectOffset))
 }
 
 func (p *RenderPassEncoder) DrawIndirect(indirectBuffer *Buffer, indirectOffset uint64) {
+	p.refs.check("RenderPassEncoder.DrawIndirect", p.label)
+	indirectBuffer.refs.check("RenderPassEncoder.DrawIndirect", indirectBuffer.label)
 	C.wgpuRenderPassEncoderDrawIndirect(p.ref, indirectBuffer.ref, C.uint64_t(indirectOffset))
 }
 
 func (p *RenderPassEncoder) End() (err error) {
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*RenderPassEncoder).End(): " + message)
-	}
+	p.refs.check("RenderPassEncoder.End", p.label)
+	cb := newErrorCallback(&err, "RenderPassEncoder.End", p.label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -84,14 +94,17 @@ func (p *RenderPassEncoder) End() (err error) {
 }
 
 func (p *RenderPassEncoder) EndOcclusionQuery() {
+	p.refs.check("RenderPassEncoder.EndOcclusionQuery", p.label)
 	C.wgpuRenderPassEncoderEndOcclusionQuery(p.ref)
 }
 
 func (p *RenderPassEncoder) EndPipelineStatisticsQuery() {
+	p.refs.check("RenderPassEncoder.EndPipelineStatisticsQuery", p.label)
 	C.wgpuRenderPassEncoderEndPipelineStatisticsQuery(p.ref)
 }
 
 func (p *RenderPassEncoder) ExecuteBundles(bundles ...*RenderBundle) {
+	p.refs.check("RenderPassEncoder.ExecuteBundles", p.label)
 	bundlesCount := len(bundles)
 	if bundlesCount == 0 {
 		C.wgpuRenderPassEncoderExecuteBundles(p.ref, 0, nil)
@@ -103,6 +116,7 @@ func (p *RenderPassEncoder) ExecuteBundles(bundles ...*RenderBundle) {
 
 	bundlesSlice := unsafe.Slice((*C.WGPURenderBundle)(bundlesPtr), bundlesCount)
 	for i, v := range bundles {
+		v.refs.check("RenderPassEncoder.ExecuteBundles", v.label)
 		bundlesSlice[i] = v.ref
 	}
 
@@ -110,6 +124,7 @@ func (p *RenderPassEncoder) ExecuteBundles(bundles ...*RenderBundle) {
 }
 
 func (p *RenderPassEncoder) InsertDebugMarker(markerLabel string) {
+	p.refs.check("RenderPassEncoder.InsertDebugMarker", p.label)
 	markerLabelStr := C.CString(markerLabel)
 	defer C.free(unsafe.Pointer(markerLabelStr))
 
@@ -117,10 +132,12 @@ func (p *RenderPassEncoder) InsertDebugMarker(markerLabel string) {
 }
 
 func (p *RenderPassEncoder) PopDebugGroup() {
+	p.refs.check("RenderPassEncoder.PopDebugGroup", p.label)
 	C.wgpuRenderPassEncoderPopDebugGroup(p.ref)
 }
 
 func (p *RenderPassEncoder) PushDebugGroup(groupLabel string) {
+	p.refs.check("RenderPassEncoder.PushDebugGroup", p.label)
 	groupLabelStr := C.CString(groupLabel)
 	defer C.free(unsafe.Pointer(groupLabelStr))
 
@@ -128,6 +145,8 @@ func (p *RenderPassEncoder) PushDebugGroup(groupLabel string) {
 }
 
 func (p *RenderPassEncoder) SetBindGroup(groupIndex uint32, group *BindGroup, dynamicOffsets []uint32) {
+	p.refs.check("RenderPassEncoder.SetBindGroup", p.label)
+	group.refs.check("RenderPassEncoder.SetBindGroup", group.label)
 	dynamicOffsetCount := len(dynamicOffsets)
 	if dynamicOffsetCount == 0 {
 		C.wgpuRenderPassEncoderSetBindGroup(
@@ -149,6 +168,7 @@ func (p *RenderPassEncoder) SetBindGroup(groupIndex uint32, group *BindGroup, dy
 }
 
 func (p *RenderPassEncoder) SetBlendConstant(color *Color) {
+	p.refs.check("RenderPassEncoder.SetBlendConstant", p.label)
 	c := C.WGPUColor{
 		r: C.double(color.R),
 		g: C.double(color.G),
@@ -159,6 +179,8 @@ func (p *RenderPassEncoder) SetBlendConstant(color *Color) {
 }
 
 func (p *RenderPassEncoder) SetIndexBuffer(buffer *Buffer, format IndexFormat, offset uint64, size uint64) {
+	p.refs.check("RenderPassEncoder.SetIndexBuffer", p.label)
+	buffer.refs.check("RenderPassEncoder.SetIndexBuffer", buffer.label)
 	C.wgpuRenderPassEncoderSetIndexBuffer(
 		p.ref,
 		buffer.ref,
@@ -169,10 +191,13 @@ func (p *RenderPassEncoder) SetIndexBuffer(buffer *Buffer, format IndexFormat, o
 }
 
 func (p *RenderPassEncoder) SetPipeline(pipeline *RenderPipeline) {
+	p.refs.check("RenderPassEncoder.SetPipeline", p.label)
+	pipeline.refs.check("RenderPassEncoder.SetPipeline", pipeline.label)
 	C.wgpuRenderPassEncoderSetPipeline(p.ref, pipeline.ref)
 }
 
 func (p *RenderPassEncoder) SetScissorRect(x, y, width, height uint32) {
+	p.refs.check("RenderPassEncoder.SetScissorRect", p.label)
 	C.wgpuRenderPassEncoderSetScissorRect(
 		p.ref,
 		C.uint32_t(x),
@@ -183,10 +208,13 @@ func (p *RenderPassEncoder) SetScissorRect(x, y, width, height uint32) {
 }
 
 func (p *RenderPassEncoder) SetStencilReference(reference uint32) {
+	p.refs.check("RenderPassEncoder.SetStencilReference", p.label)
 	C.wgpuRenderPassEncoderSetStencilReference(p.ref, C.uint32_t(reference))
 }
 
 func (p *RenderPassEncoder) SetVertexBuffer(slot uint32, buffer *Buffer, offset uint64, size uint64) {
+	p.refs.check("RenderPassEncoder.SetVertexBuffer", p.label)
+	buffer.refs.check("RenderPassEncoder.SetVertexBuffer", buffer.label)
 	C.wgpuRenderPassEncoderSetVertexBuffer(
 		p.ref,
 		C.uint32_t(slot),
@@ -197,6 +225,7 @@ func (p *RenderPassEncoder) SetVertexBuffer(slot uint32, buffer *Buffer, offset
 }
 
 func (p *RenderPassEncoder) SetViewport(x, y, width, height, minDepth, maxDepth float32) {
+	p.refs.check("RenderPassEncoder.SetViewport", p.label)
 	C.wgpuRenderPassEncoderSetViewport(
 		p.ref,
 		C.float(x),
@@ -209,6 +238,7 @@ func (p *RenderPassEncoder) SetViewport(x, y, width, height, minDepth, maxDepth
 }
 
 func (p *RenderPassEncoder) SetPushConstants(stages ShaderStage, offset uint32, data []byte) {
+	p.refs.check("RenderPassEncoder.SetPushConstants", p.label)
 	size := len(data)
 	if size == 0 {
 		C.wgpuRenderPassEncoderSetPushConstants(
@@ -230,7 +260,9 @@ func (p *RenderPassEncoder) SetPushConstants(stages ShaderStage, offset uint32,
 	)
 }
 
-func (p *RenderPassEncoder) MultiDrawIndirect(encoder *RenderPassEncoder, buffer Buffer, offset uint64, count uint32) {
+func (p *RenderPassEncoder) MultiDrawIndirect(encoder *RenderPassEncoder, buffer *Buffer, offset uint64, count uint32) {
+	encoder.refs.check("RenderPassEncoder.MultiDrawIndirect", encoder.label)
+	buffer.refs.check("RenderPassEncoder.MultiDrawIndirect", buffer.label)
 	C.wgpuRenderPassEncoderMultiDrawIndirect(
 		encoder.ref,
 		buffer.ref,
@@ -239,7 +271,9 @@ func (p *RenderPassEncoder) MultiDrawIndirect(encoder *RenderPassEncoder, buffer
 	)
 }
 
-func (p *RenderPassEncoder) MultiDrawIndexedIndirect(encoder *RenderPassEncoder, buffer Buffer, offset uint64, count uint32) {
+func (p *RenderPassEncoder) MultiDrawIndexedIndirect(encoder *RenderPassEncoder, buffer *Buffer, offset uint64, count uint32) {
+	encoder.refs.check("RenderPassEncoder.MultiDrawIndexedIndirect", encoder.label)
+	buffer.refs.check("RenderPassEncoder.MultiDrawIndexedIndirect", buffer.label)
 	C.wgpuRenderPassEncoderMultiDrawIndexedIndirect(
 		encoder.ref,
 		buffer.ref,
@@ -248,7 +282,10 @@ func (p *RenderPassEncoder) MultiDrawIndexedIndirect(encoder *RenderPassEncoder,
 	)
 }
 
-func (p *RenderPassEncoder) MultiDrawIndirectCount(encoder *RenderPassEncoder, buffer Buffer, offset uint64, countBuffer Buffer, countBufferOffset uint64, maxCount uint32) {
+func (p *RenderPassEncoder) MultiDrawIndirectCount(encoder *RenderPassEncoder, buffer *Buffer, offset uint64, countBuffer *Buffer, countBufferOffset uint64, maxCount uint32) {
+	encoder.refs.check("RenderPassEncoder.MultiDrawIndirectCount", encoder.label)
+	buffer.refs.check("RenderPassEncoder.MultiDrawIndirectCount", buffer.label)
+	countBuffer.refs.check("RenderPassEncoder.MultiDrawIndirectCount", countBuffer.label)
 	C.wgpuRenderPassEncoderMultiDrawIndirectCount(
 		encoder.ref,
 		buffer.ref,
@@ -259,7 +296,10 @@ func (p *RenderPassEncoder) MultiDrawIndirectCount(encoder *RenderPassEncoder, b
 	)
 }
 
-func (p *RenderPassEncoder) MultiDrawIndexedIndirectCount(encoder *RenderPassEncoder, buffer Buffer, offset uint64, countBuffer Buffer, countBufferOffset uint64, maxCount uint32) {
+func (p *RenderPassEncoder) MultiDrawIndexedIndirectCount(encoder *RenderPassEncoder, buffer *Buffer, offset uint64, countBuffer *Buffer, countBufferOffset uint64, maxCount uint32) {
+	encoder.refs.check("RenderPassEncoder.MultiDrawIndexedIndirectCount", encoder.label)
+	buffer.refs.check("RenderPassEncoder.MultiDrawIndexedIndirectCount", buffer.label)
+	countBuffer.refs.check("RenderPassEncoder.MultiDrawIndexedIndirectCount", countBuffer.label)
 	C.wgpuRenderPassEncoderMultiDrawIndexedIndirectCount(
 		encoder.ref,
 		buffer.ref,
@@ -271,5 +311,7 @@ func (p *RenderPassEncoder) MultiDrawIndexedIndirectCount(encoder *RenderPassEnc
 }
 
 func (p *RenderPassEncoder) Release() {
-	C.gowebgpu_render_pass_encoder_release(p.ref, p.deviceRef)
+	if p.refs.release("RenderPassEncoder.Release", p.label) {
+		C.gowebgpu_render_pass_encoder_release(p.ref, p.deviceRef)
+	}
 }
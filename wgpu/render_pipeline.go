@@ -11,18 +11,25 @@ package wgpu
 import "C"
 
 type RenderPipeline struct {
-	ref C.WGPURenderPipeline
+	ref   C.WGPURenderPipeline
+	label string
+	refs  refCount
 }
 
 func (p *RenderPipeline) GetBindGroupLayout(groupIndex uint32) *BindGroupLayout {
+	p.refs.check("RenderPipeline.GetBindGroupLayout", p.label)
 	ref := C.wgpuRenderPipelineGetBindGroupLayout(p.ref, C.uint32_t(groupIndex))
 	if ref == nil {
 		panic("Failed to accquire BindGroupLayout")
 	}
 
-	return &BindGroupLayout{ref}
+	layout := &BindGroupLayout{ref: ref}
+	track(layout, "BindGroupLayout", "")
+	return layout
 }
 
 func (p *RenderPipeline) Release() {
-	C.wgpuRenderPipelineRelease(p.ref)
+	if p.refs.release("RenderPipeline.Release", p.label) {
+		C.wgpuRenderPipelineRelease(p.ref)
+	}
 }
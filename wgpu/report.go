@@ -0,0 +1,109 @@
+package wgpu
+
+import "fmt"
+
+type RegistryReport struct {
+	NumAllocated        uint64
+	NumKeptFromUser     uint64
+	NumReleasedFromUser uint64
+	NumError            uint64
+	ElementSize         uint64
+}
+
+type HubReport struct {
+	Adapters         RegistryReport
+	Devices          RegistryReport
+	PipelineLayouts  RegistryReport
+	ShaderModules    RegistryReport
+	BindGroupLayouts RegistryReport
+	BindGroups       RegistryReport
+	CommandBuffers   RegistryReport
+	RenderBundles    RegistryReport
+	RenderPipelines  RegistryReport
+	ComputePipelines RegistryReport
+	QuerySets        RegistryReport
+	Buffers          RegistryReport
+	Textures         RegistryReport
+	TextureViews     RegistryReport
+	Samplers         RegistryReport
+}
+
+type GlobalReport struct {
+	Surfaces RegistryReport
+	Vulkan   *HubReport
+	Metal    *HubReport
+	Dx12     *HubReport
+	Dx11     *HubReport
+	Gl       *HubReport
+}
+
+// RegistryDelta is the change in the counts of a registry between two
+// GlobalReports.
+type RegistryDelta struct {
+	// Registry names the registry, such as "Vulkan.BindGroups" or
+	// "Surfaces".
+	Registry            string
+	NumAllocated        int64
+	NumKeptFromUser     int64
+	NumReleasedFromUser int64
+	NumError            int64
+}
+
+func (d RegistryDelta) String() string {
+	return fmt.Sprintf("%s: allocated %+d, kept %+d, released %+d, error %+d",
+		d.Registry, d.NumAllocated, d.NumKeptFromUser, d.NumReleasedFromUser, d.NumError)
+}
+
+// Diff returns the registries whose counts changed from before to r, in
+// the order of the report fields. A registry growing between snapshots
+// of a steady workload points at a leak, which the leak tracker can
+// attribute; see CheckLeaks.
+func (r GlobalReport) Diff(before GlobalReport) []RegistryDelta {
+	var deltas []RegistryDelta
+	diff := func(name string, before, after RegistryReport) {
+		d := RegistryDelta{
+			Registry:            name,
+			NumAllocated:        int64(after.NumAllocated - before.NumAllocated),
+			NumKeptFromUser:     int64(after.NumKeptFromUser - before.NumKeptFromUser),
+			NumReleasedFromUser: int64(after.NumReleasedFromUser - before.NumReleasedFromUser),
+			NumError:            int64(after.NumError - before.NumError),
+		}
+		if d != (RegistryDelta{Registry: name}) {
+			deltas = append(deltas, d)
+		}
+	}
+	diffHub := func(backend string, before, after *HubReport) {
+		if before == nil && after == nil {
+			return
+		}
+		if before == nil {
+			before = &HubReport{}
+		}
+		if after == nil {
+			after = &HubReport{}
+		}
+		diff(backend+".Adapters", before.Adapters, after.Adapters)
+		diff(backend+".Devices", before.Devices, after.Devices)
+		diff(backend+".PipelineLayouts", before.PipelineLayouts, after.PipelineLayouts)
+		diff(backend+".ShaderModules", before.ShaderModules, after.ShaderModules)
+		diff(backend+".BindGroupLayouts", before.BindGroupLayouts, after.BindGroupLayouts)
+		diff(backend+".BindGroups", before.BindGroups, after.BindGroups)
+		diff(backend+".CommandBuffers", before.CommandBuffers, after.CommandBuffers)
+		diff(backend+".RenderBundles", before.RenderBundles, after.RenderBundles)
+		diff(backend+".RenderPipelines", before.RenderPipelines, after.RenderPipelines)
+		diff(backend+".ComputePipelines", before.ComputePipelines, after.ComputePipelines)
+		diff(backend+".QuerySets", before.QuerySets, after.QuerySets)
+		diff(backend+".Buffers", before.Buffers, after.Buffers)
+		diff(backend+".Textures", before.Textures, after.Textures)
+		diff(backend+".TextureViews", before.TextureViews, after.TextureViews)
+		diff(backend+".Samplers", before.Samplers, after.Samplers)
+	}
+
+	diff("Surfaces", before.Surfaces, r.Surfaces)
+	diffHub("Vulkan", before.Vulkan, r.Vulkan)
+	diffHub("Metal", before.Metal, r.Metal)
+	diffHub("Dx12", before.Dx12, r.Dx12)
+	diffHub("Dx11", before.Dx11, r.Dx11)
+	diffHub("Gl", before.Gl, r.Gl)
+	return deltas
+}
@@ -2,7 +2,13 @@
 
 package wgpu
 
-import "syscall/js"
+import (
+	"strings"
+	"syscall/js"
+	"unicode/utf8"
+
+	"github.com/openfluke/webgpu/jsx"
+)
 
 // ShaderModuleDescriptor as described:
 // https://gpuweb.github.io/gpuweb/#dictdef-gpushadermoduledescriptor
@@ -13,18 +19,82 @@ type ShaderModuleDescriptor struct {
 
 func (g ShaderModuleDescriptor) toJS() any {
 	return map[string]any{
-		"code": g.WGSLDescriptor.Code,
+		"label": g.Label,
+		"code":  g.WGSLDescriptor.Code,
+	}
+}
+
+// hash writes the contents of the descriptor, other than the label, for
+// PipelineCache.
+func (g *ShaderModuleDescriptor) hash(h *cacheHasher) {
+	if g.WGSLDescriptor != nil {
+		h.uint(2)
+		h.string(g.WGSLDescriptor.Code)
 	}
+	h.uint(0)
 }
 
 // ShaderModule as described:
 // https://gpuweb.github.io/gpuweb/#gpushadermodule
 type ShaderModule struct {
 	jsValue js.Value
+
+	// code is the WGSL source, for converting the UTF-16 offsets of
+	// compilation messages to bytes.
+	code string
 }
 
 func (g ShaderModule) toJS() any {
 	return g.jsValue
 }
 
+// GetCompilationInfo as described:
+// https://gpuweb.github.io/gpuweb/#dom-gpushadermodule-getcompilationinfo
+func (g ShaderModule) GetCompilationInfo() (*CompilationInfo, error) {
+	jsInfo, ok := jsx.Await(g.jsValue.Call("getCompilationInfo"))
+	if !ok {
+		return nil, &Error{Op: "ShaderModule.GetCompilationInfo", Type: ErrorTypeUnknown, Message: jsInfo.Get("message").String()}
+	}
+
+	jsMessages := jsInfo.Get("messages")
+	info := &CompilationInfo{Messages: make([]CompilationMessage, jsMessages.Length())}
+	for i := range info.Messages {
+		jsMessage := jsMessages.Index(i)
+		m := CompilationMessage{
+			Message: jsMessage.Get("message").String(),
+			LineNum: uint64(jsMessage.Get("lineNum").Int()),
+		}
+		switch jsMessage.Get("type").String() {
+		case "warning":
+			m.Type = CompilationMessageTypeWarning
+		case "info":
+			m.Type = CompilationMessageTypeInfo
+		}
+		if m.LineNum > 0 {
+			offset := utf16ToByteOffset(g.code, 0, jsMessage.Get("offset").Int())
+			m.Offset = uint64(offset)
+			m.Length = uint64(utf16ToByteOffset(g.code, offset, jsMessage.Get("length").Int()) - offset)
+			m.LinePos = uint64(offset - strings.LastIndexByte(g.code[:offset], '\n'))
+		}
+		info.Messages[i] = m
+	}
+	return info, nil
+}
+
+// utf16ToByteOffset returns the byte offset in s that is n UTF-16 code
+// units after the byte offset start.
+func utf16ToByteOffset(s string, start, n int) int {
+	i := start
+	for n > 0 && i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r >= 0x10000 {
+			n -= 2 // A surrogate pair.
+		} else {
+			n--
+		}
+		i += size
+	}
+	return i
+}
+
 func (g ShaderModule) Release() {} // no-op
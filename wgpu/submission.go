@@ -0,0 +1,103 @@
+package wgpu
+
+import (
+	"context"
+	"sync"
+)
+
+// Submission tracks the command buffers submitted by
+// Queue.SubmitTracked until the GPU completes them.
+type Submission struct {
+	// Index is the index of the submission, or 0 in the browser.
+	Index SubmissionIndex
+
+	done       chan struct{}
+	mu         sync.Mutex
+	completed  bool
+	err        error
+	onComplete []func()
+
+	// poll, if set, polls the device until the submission completes. Wait
+	// runs it once, on its own goroutine.
+	poll     func()
+	pollOnce sync.Once
+}
+
+func newSubmission(index SubmissionIndex) *Submission {
+	return &Submission{Index: index, done: make(chan struct{})}
+}
+
+// complete marks the submission completed with err and runs the
+// OnComplete callbacks. Calls after the first do nothing.
+func (s *Submission) complete(err error) {
+	s.mu.Lock()
+	if s.completed {
+		s.mu.Unlock()
+		return
+	}
+	s.completed = true
+	s.err = err
+	callbacks := s.onComplete
+	s.onComplete = nil
+	close(s.done)
+	s.mu.Unlock()
+
+	for _, f := range callbacks {
+		f()
+	}
+}
+
+// Done returns a channel that is closed when the submission completes.
+// Outside the browser the completion is only noticed by a poll, from
+// Wait, a Poller or Device.Poll.
+func (s *Submission) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait polls the device until the submission completes and returns the
+// error of the submitted work, if any. If ctx is done first, Wait
+// returns ctx.Err(); the submission can still be waited for. The first
+// Wait starts a goroutine that polls until the submission completes or
+// the device is lost, whichever Wait calls are still waiting.
+func (s *Submission) Wait(ctx context.Context) error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+	}
+	if s.poll != nil {
+		s.pollOnce.Do(func() { go s.poll() })
+	}
+	select {
+	case <-s.done:
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// OnComplete calls f when the submission completes, on the goroutine
+// that notices it, or right away if it has completed. Callbacks run in
+// the order they were added.
+func (s *Submission) OnComplete(f func()) {
+	s.mu.Lock()
+	if !s.completed {
+		s.onComplete = append(s.onComplete, f)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	f()
+}
+
+// submissionError returns the error of submitted work that completed
+// with status, or nil on success.
+func submissionError(label string, status QueueWorkDoneStatus) error {
+	switch status {
+	case QueueWorkDoneStatusSuccess:
+		return nil
+	case QueueWorkDoneStatusDeviceLost:
+		return &Error{Op: "Queue.SubmitTracked", Label: label, Type: ErrorTypeDeviceLost, Message: "device lost before the submitted work completed"}
+	}
+	return &Error{Op: "Queue.SubmitTracked", Label: label, Type: ErrorTypeUnknown, Message: "submitted work failed: " + status.String()}
+}
@@ -0,0 +1,50 @@
+package wgpu
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSubmission(t *testing.T) {
+	s := newSubmission(3)
+	var order []int
+	s.OnComplete(func() { order = append(order, 1) })
+	s.OnComplete(func() { order = append(order, 2) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Wait before completion returned %v", err)
+	}
+
+	s.complete(submissionError("queue", QueueWorkDoneStatusDeviceLost))
+	err := s.Wait(context.Background())
+	if !errors.Is(err, ErrDeviceLost) {
+		t.Errorf("Wait returned %v, want a device-lost error", err)
+	}
+	s.complete(nil)
+	if err := s.Wait(context.Background()); !errors.Is(err, ErrDeviceLost) {
+		t.Errorf("second completion replaced the error with %v", err)
+	}
+	s.OnComplete(func() { order = append(order, 3) })
+	if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 3 {
+		t.Errorf("callbacks ran in order %v", order)
+	}
+	select {
+	case <-s.Done():
+	default:
+		t.Error("Done is not closed")
+	}
+}
+
+func TestSubmissionError(t *testing.T) {
+	if err := submissionError("q", QueueWorkDoneStatusSuccess); err != nil {
+		t.Errorf("success returned %v", err)
+	}
+	var wgpuErr *Error
+	if err := submissionError("q", QueueWorkDoneStatusError); !errors.As(err, &wgpuErr) || wgpuErr.Type != ErrorTypeUnknown || wgpuErr.Label != "q" {
+		t.Errorf("error status returned %v", err)
+	}
+}
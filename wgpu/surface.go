@@ -7,20 +7,20 @@ package wgpu
 #include <stdlib.h>
 #include "./lib/wgpu.h"
 
-extern void gowebgpu_error_callback_c(WGPUErrorType type, char const * message, void * userdata);
+extern void gowebgpu_push_error_scopes(WGPUDevice device);
+extern void gowebgpu_pop_error_scopes(WGPUDevice device, void * userdata);
 
 static inline WGPUTexture gowebgpu_surface_get_current_texture(WGPUSurface surface, WGPUDevice device, void * error_userdata) {
 	WGPUSurfaceTexture ref;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	wgpuSurfaceGetCurrentTexture(surface, &ref);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref.texture;
 }
 
 */
 import "C"
 import (
-	"errors"
 	"runtime/cgo"
 	"unsafe"
 )
@@ -28,9 +28,12 @@ import (
 type Surface struct {
 	deviceRef C.WGPUDevice
 	ref       C.WGPUSurface
+	refs      refCount
 }
 
 func (p *Surface) GetCapabilities(adapter *Adapter) (ret SurfaceCapabilities) {
+	p.refs.check("Surface.GetCapabilities", "")
+	adapter.refs.check("Surface.GetCapabilities", "")
 	var caps C.WGPUSurfaceCapabilities
 	C.wgpuSurfaceGetCapabilities(p.ref, adapter.ref, &caps)
 
@@ -72,6 +75,8 @@ func (p *Surface) GetCapabilities(adapter *Adapter) (ret SurfaceCapabilities) {
 }
 
 func (p *Surface) Configure(adapter *Adapter, device *Device, config *SurfaceConfiguration) {
+	p.refs.check("Surface.Configure", "")
+	device.refs.check("Surface.Configure", device.label)
 	p.deviceRef = device.ref
 
 	var cfg *C.WGPUSurfaceConfiguration
@@ -104,10 +109,9 @@ func (p *Surface) Configure(adapter *Adapter, device *Device, config *SurfaceCon
 // NOTE: you should typically not call [Texture.Release] on the returned texture.
 // Instead, you should call [TextureView.Release] on any [TextureView] you create from it.
 func (p *Surface) GetCurrentTexture() (*Texture, error) {
+	p.refs.check("Surface.GetCurrentTexture", "")
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Surface).GetCurrentTexture(): " + message)
-	}
+	cb := newErrorCallback(&err, "Surface.GetCurrentTexture", "")
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -123,13 +127,18 @@ func (p *Surface) GetCurrentTexture() (*Texture, error) {
 		return nil, err
 	}
 
-	return &Texture{p.deviceRef, ref}, nil
+	texture := &Texture{deviceRef: p.deviceRef, ref: ref}
+	track(texture, "Texture", "")
+	return texture, nil
 }
 
 func (p *Surface) Present() {
+	p.refs.check("Surface.Present", "")
 	C.wgpuSurfacePresent(p.ref)
 }
 
 func (p *Surface) Release() {
-	C.wgpuSurfaceRelease(p.ref)
+	if p.refs.release("Surface.Release", "") {
+		C.wgpuSurfaceRelease(p.ref)
+	}
 }
@@ -29,7 +29,7 @@ func (g Surface) Configure(adapter *Adapter, device *Device, config *SurfaceConf
 
 func (g Surface) GetCurrentTexture() (*Texture, error) {
 	texture := g.jsValue.Call("getCurrentTexture")
-	return &Texture{texture}, nil
+	return &Texture{jsValue: texture}, nil
 }
 
 func (g Surface) Present() {} // no-op
@@ -7,13 +7,14 @@ package wgpu
 #include <stdlib.h>
 #include "./lib/wgpu.h"
 
-extern void gowebgpu_error_callback_c(WGPUErrorType type, char const * message, void * userdata);
+extern void gowebgpu_push_error_scopes(WGPUDevice device);
+extern void gowebgpu_pop_error_scopes(WGPUDevice device, void * userdata);
 
 static inline WGPUTextureView gowebgpu_texture_create_view(WGPUTexture texture, WGPUTextureViewDescriptor const * descriptor, WGPUDevice device, void * error_userdata) {
 	WGPUTextureView ref = NULL;
-	wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+	gowebgpu_push_error_scopes(device);
 	ref = wgpuTextureCreateView(texture, descriptor);
-	wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, error_userdata);
+	gowebgpu_pop_error_scopes(device, error_userdata);
 	return ref;
 }
 
@@ -25,7 +26,6 @@ static inline void gowebgpu_texture_release(WGPUTexture texture, WGPUDevice devi
 */
 import "C"
 import (
-	"errors"
 	"runtime/cgo"
 	"unsafe"
 )
@@ -33,9 +33,14 @@ import (
 type Texture struct {
 	deviceRef C.WGPUDevice
 	ref       C.WGPUTexture
+	label     string
+	refs      refCount
+
+	device *Device
 }
 
 func (p *Texture) CreateView(descriptor *TextureViewDescriptor) (*TextureView, error) {
+	p.refs.check("Texture.CreateView", p.label)
 	var desc *C.WGPUTextureViewDescriptor
 
 	if descriptor != nil {
@@ -58,9 +63,11 @@ func (p *Texture) CreateView(descriptor *TextureViewDescriptor) (*TextureView, e
 	}
 
 	var err error = nil
-	var cb errorCallback = func(_ ErrorType, message string) {
-		err = errors.New("wgpu.(*Texture).CreateView(): " + message)
+	var label string
+	if descriptor != nil {
+		label = descriptor.Label
 	}
+	cb := newErrorCallback(&err, "Texture.CreateView", label)
 	errorCallbackHandle := cgo.NewHandle(cb)
 	defer errorCallbackHandle.Delete()
 
@@ -75,45 +82,58 @@ func (p *Texture) CreateView(descriptor *TextureViewDescriptor) (*TextureView, e
 		return nil, err
 	}
 
-	return &TextureView{ref}, nil
+	view := &TextureView{ref: ref, label: label}
+	track(view, "TextureView", label)
+	return view, nil
 }
 
 func (p *Texture) Destroy() {
+	p.refs.check("Texture.Destroy", p.label)
 	C.wgpuTextureDestroy(p.ref)
 }
 
 func (p *Texture) GetDepthOrArrayLayers() uint32 {
+	p.refs.check("Texture.GetDepthOrArrayLayers", p.label)
 	return uint32(C.wgpuTextureGetDepthOrArrayLayers(p.ref))
 }
 
 func (p *Texture) GetDimension() TextureDimension {
+	p.refs.check("Texture.GetDimension", p.label)
 	return TextureDimension(C.wgpuTextureGetDimension(p.ref))
 }
 
 func (p *Texture) GetFormat() TextureFormat {
+	p.refs.check("Texture.GetFormat", p.label)
 	return TextureFormat(C.wgpuTextureGetFormat(p.ref))
 }
 
 func (p *Texture) GetHeight() uint32 {
+	p.refs.check("Texture.GetHeight", p.label)
 	return uint32(C.wgpuTextureGetHeight(p.ref))
 }
 
 func (p *Texture) GetMipLevelCount() uint32 {
+	p.refs.check("Texture.GetMipLevelCount", p.label)
 	return uint32(C.wgpuTextureGetMipLevelCount(p.ref))
 }
 
 func (p *Texture) GetSampleCount() uint32 {
+	p.refs.check("Texture.GetSampleCount", p.label)
 	return uint32(C.wgpuTextureGetSampleCount(p.ref))
 }
 
 func (p *Texture) GetUsage() TextureUsage {
+	p.refs.check("Texture.GetUsage", p.label)
 	return TextureUsage(C.wgpuTextureGetUsage(p.ref))
 }
 
 func (p *Texture) GetWidth() uint32 {
+	p.refs.check("Texture.GetWidth", p.label)
 	return uint32(C.wgpuTextureGetWidth(p.ref))
 }
 
 func (p *Texture) Release() {
-	C.gowebgpu_texture_release(p.ref, p.deviceRef)
+	if p.refs.release("Texture.Release", p.label) {
+		C.gowebgpu_texture_release(p.ref, p.deviceRef)
+	}
 }
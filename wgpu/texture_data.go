@@ -0,0 +1,146 @@
+package wgpu
+
+import (
+	"bytes"
+	"io"
+	"math/bits"
+	"strconv"
+)
+
+// TextureData is a texture read from a container file by ParseKTX2 or
+// ParseDDS, ready for CreateTextureFromData.
+type TextureData struct {
+	Format    TextureFormat
+	Dimension TextureDimension
+	// Size is the size of the first mip level. For 2D textures,
+	// DepthOrArrayLayers is the number of array layers, six per cube.
+	Size          Extent3D
+	MipLevelCount uint32
+	// Cube reports whether the layers are the faces of cube maps, in
+	// the order +X, -X, +Y, -Y, +Z, -Z.
+	Cube bool
+	// Levels holds the texels of each mip level, whole blocks in rows
+	// without padding, one array layer or depth slice after the other.
+	Levels [][]byte
+}
+
+// FeatureSet is the set of features of an adapter or device.
+type FeatureSet interface {
+	HasFeature(feature FeatureName) bool
+}
+
+// SelectTextureFormat returns the first of formats that features
+// supports, for choosing between assets compressed for several
+// platforms. It returns false if features supports none.
+func SelectTextureFormat(features FeatureSet, formats ...TextureFormat) (TextureFormat, bool) {
+	for _, format := range formats {
+		if f := format.RequiredFeature(TextureUsageNone); f == FeatureNameUndefined || features.HasFeature(f) {
+			return format, true
+		}
+	}
+	return TextureFormatUndefined, false
+}
+
+// ReadTextureData reads a KTX2 or DDS file, as ParseKTX2 or ParseDDS.
+func ReadTextureData(r io.Reader) (*TextureData, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(data, ktx2Identifier[:]):
+		return ParseKTX2(data)
+	case bytes.HasPrefix(data, []byte(ddsMagic)):
+		return ParseDDS(data)
+	}
+	return nil, &Error{Op: "ReadTextureData", Type: ErrorTypeValidation, Message: "unknown texture container"}
+}
+
+// checkHeader checks the size and mip level count read from a file,
+// before anything is sized from them: the texture must not be empty,
+// and its mip levels must halve down to no less than one texel.
+func (d *TextureData) checkHeader(op string) error {
+	if d.Size.Width == 0 {
+		return &Error{Op: op, Type: ErrorTypeValidation, Message: "texture has no width"}
+	}
+	largest := d.Size.Width
+	if d.Dimension != TextureDimension1D {
+		largest = max(largest, d.Size.Height)
+	}
+	if d.Dimension == TextureDimension3D {
+		largest = max(largest, d.Size.DepthOrArrayLayers)
+	}
+	if d.MipLevelCount > uint32(bits.Len32(largest)) {
+		return &Error{Op: op, Type: ErrorTypeValidation, Message: "invalid mip level count " + strconv.FormatUint(uint64(d.MipLevelCount), 10)}
+	}
+	return nil
+}
+
+// levelLayout returns the layout of a mip level of the texture.
+func (d *TextureData) levelLayout(level uint32) (CopyLayout, error) {
+	return TextureCopyLayout(d.Format, d.Dimension, d.Size, level, TextureAspectAll)
+}
+
+// checkLevels checks that Levels holds MipLevelCount levels of the
+// right size.
+func (d *TextureData) checkLevels(op string) error {
+	if uint32(len(d.Levels)) != d.MipLevelCount {
+		return &Error{Op: op, Type: ErrorTypeValidation, Message: strconv.Itoa(len(d.Levels)) + " levels, want " + strconv.FormatUint(uint64(d.MipLevelCount), 10)}
+	}
+	for level, data := range d.Levels {
+		layout, err := d.levelLayout(uint32(level))
+		if err != nil {
+			return err
+		}
+		if uint64(len(data)) != layout.TightSize() {
+			return &Error{Op: op, Type: ErrorTypeValidation, Message: "level " + strconv.Itoa(level) + " has " + strconv.Itoa(len(data)) + " bytes, want " + strconv.FormatUint(layout.TightSize(), 10)}
+		}
+	}
+	return nil
+}
+
+// CreateTextureFromData creates a texture for data and writes every mip
+// level and array layer of it. The texture has usage and
+// TextureUsageCopyDst. The device must have the feature the format
+// requires, see SelectTextureFormat.
+func (p *Device) CreateTextureFromData(data *TextureData, label string, usage TextureUsage) (*Texture, error) {
+	usage |= TextureUsageCopyDst
+	if f := data.Format.RequiredFeature(usage); f != FeatureNameUndefined && !p.HasFeature(f) {
+		return nil, &Error{Op: "Device.CreateTextureFromData", Label: label, Type: ErrorTypeValidation, Message: "format " + data.Format.String() + " needs feature " + f.String()}
+	}
+	if err := data.checkLevels("Device.CreateTextureFromData"); err != nil {
+		return nil, err
+	}
+
+	texture, err := p.CreateTexture(&TextureDescriptor{
+		Label:         label,
+		Usage:         usage,
+		Dimension:     data.Dimension,
+		Size:          data.Size,
+		Format:        data.Format,
+		MipLevelCount: data.MipLevelCount,
+		SampleCount:   1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	queue := p.GetQueue()
+	defer queue.Release()
+	for level, pixels := range data.Levels {
+		layout, err := data.levelLayout(uint32(level))
+		if err == nil {
+			err = queue.WriteTexture(
+				&ImageCopyTexture{Texture: texture, MipLevel: uint32(level), Aspect: TextureAspectAll},
+				pixels,
+				&TextureDataLayout{BytesPerRow: layout.RowBytes, RowsPerImage: layout.Rows},
+				&layout.Extent,
+			)
+		}
+		if err != nil {
+			texture.Release()
+			return nil, err
+		}
+	}
+	return texture, nil
+}
@@ -0,0 +1,347 @@
+package wgpu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// ddsFile builds a DDS file. fourCC is either a four character code or
+// "" for uncompressed RGBA8; dx10 holds the DX10 header fields after
+// the format, if the fourCC is "DX10".
+type ddsFile struct {
+	width, height, depth, levels, caps2 uint32
+	fourCC                              string
+	dx10                                []uint32
+	dataSize                            int
+}
+
+func (f ddsFile) bytes() []byte {
+	b := make([]byte, ddsHeaderSize)
+	copy(b, ddsMagic)
+	put := func(offset int, v uint32) { binary.LittleEndian.PutUint32(b[offset:], v) }
+	put(4, 124)
+	flags := uint32(0)
+	if f.levels != 0 {
+		flags |= ddsdMipMapCount
+	}
+	put(8, flags)
+	put(12, f.height)
+	put(16, f.width)
+	put(24, f.depth)
+	put(28, f.levels)
+	put(ddsPixelFormat, 32)
+	if f.fourCC == "" {
+		put(ddsPixelFormat+4, ddpfRGB|ddpfAlphaPixels)
+		put(ddsPixelFormat+12, 32)
+		put(ddsPixelFormat+16, 0xff)
+		put(ddsPixelFormat+20, 0xff00)
+		put(ddsPixelFormat+24, 0xff0000)
+		put(ddsPixelFormat+28, 0xff000000)
+	} else {
+		put(ddsPixelFormat+4, ddpfFourCC)
+		copy(b[ddsFourCC:], f.fourCC)
+	}
+	put(4+108, f.caps2)
+	for _, v := range f.dx10 {
+		b = binary.LittleEndian.AppendUint32(b, v)
+	}
+	data := make([]byte, f.dataSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return append(b, data...)
+}
+
+// ktx2File builds a KTX2 file whose levels have the given sizes, or
+// only the given level index if levelSizes is nil.
+type ktx2File struct {
+	vkFormat, width, height, depth, layers, faces, levels uint32
+	levelSizes                                            []int
+	index                                                 [][2]uint64
+}
+
+func (f ktx2File) bytes() []byte {
+	const headerSize = 80
+	b := make([]byte, headerSize)
+	copy(b, ktx2Identifier[:])
+	put := func(offset int, v uint32) { binary.LittleEndian.PutUint32(b[offset:], v) }
+	put(12, f.vkFormat)
+	put(20, f.width)
+	put(24, f.height)
+	put(28, f.depth)
+	put(32, f.layers)
+	put(36, f.faces)
+	put(40, f.levels)
+
+	index := f.index
+	offset := uint64(headerSize + 24*len(f.levelSizes))
+	for _, size := range f.levelSizes {
+		index = append(index, [2]uint64{offset, uint64(size)})
+		offset += uint64(size)
+	}
+	for _, entry := range index {
+		b = binary.LittleEndian.AppendUint64(b, entry[0])
+		b = binary.LittleEndian.AppendUint64(b, entry[1])
+		b = binary.LittleEndian.AppendUint64(b, entry[1])
+	}
+	for level, size := range f.levelSizes {
+		b = append(b, bytes.Repeat([]byte{byte(level)}, size)...)
+	}
+	return b
+}
+
+func TestParseDDS(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       ddsFile
+		format     TextureFormat
+		dimension  TextureDimension
+		size       Extent3D
+		cube       bool
+		levelSizes []int
+	}{
+		{
+			name:       "rgba8 with mips",
+			file:       ddsFile{width: 4, height: 2, levels: 3, dataSize: 32 + 8 + 4},
+			format:     TextureFormatRGBA8Unorm,
+			dimension:  TextureDimension2D,
+			size:       Extent3D{Width: 4, Height: 2, DepthOrArrayLayers: 1},
+			levelSizes: []int{32, 8, 4},
+		},
+		{
+			name:       "dxt1 without mip count",
+			file:       ddsFile{width: 8, height: 8, fourCC: "DXT1", dataSize: 32},
+			format:     TextureFormatBC1RGBAUnorm,
+			dimension:  TextureDimension2D,
+			size:       Extent3D{Width: 8, Height: 8, DepthOrArrayLayers: 1},
+			levelSizes: []int{32},
+		},
+		{
+			name:       "legacy cube map",
+			file:       ddsFile{width: 1, height: 1, caps2: ddsCaps2Cubemap | ddsCaps2Faces, dataSize: 6 * 4},
+			format:     TextureFormatRGBA8Unorm,
+			dimension:  TextureDimension2D,
+			size:       Extent3D{Width: 1, Height: 1, DepthOrArrayLayers: 6},
+			cube:       true,
+			levelSizes: []int{24},
+		},
+		{
+			name:       "volume",
+			file:       ddsFile{width: 2, height: 2, depth: 4, levels: 3, caps2: ddsCaps2Volume, dataSize: 64 + 8 + 4},
+			format:     TextureFormatRGBA8Unorm,
+			dimension:  TextureDimension3D,
+			size:       Extent3D{Width: 2, Height: 2, DepthOrArrayLayers: 4},
+			levelSizes: []int{64, 8, 4},
+		},
+		{
+			name:       "DX10 BC7 array",
+			file:       ddsFile{width: 4, height: 4, levels: 1, fourCC: "DX10", dx10: []uint32{98, 3, 0, 2, 0}, dataSize: 32},
+			format:     TextureFormatBC7RGBAUnorm,
+			dimension:  TextureDimension2D,
+			size:       Extent3D{Width: 4, Height: 4, DepthOrArrayLayers: 2},
+			levelSizes: []int{32},
+		},
+		{
+			name:       "DX10 1D",
+			file:       ddsFile{width: 8, height: 1, levels: 4, fourCC: "DX10", dx10: []uint32{61, ddsDimension1D, 0, 1, 0}, dataSize: 8 + 4 + 2 + 1},
+			format:     TextureFormatR8Unorm,
+			dimension:  TextureDimension1D,
+			size:       Extent3D{Width: 8, Height: 1, DepthOrArrayLayers: 1},
+			levelSizes: []int{8, 4, 2, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ParseDDS(tt.file.bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Format != tt.format || d.Dimension != tt.dimension || d.Size != tt.size || d.Cube != tt.cube {
+				t.Errorf("got %v %v %+v cube %v", d.Format, d.Dimension, d.Size, d.Cube)
+			}
+			var sizes []int
+			for _, level := range d.Levels {
+				sizes = append(sizes, len(level))
+			}
+			if d.MipLevelCount != uint32(len(tt.levelSizes)) || !equalInts(sizes, tt.levelSizes) {
+				t.Errorf("got %d levels of %v bytes, want %v", d.MipLevelCount, sizes, tt.levelSizes)
+			}
+			if err := d.checkLevels("test"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestParseDDSLayerOrder(t *testing.T) {
+	// Two layers of two levels of R8: DDS stores layer 0 levels 0 and 1,
+	// then layer 1.
+	f := ddsFile{width: 2, height: 1, levels: 2, fourCC: "DX10", dx10: []uint32{61, 3, 0, 2, 0}, dataSize: 6}
+	d, err := ParseDDS(f.bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(d.Levels[0], []byte{0, 1, 3, 4}) || !bytes.Equal(d.Levels[1], []byte{2, 5}) {
+		t.Errorf("got levels %v", d.Levels)
+	}
+}
+
+func TestParseDDSErrors(t *testing.T) {
+	hostileLevels := ddsFile{width: 4, height: 4, levels: 0x0FFFFFFF}.bytes()
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", append([]byte("DDX "), make([]byte, 124)...)},
+		{"truncated header", ddsFile{width: 4, height: 4}.bytes()[:100]},
+		{"truncated DX10 header", ddsFile{width: 4, height: 4, fourCC: "DX10"}.bytes()},
+		{"truncated data", ddsFile{width: 4, height: 4, dataSize: 63}.bytes()},
+		{"truncated mip tail", ddsFile{width: 4, height: 4, levels: 3, dataSize: 64 + 16 + 3}.bytes()},
+		{"unknown fourCC", ddsFile{width: 4, height: 4, fourCC: "XYZW", dataSize: 64}.bytes()},
+		{"unknown DXGI format", ddsFile{width: 4, height: 4, fourCC: "DX10", dx10: []uint32{1000, 3, 0, 1, 0}, dataSize: 64}.bytes()},
+		{"cube map lacking faces", ddsFile{width: 1, height: 1, caps2: ddsCaps2Cubemap, dataSize: 24}.bytes()},
+		{"hostile mip count", hostileLevels},
+		{"mip count past 1x1", ddsFile{width: 4, height: 4, levels: 4, dataSize: 1024}.bytes()},
+		{"hostile size", ddsFile{width: 0x7fffffff, height: 0x7fffffff, fourCC: "DXT1", dataSize: 64}.bytes()},
+		{"hostile array size", ddsFile{width: 1, height: 1, fourCC: "DX10", dx10: []uint32{28, 3, ddsMiscCube, 0x2aaaaaab, 0}, dataSize: 64}.bytes()},
+		{"zero width", ddsFile{width: 0, height: 4, dataSize: 64}.bytes()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ParseDDS(tt.data)
+			var wgpuErr *Error
+			if !errors.As(err, &wgpuErr) || wgpuErr.Type != ErrorTypeValidation {
+				t.Fatalf("got %v, %v, want a validation *Error", d, err)
+			}
+		})
+	}
+}
+
+func TestParseKTX2(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       ktx2File
+		format     TextureFormat
+		dimension  TextureDimension
+		size       Extent3D
+		cube       bool
+		levelSizes []int
+	}{
+		{
+			name:       "rgba8 with mips",
+			file:       ktx2File{vkFormat: 37, width: 4, height: 4, faces: 1, levels: 3, levelSizes: []int{64, 16, 4}},
+			format:     TextureFormatRGBA8Unorm,
+			dimension:  TextureDimension2D,
+			size:       Extent3D{Width: 4, Height: 4, DepthOrArrayLayers: 1},
+			levelSizes: []int{64, 16, 4},
+		},
+		{
+			name:       "bc7 cube",
+			file:       ktx2File{vkFormat: 145, width: 4, height: 4, faces: 6, levelSizes: []int{6 * 16}},
+			format:     TextureFormatBC7RGBAUnorm,
+			dimension:  TextureDimension2D,
+			size:       Extent3D{Width: 4, Height: 4, DepthOrArrayLayers: 6},
+			cube:       true,
+			levelSizes: []int{96},
+		},
+		{
+			name:       "3D",
+			file:       ktx2File{vkFormat: 9, width: 2, height: 2, depth: 2, faces: 1, levels: 2, levelSizes: []int{8, 1}},
+			format:     TextureFormatR8Unorm,
+			dimension:  TextureDimension3D,
+			size:       Extent3D{Width: 2, Height: 2, DepthOrArrayLayers: 2},
+			levelSizes: []int{8, 1},
+		},
+		{
+			name:       "1D array",
+			file:       ktx2File{vkFormat: 9, width: 4, layers: 3, faces: 1, levelSizes: []int{12}},
+			format:     TextureFormatR8Unorm,
+			dimension:  TextureDimension1D,
+			size:       Extent3D{Width: 4, Height: 1, DepthOrArrayLayers: 3},
+			levelSizes: []int{12},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ParseKTX2(tt.file.bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Format != tt.format || d.Dimension != tt.dimension || d.Size != tt.size || d.Cube != tt.cube {
+				t.Errorf("got %v %v %+v cube %v", d.Format, d.Dimension, d.Size, d.Cube)
+			}
+			var sizes []int
+			for _, level := range d.Levels {
+				sizes = append(sizes, len(level))
+			}
+			if !equalInts(sizes, tt.levelSizes) {
+				t.Errorf("got levels of %v bytes, want %v", sizes, tt.levelSizes)
+			}
+		})
+	}
+}
+
+func TestParseKTX2Errors(t *testing.T) {
+	valid := ktx2File{vkFormat: 37, width: 4, height: 4, faces: 1, levels: 1, levelSizes: []int{64}}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad identifier", append([]byte("KTX 11"), make([]byte, 80)...)},
+		{"truncated header", valid.bytes()[:60]},
+		{"truncated level index", ktx2File{vkFormat: 37, width: 4, height: 4, faces: 1, levels: 3}.bytes()},
+		{"level out of bounds", ktx2File{vkFormat: 37, width: 4, height: 4, faces: 1, levels: 1, index: [][2]uint64{{80, 1 << 62}}}.bytes()},
+		{"level offset past the end", ktx2File{vkFormat: 37, width: 4, height: 4, faces: 1, levels: 1, index: [][2]uint64{{1 << 63, 64}}}.bytes()},
+		{"level of the wrong size", ktx2File{vkFormat: 37, width: 4, height: 4, faces: 1, levelSizes: []int{63}}.bytes()},
+		{"unknown format", ktx2File{vkFormat: 1, width: 4, height: 4, faces: 1, levelSizes: []int{64}}.bytes()},
+		{"supercompressed", func() []byte {
+			b := valid.bytes()
+			binary.LittleEndian.PutUint32(b[44:], 1)
+			return b
+		}()},
+		{"bad face count", ktx2File{vkFormat: 37, width: 4, height: 4, faces: 2, levelSizes: []int{64}}.bytes()},
+		{"3D with faces", ktx2File{vkFormat: 37, width: 4, height: 4, depth: 4, faces: 6, levelSizes: []int{64}}.bytes()},
+		{"hostile mip count", ktx2File{vkFormat: 37, width: 4, height: 4, faces: 1, levels: 0x0FFFFFFF}.bytes()},
+		{"mip count past 1x1", ktx2File{vkFormat: 37, width: 4, height: 4, faces: 1, levels: 4, levelSizes: []int{64, 16, 4, 4}}.bytes()},
+		{"hostile layer count", ktx2File{vkFormat: 37, width: 1, height: 1, layers: 0x2aaaaaab, faces: 6, levelSizes: []int{4}}.bytes()},
+		{"zero width", ktx2File{vkFormat: 37, height: 4, faces: 1, levelSizes: []int{16}}.bytes()},
+		{"hostile size", ktx2File{vkFormat: 133, width: 0x7fffffff, height: 0x7fffffff, faces: 1, levelSizes: []int{64}}.bytes()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ParseKTX2(tt.data)
+			var wgpuErr *Error
+			if !errors.As(err, &wgpuErr) || wgpuErr.Type != ErrorTypeValidation {
+				t.Fatalf("got %v, %v, want a validation *Error", d, err)
+			}
+		})
+	}
+}
+
+func TestReadTextureData(t *testing.T) {
+	if _, err := ReadTextureData(bytes.NewReader(ddsFile{width: 1, height: 1, dataSize: 4}.bytes())); err != nil {
+		t.Error(err)
+	}
+	if _, err := ReadTextureData(bytes.NewReader(ktx2File{vkFormat: 37, width: 1, height: 1, faces: 1, levelSizes: []int{4}}.bytes())); err != nil {
+		t.Error(err)
+	}
+	if _, err := ReadTextureData(bytes.NewReader([]byte("PNG"))); err == nil {
+		t.Error("unknown container accepted")
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
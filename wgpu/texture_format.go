@@ -0,0 +1,293 @@
+package wgpu
+
+// formatKind is how the texels of a format are encoded.
+type formatKind uint8
+
+const (
+	formatUnorm formatKind = iota + 1
+	formatSnorm
+	formatUint
+	formatSint
+	formatFloat
+	formatFloat32 // 32-bit floats, filterable only with FeatureNameFloat32Filterable
+	formatDepth
+	formatStencil
+	formatDepthStencil
+)
+
+// formatInfo describes a texture format. size is the number of bytes
+// per block, 0 for depth formats whose layout is opaque.
+type formatInfo struct {
+	blockWidth, blockHeight uint8
+	size                    uint8
+	components              uint8
+	kind                    formatKind
+	feature                 FeatureName
+	srgb                    bool
+}
+
+var textureFormatInfos = [...]formatInfo{
+	TextureFormatR8Unorm:              {1, 1, 1, 1, formatUnorm, FeatureNameUndefined, false},
+	TextureFormatR8Snorm:              {1, 1, 1, 1, formatSnorm, FeatureNameUndefined, false},
+	TextureFormatR8Uint:               {1, 1, 1, 1, formatUint, FeatureNameUndefined, false},
+	TextureFormatR8Sint:               {1, 1, 1, 1, formatSint, FeatureNameUndefined, false},
+	TextureFormatR16Uint:              {1, 1, 2, 1, formatUint, FeatureNameUndefined, false},
+	TextureFormatR16Sint:              {1, 1, 2, 1, formatSint, FeatureNameUndefined, false},
+	TextureFormatR16Float:             {1, 1, 2, 1, formatFloat, FeatureNameUndefined, false},
+	TextureFormatRG8Unorm:             {1, 1, 2, 2, formatUnorm, FeatureNameUndefined, false},
+	TextureFormatRG8Snorm:             {1, 1, 2, 2, formatSnorm, FeatureNameUndefined, false},
+	TextureFormatRG8Uint:              {1, 1, 2, 2, formatUint, FeatureNameUndefined, false},
+	TextureFormatRG8Sint:              {1, 1, 2, 2, formatSint, FeatureNameUndefined, false},
+	TextureFormatR32Float:             {1, 1, 4, 1, formatFloat32, FeatureNameUndefined, false},
+	TextureFormatR32Uint:              {1, 1, 4, 1, formatUint, FeatureNameUndefined, false},
+	TextureFormatR32Sint:              {1, 1, 4, 1, formatSint, FeatureNameUndefined, false},
+	TextureFormatRG16Uint:             {1, 1, 4, 2, formatUint, FeatureNameUndefined, false},
+	TextureFormatRG16Sint:             {1, 1, 4, 2, formatSint, FeatureNameUndefined, false},
+	TextureFormatRG16Float:            {1, 1, 4, 2, formatFloat, FeatureNameUndefined, false},
+	TextureFormatRGBA8Unorm:           {1, 1, 4, 4, formatUnorm, FeatureNameUndefined, false},
+	TextureFormatRGBA8UnormSrgb:       {1, 1, 4, 4, formatUnorm, FeatureNameUndefined, true},
+	TextureFormatRGBA8Snorm:           {1, 1, 4, 4, formatSnorm, FeatureNameUndefined, false},
+	TextureFormatRGBA8Uint:            {1, 1, 4, 4, formatUint, FeatureNameUndefined, false},
+	TextureFormatRGBA8Sint:            {1, 1, 4, 4, formatSint, FeatureNameUndefined, false},
+	TextureFormatBGRA8Unorm:           {1, 1, 4, 4, formatUnorm, FeatureNameUndefined, false},
+	TextureFormatBGRA8UnormSrgb:       {1, 1, 4, 4, formatUnorm, FeatureNameUndefined, true},
+	TextureFormatRGB10A2Uint:          {1, 1, 4, 4, formatUint, FeatureNameUndefined, false},
+	TextureFormatRGB10A2Unorm:         {1, 1, 4, 4, formatUnorm, FeatureNameUndefined, false},
+	TextureFormatRG11B10Ufloat:        {1, 1, 4, 3, formatFloat, FeatureNameUndefined, false},
+	TextureFormatRGB9E5Ufloat:         {1, 1, 4, 3, formatFloat, FeatureNameUndefined, false},
+	TextureFormatRG32Float:            {1, 1, 8, 2, formatFloat32, FeatureNameUndefined, false},
+	TextureFormatRG32Uint:             {1, 1, 8, 2, formatUint, FeatureNameUndefined, false},
+	TextureFormatRG32Sint:             {1, 1, 8, 2, formatSint, FeatureNameUndefined, false},
+	TextureFormatRGBA16Uint:           {1, 1, 8, 4, formatUint, FeatureNameUndefined, false},
+	TextureFormatRGBA16Sint:           {1, 1, 8, 4, formatSint, FeatureNameUndefined, false},
+	TextureFormatRGBA16Float:          {1, 1, 8, 4, formatFloat, FeatureNameUndefined, false},
+	TextureFormatRGBA32Float:          {1, 1, 16, 4, formatFloat32, FeatureNameUndefined, false},
+	TextureFormatRGBA32Uint:           {1, 1, 16, 4, formatUint, FeatureNameUndefined, false},
+	TextureFormatRGBA32Sint:           {1, 1, 16, 4, formatSint, FeatureNameUndefined, false},
+	TextureFormatStencil8:             {1, 1, 1, 1, formatStencil, FeatureNameUndefined, false},
+	TextureFormatDepth16Unorm:         {1, 1, 2, 1, formatDepth, FeatureNameUndefined, false},
+	TextureFormatDepth24Plus:          {1, 1, 0, 1, formatDepth, FeatureNameUndefined, false},
+	TextureFormatDepth24PlusStencil8:  {1, 1, 0, 2, formatDepthStencil, FeatureNameUndefined, false},
+	TextureFormatDepth32Float:         {1, 1, 4, 1, formatDepth, FeatureNameUndefined, false},
+	TextureFormatDepth32FloatStencil8: {1, 1, 0, 2, formatDepthStencil, FeatureNameDepth32FloatStencil8, false},
+	TextureFormatBC1RGBAUnorm:         {4, 4, 8, 4, formatUnorm, FeatureNameTextureCompressionBC, false},
+	TextureFormatBC1RGBAUnormSrgb:     {4, 4, 8, 4, formatUnorm, FeatureNameTextureCompressionBC, true},
+	TextureFormatBC2RGBAUnorm:         {4, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionBC, false},
+	TextureFormatBC2RGBAUnormSrgb:     {4, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionBC, true},
+	TextureFormatBC3RGBAUnorm:         {4, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionBC, false},
+	TextureFormatBC3RGBAUnormSrgb:     {4, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionBC, true},
+	TextureFormatBC4RUnorm:            {4, 4, 8, 1, formatUnorm, FeatureNameTextureCompressionBC, false},
+	TextureFormatBC4RSnorm:            {4, 4, 8, 1, formatSnorm, FeatureNameTextureCompressionBC, false},
+	TextureFormatBC5RGUnorm:           {4, 4, 16, 2, formatUnorm, FeatureNameTextureCompressionBC, false},
+	TextureFormatBC5RGSnorm:           {4, 4, 16, 2, formatSnorm, FeatureNameTextureCompressionBC, false},
+	TextureFormatBC6HRGBUfloat:        {4, 4, 16, 3, formatFloat, FeatureNameTextureCompressionBC, false},
+	TextureFormatBC6HRGBFloat:         {4, 4, 16, 3, formatFloat, FeatureNameTextureCompressionBC, false},
+	TextureFormatBC7RGBAUnorm:         {4, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionBC, false},
+	TextureFormatBC7RGBAUnormSrgb:     {4, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionBC, true},
+	TextureFormatETC2RGB8Unorm:        {4, 4, 8, 3, formatUnorm, FeatureNameTextureCompressionETC2, false},
+	TextureFormatETC2RGB8UnormSrgb:    {4, 4, 8, 3, formatUnorm, FeatureNameTextureCompressionETC2, true},
+	TextureFormatETC2RGB8A1Unorm:      {4, 4, 8, 4, formatUnorm, FeatureNameTextureCompressionETC2, false},
+	TextureFormatETC2RGB8A1UnormSrgb:  {4, 4, 8, 4, formatUnorm, FeatureNameTextureCompressionETC2, true},
+	TextureFormatETC2RGBA8Unorm:       {4, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionETC2, false},
+	TextureFormatETC2RGBA8UnormSrgb:   {4, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionETC2, true},
+	TextureFormatEACR11Unorm:          {4, 4, 8, 1, formatUnorm, FeatureNameTextureCompressionETC2, false},
+	TextureFormatEACR11Snorm:          {4, 4, 8, 1, formatSnorm, FeatureNameTextureCompressionETC2, false},
+	TextureFormatEACRG11Unorm:         {4, 4, 16, 2, formatUnorm, FeatureNameTextureCompressionETC2, false},
+	TextureFormatEACRG11Snorm:         {4, 4, 16, 2, formatSnorm, FeatureNameTextureCompressionETC2, false},
+	TextureFormatASTC4x4Unorm:         {4, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC4x4UnormSrgb:     {4, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC5x4Unorm:         {5, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC5x4UnormSrgb:     {5, 4, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC5x5Unorm:         {5, 5, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC5x5UnormSrgb:     {5, 5, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC6x5Unorm:         {6, 5, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC6x5UnormSrgb:     {6, 5, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC6x6Unorm:         {6, 6, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC6x6UnormSrgb:     {6, 6, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC8x5Unorm:         {8, 5, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC8x5UnormSrgb:     {8, 5, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC8x6Unorm:         {8, 6, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC8x6UnormSrgb:     {8, 6, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC8x8Unorm:         {8, 8, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC8x8UnormSrgb:     {8, 8, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC10x5Unorm:        {10, 5, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC10x5UnormSrgb:    {10, 5, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC10x6Unorm:        {10, 6, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC10x6UnormSrgb:    {10, 6, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC10x8Unorm:        {10, 8, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC10x8UnormSrgb:    {10, 8, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC10x10Unorm:       {10, 10, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC10x10UnormSrgb:   {10, 10, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC12x10Unorm:       {12, 10, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC12x10UnormSrgb:   {12, 10, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+	TextureFormatASTC12x12Unorm:       {12, 12, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, false},
+	TextureFormatASTC12x12UnormSrgb:   {12, 12, 16, 4, formatUnorm, FeatureNameTextureCompressionASTC, true},
+}
+
+func (v TextureFormat) info() formatInfo {
+	if int(v) < len(textureFormatInfos) {
+		return textureFormatInfos[v]
+	}
+	return formatInfo{}
+}
+
+// BlockDimensions returns the width and height in texels of a block of
+// the format: 1×1 for uncompressed formats.
+func (v TextureFormat) BlockDimensions() (width, height uint32) {
+	info := v.info()
+	return uint32(info.blockWidth), uint32(info.blockHeight)
+}
+
+// BlockSize returns the number of bytes per block, the same as
+// BlockCopySize(TextureAspectAll).
+func (v TextureFormat) BlockSize() uint32 {
+	return v.BlockCopySize(TextureAspectAll)
+}
+
+// BlockCopySize returns the number of bytes per block of the given
+// aspect in buffer copies, or 0 if the aspect cannot be copied: it is
+// not part of the format, the format has several aspects and
+// TextureAspectAll is given, or the layout is opaque as for
+// TextureFormatDepth24Plus.
+func (v TextureFormat) BlockCopySize(aspect TextureAspect) uint32 {
+	info := v.info()
+	switch v {
+	case TextureFormatDepth24PlusStencil8, TextureFormatDepth32FloatStencil8:
+		switch {
+		case aspect == TextureAspectStencilOnly:
+			return 1
+		case aspect == TextureAspectDepthOnly && v == TextureFormatDepth32FloatStencil8:
+			return 4
+		}
+		return 0
+	}
+	switch aspect {
+	case TextureAspectDepthOnly:
+		if info.kind != formatDepth {
+			return 0
+		}
+	case TextureAspectStencilOnly:
+		if info.kind != formatStencil {
+			return 0
+		}
+	}
+	return uint32(info.size)
+}
+
+// Components returns the number of components of the format, such as 4
+// for RGBA formats and 2 for depth-stencil formats.
+func (v TextureFormat) Components() uint32 {
+	return uint32(v.info().components)
+}
+
+// HasColorAspect reports whether the format is a color format.
+func (v TextureFormat) HasColorAspect() bool {
+	switch v.info().kind {
+	case 0, formatDepth, formatStencil, formatDepthStencil:
+		return false
+	}
+	return true
+}
+
+// HasDepthAspect reports whether the format has a depth aspect.
+func (v TextureFormat) HasDepthAspect() bool {
+	kind := v.info().kind
+	return kind == formatDepth || kind == formatDepthStencil
+}
+
+// HasStencilAspect reports whether the format has a stencil aspect.
+func (v TextureFormat) HasStencilAspect() bool {
+	kind := v.info().kind
+	return kind == formatStencil || kind == formatDepthStencil
+}
+
+// IsSrgb reports whether the format stores sRGB-encoded colors.
+func (v TextureFormat) IsSrgb() bool {
+	return v.info().srgb
+}
+
+// IsCompressed reports whether the format is block-compressed.
+func (v TextureFormat) IsCompressed() bool {
+	return v.info().blockWidth > 1
+}
+
+// IsInteger reports whether the format stores unnormalized integers,
+// which shaders read as i32 or u32.
+func (v TextureFormat) IsInteger() bool {
+	kind := v.info().kind
+	return kind == formatUint || kind == formatSint
+}
+
+// IsFloat reports whether the format stores floating-point values, as
+// opposed to normalized or unnormalized integers.
+func (v TextureFormat) IsFloat() bool {
+	kind := v.info().kind
+	return kind == formatFloat || kind == formatFloat32
+}
+
+// RequiredFeature returns the feature a device needs to create
+// textures of the format with the given usage, or FeatureNameUndefined
+// if it needs none. With TextureUsageNone, only the features needed to
+// create the texture at all are considered.
+func (v TextureFormat) RequiredFeature(usage TextureUsage) FeatureName {
+	if feature := v.info().feature; feature != FeatureNameUndefined {
+		return feature
+	}
+	switch {
+	case v == TextureFormatRG11B10Ufloat && usage&TextureUsageRenderAttachment != 0:
+		return FeatureNameRG11B10UfloatRenderable
+	case v == TextureFormatBGRA8Unorm && usage&TextureUsageStorageBinding != 0:
+		return FeatureNameBGRA8UnormStorage
+	}
+	return FeatureNameUndefined
+}
+
+// FilterableFeature returns FeatureNameFloat32Filterable for the 32-bit
+// float formats, which can only be filtered when the device has it, and
+// FeatureNameUndefined otherwise.
+func (v TextureFormat) FilterableFeature() FeatureName {
+	if v.info().kind == formatFloat32 {
+		return FeatureNameFloat32Filterable
+	}
+	return FeatureNameUndefined
+}
+
+// SampleType returns the sample type textures of the format bind as.
+// Depth-stencil formats bind as TextureSampleTypeDepth, their depth
+// aspect; the 32-bit float formats bind as
+// TextureSampleTypeUnfilterableFloat, see FilterableFeature.
+func (v TextureFormat) SampleType() TextureSampleType {
+	switch v.info().kind {
+	case formatUnorm, formatSnorm, formatFloat:
+		return TextureSampleTypeFloat
+	case formatFloat32:
+		return TextureSampleTypeUnfilterableFloat
+	case formatUint, formatStencil:
+		return TextureSampleTypeUint
+	case formatSint:
+		return TextureSampleTypeSint
+	case formatDepth, formatDepthStencil:
+		return TextureSampleTypeDepth
+	}
+	return TextureSampleTypeUndefined
+}
+
+// Srgb returns the sRGB counterpart of the format, such as
+// TextureFormatRGBA8UnormSrgb for TextureFormatRGBA8Unorm. Formats
+// without one are returned unchanged.
+func (v TextureFormat) Srgb() TextureFormat {
+	if !v.info().srgb && (v + 1).info().srgb {
+		return v + 1
+	}
+	return v
+}
+
+// Linear returns the linear counterpart of an sRGB format, such as
+// TextureFormatRGBA8Unorm for TextureFormatRGBA8UnormSrgb. Other
+// formats are returned unchanged.
+func (v TextureFormat) Linear() TextureFormat {
+	if v.info().srgb {
+		return v - 1
+	}
+	return v
+}
@@ -20,17 +20,60 @@ func (g TextureView) Release() {} // no-op
 // https://gpuweb.github.io/gpuweb/#gputexture
 type Texture struct {
 	jsValue js.Value
+
+	device *Device
 }
 
 func (g Texture) toJS() any {
 	return g.jsValue
 }
 
+// GetWidth as described:
+// https://gpuweb.github.io/gpuweb/#dom-gputexture-width
+func (g Texture) GetWidth() uint32 {
+	return uint32(g.jsValue.Get("width").Int())
+}
+
+// GetHeight as described:
+// https://gpuweb.github.io/gpuweb/#dom-gputexture-height
+func (g Texture) GetHeight() uint32 {
+	return uint32(g.jsValue.Get("height").Int())
+}
+
+// GetDimension as described:
+// https://gpuweb.github.io/gpuweb/#dom-gputexture-dimension
+func (g Texture) GetDimension() TextureDimension {
+	jsDimension := g.jsValue.Get("dimension").String()
+	for _, d := range []TextureDimension{TextureDimension1D, TextureDimension2D, TextureDimension3D} {
+		if d.String() == jsDimension {
+			return d
+		}
+	}
+	return TextureDimension2D
+}
+
 // GetFormat as described:
 // https://gpuweb.github.io/gpuweb/#dom-gputexture-format
 func (g Texture) GetFormat() TextureFormat {
-	jsFormat := g.jsValue.Get("format")
-	return TextureFormat(jsFormat.Int()) // TODO(kai): need to set from string
+	jsFormat := g.jsValue.Get("format").String()
+	for i := range textureFormatInfos {
+		if f := TextureFormat(i); f.String() == jsFormat {
+			return f
+		}
+	}
+	return TextureFormatUndefined
+}
+
+// GetUsage as described:
+// https://gpuweb.github.io/gpuweb/#dom-gputexture-usage
+func (g Texture) GetUsage() TextureUsage {
+	return TextureUsage(g.jsValue.Get("usage").Int())
+}
+
+// GetSampleCount as described:
+// https://gpuweb.github.io/gpuweb/#dom-gputexture-samplecount
+func (g Texture) GetSampleCount() uint32 {
+	return uint32(g.jsValue.Get("sampleCount").Int())
 }
 
 // GetDepthOrArrayLayers as described:
@@ -156,6 +156,7 @@ func (g *TextureDataLayout) toJS() any {
 
 func (g *RenderPassDescriptor) toJS() any {
 	result := make(map[string]any)
+	result["label"] = g.Label
 	result["colorAttachments"] = mapSlice(g.ColorAttachments, func(attachment RenderPassColorAttachment) any {
 		return attachment.toJS()
 	})
@@ -190,6 +191,7 @@ func (g *RenderPassDepthStencilAttachment) toJS() any {
 
 func (g *RenderPipelineDescriptor) toJS() any {
 	result := make(map[string]any)
+	result["label"] = g.Label
 	if g.Layout == nil {
 		result["layout"] = "auto"
 	} else {
@@ -205,6 +207,7 @@ func (g *RenderPipelineDescriptor) toJS() any {
 
 func (g *SamplerDescriptor) toJS() any {
 	result := make(map[string]any)
+	result["label"] = g.Label
 	result["addressModeU"] = enumToJS(g.AddressModeU)
 	result["addressModeV"] = enumToJS(g.AddressModeV)
 	result["addressModeW"] = enumToJS(g.AddressModeW)
@@ -222,9 +225,20 @@ func (g *ProgrammableStageDescriptor) toJS() any {
 	return map[string]any{
 		"module":     pointerToJS(g.Module),
 		"entryPoint": g.EntryPoint,
+		"constants":  constantsToJS(g.Constants),
 	}
 }
 
+// constantsToJS converts constants to a record<USVString,
+// GPUPipelineConstantValue>.
+func constantsToJS(constants []ConstantEntry) any {
+	result := make(map[string]any, len(constants))
+	for _, c := range constants {
+		result[c.Key] = c.Value
+	}
+	return result
+}
+
 func limitsFromJS(j js.Value) Limits {
 	return Limits{
 		MaxTextureDimension1D:                     uint32(j.Get("maxTextureDimension1D").Int()),
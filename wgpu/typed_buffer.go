@@ -0,0 +1,137 @@
+package wgpu
+
+import (
+	"context"
+	"strconv"
+	"unsafe"
+)
+
+// TypedBuffer is a buffer holding a fixed number of elements of type T.
+// The memory layout of T must match the shader's, which
+// cmd/wgslgen can generate. The buffer size is padded to
+// CopyBufferAlignment.
+type TypedBuffer[T any] struct {
+	buffer *Buffer
+	len    int
+}
+
+// CreateTypedBuffer creates a buffer for n elements of type T.
+func CreateTypedBuffer[T any](device *Device, label string, usage BufferUsage, n int) (*TypedBuffer[T], error) {
+	if n < 0 {
+		return nil, &Error{Op: "CreateTypedBuffer", Label: label, Type: ErrorTypeValidation, Message: "negative length " + strconv.Itoa(n)}
+	}
+	buffer, err := device.CreateBuffer(&BufferDescriptor{
+		Label: label,
+		Size:  alignCopySize(uint64(n) * elemSize[T]()),
+		Usage: usage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TypedBuffer[T]{buffer: buffer, len: n}, nil
+}
+
+// CreateTypedBufferInit creates a buffer holding a copy of data.
+func CreateTypedBufferInit[T any](device *Device, label string, usage BufferUsage, data []T) (*TypedBuffer[T], error) {
+	buffer, err := device.CreateBufferInit(&BufferInitDescriptor{
+		Label:    label,
+		Contents: ToBytes(data),
+		Usage:    usage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TypedBuffer[T]{buffer: buffer, len: len(data)}, nil
+}
+
+// Buffer returns the underlying buffer.
+func (b *TypedBuffer[T]) Buffer() *Buffer { return b.buffer }
+
+// Len returns the number of elements in the buffer.
+func (b *TypedBuffer[T]) Len() int { return b.len }
+
+// Size returns the size of the elements in bytes, without padding.
+func (b *TypedBuffer[T]) Size() uint64 { return uint64(b.len) * elemSize[T]() }
+
+// Release releases the underlying buffer.
+func (b *TypedBuffer[T]) Release() { b.buffer.Release() }
+
+// Write writes data to the elements starting at index offset with
+// queue.WriteBuffer. The written bytes must start at a multiple of 4;
+// they must also end at one unless the write reaches the last element,
+// in which case the padding is written too.
+func (b *TypedBuffer[T]) Write(queue *Queue, offset int, data []T) error {
+	fail := func(msg string) error {
+		return &Error{Op: "TypedBuffer.Write", Label: b.buffer.Label(), Type: ErrorTypeValidation, Message: msg}
+	}
+	if offset < 0 || offset+len(data) > b.len {
+		return fail("elements [" + strconv.Itoa(offset) + ", " + strconv.Itoa(offset+len(data)) + ") out of range [0, " + strconv.Itoa(b.len) + ")")
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	byteOffset := uint64(offset) * elemSize[T]()
+	bytes := ToBytes(data)
+	if byteOffset%CopyBufferAlignment != 0 {
+		return fail("byte offset " + strconv.FormatUint(byteOffset, 10) + " is not a multiple of 4")
+	}
+	if len(bytes)%CopyBufferAlignment != 0 {
+		if offset+len(data) != b.len {
+			return fail("byte size " + strconv.Itoa(len(bytes)) + " is not a multiple of 4")
+		}
+		padded := make([]byte, alignCopySize(uint64(len(bytes))))
+		copy(padded, bytes)
+		bytes = padded
+	}
+	return queue.WriteBuffer(b.buffer, byteOffset, bytes)
+}
+
+// Read reads all elements, as Buffer.Read does. The buffer needs
+// BufferUsageMapRead.
+func (b *TypedBuffer[T]) Read(ctx context.Context) ([]T, error) {
+	dst := make([]T, b.len)
+	if err := b.buffer.ReadInto(ctx, ToBytes(dst)); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// Slice returns the elements [start, end) as a BufferSlice, for use in
+// bind groups. The byte offset of start must satisfy the device's
+// minUniformBufferOffsetAlignment or minStorageBufferOffsetAlignment
+// limit when bound.
+func (b *TypedBuffer[T]) Slice(start, end int) BufferSlice {
+	if start < 0 || end < start || end > b.len {
+		panic("wgpu: TypedBuffer.Slice: range [" + strconv.Itoa(start) + ", " + strconv.Itoa(end) + ") out of bounds [0, " + strconv.Itoa(b.len) + ")")
+	}
+	size := elemSize[T]()
+	return BufferSlice{Buffer: b.buffer, Offset: uint64(start) * size, Size: uint64(end-start) * size}
+}
+
+// BindGroupEntry returns a bind group entry for the whole buffer.
+func (b *TypedBuffer[T]) BindGroupEntry(binding uint32) BindGroupEntry {
+	return b.Slice(0, b.len).BindGroupEntry(binding)
+}
+
+// BufferSlice is a byte range of a buffer.
+type BufferSlice struct {
+	Buffer *Buffer
+	Offset uint64
+	Size   uint64
+}
+
+// BindGroupEntry returns a bind group entry for the range.
+func (s BufferSlice) BindGroupEntry(binding uint32) BindGroupEntry {
+	return BindGroupEntry{Binding: binding, Buffer: s.Buffer, Offset: s.Offset, Size: s.Size}
+}
+
+func elemSize[T any]() uint64 {
+	var zero T
+	return uint64(unsafe.Sizeof(zero))
+}
+
+// alignCopySize rounds size up to CopyBufferAlignment.
+func alignCopySize(size uint64) uint64 {
+	return (size + CopyBufferAlignment - 1) &^ (CopyBufferAlignment - 1)
+}
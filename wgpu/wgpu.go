@@ -87,6 +87,7 @@ func init() {
 }
 
 func SetLogLevel(level LogLevel) {
+	logLevel.Store(uint32(level))
 	C.wgpuSetLogLevel(C.WGPULogLevel(level))
 }
 
@@ -95,26 +96,118 @@ func GetVersion() Version {
 }
 
 type (
-	BindGroup       struct{ ref C.WGPUBindGroup }
-	BindGroupLayout struct{ ref C.WGPUBindGroupLayout }
-	CommandBuffer   struct{ ref C.WGPUCommandBuffer }
-	PipelineLayout  struct{ ref C.WGPUPipelineLayout }
-	QuerySet        struct{ ref C.WGPUQuerySet }
-	RenderBundle    struct{ ref C.WGPURenderBundle }
-	Sampler         struct{ ref C.WGPUSampler }
-	ShaderModule    struct{ ref C.WGPUShaderModule }
-	TextureView     struct{ ref C.WGPUTextureView }
+	BindGroup struct {
+		ref   C.WGPUBindGroup
+		label string
+		refs  refCount
+	}
+	BindGroupLayout struct {
+		ref   C.WGPUBindGroupLayout
+		label string
+		refs  refCount
+	}
+	CommandBuffer struct {
+		ref   C.WGPUCommandBuffer
+		label string
+		refs  refCount
+	}
+	PipelineLayout struct {
+		ref   C.WGPUPipelineLayout
+		label string
+		refs  refCount
+	}
+	QuerySet struct {
+		ref   C.WGPUQuerySet
+		label string
+		refs  refCount
+	}
+	RenderBundle struct {
+		ref   C.WGPURenderBundle
+		label string
+		refs  refCount
+	}
+	Sampler struct {
+		ref   C.WGPUSampler
+		label string
+		refs  refCount
+	}
+	TextureView struct {
+		ref   C.WGPUTextureView
+		label string
+		refs  refCount
+	}
 )
 
-func (p *BindGroup) Release()       { C.wgpuBindGroupRelease(p.ref) }
-func (p *BindGroupLayout) Release() { C.wgpuBindGroupLayoutRelease(p.ref) }
-func (p *CommandBuffer) Release()   { C.wgpuCommandBufferRelease(p.ref) }
-func (p *PipelineLayout) Release()  { C.wgpuPipelineLayoutRelease(p.ref) }
-func (p *QuerySet) Release()        { C.wgpuQuerySetRelease(p.ref) }
-func (p *RenderBundle) Release()    { C.wgpuRenderBundleRelease(p.ref) }
-func (p *Sampler) Release()         { C.wgpuSamplerRelease(p.ref) }
-func (p *ShaderModule) Release()    { C.wgpuShaderModuleRelease(p.ref) }
-func (p *TextureView) Release()     { C.wgpuTextureViewRelease(p.ref) }
+// ShaderModule keeps its WGSL source, to validate the constants of the
+// pipelines created from it.
+type ShaderModule struct {
+	ref   C.WGPUShaderModule
+	label string
+	refs  refCount
+	code  string
+}
+
+func (p *BindGroup) Release() {
+	if p.refs.release("BindGroup.Release", p.label) {
+		C.wgpuBindGroupRelease(p.ref)
+	}
+}
+
+func (p *BindGroupLayout) Release() {
+	if p.refs.release("BindGroupLayout.Release", p.label) {
+		C.wgpuBindGroupLayoutRelease(p.ref)
+	}
+}
+
+func (p *CommandBuffer) Release() {
+	if p.refs.release("CommandBuffer.Release", p.label) {
+		C.wgpuCommandBufferRelease(p.ref)
+	}
+}
+
+func (p *PipelineLayout) Release() {
+	if p.refs.release("PipelineLayout.Release", p.label) {
+		C.wgpuPipelineLayoutRelease(p.ref)
+	}
+}
+
+func (p *QuerySet) Release() {
+	if p.refs.release("QuerySet.Release", p.label) {
+		C.wgpuQuerySetRelease(p.ref)
+	}
+}
+
+func (p *RenderBundle) Release() {
+	if p.refs.release("RenderBundle.Release", p.label) {
+		C.wgpuRenderBundleRelease(p.ref)
+	}
+}
+
+func (p *Sampler) Release() {
+	if p.refs.release("Sampler.Release", p.label) {
+		C.wgpuSamplerRelease(p.ref)
+	}
+}
+
+func (p *ShaderModule) Release() {
+	if p.refs.release("ShaderModule.Release", p.label) {
+		C.wgpuShaderModuleRelease(p.ref)
+	}
+}
+
+func (p *TextureView) Release() {
+	if p.refs.release("TextureView.Release", p.label) {
+		C.wgpuTextureViewRelease(p.ref)
+	}
+}
+
+// GetCompilationInfo returns no messages: wgpu-native reports no
+// warnings, and Device.CreateShaderModule returns the errors of a shader
+// that fails to compile as a *CompilationError, as it does on the web,
+// where GetCompilationInfo also returns the warnings.
+func (p *ShaderModule) GetCompilationInfo() (*CompilationInfo, error) {
+	return &CompilationInfo{}, nil
+}
 
 // cBool converts the given Go bool to a C.WGPUBool.
 func cBool(b bool) C.WGPUBool {
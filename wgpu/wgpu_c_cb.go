@@ -22,8 +22,8 @@ void gowebgpu_request_device_callback_c(WGPURequestDeviceStatus status, WGPUDevi
 }
 
 void gowebgpu_device_lost_callback_c(WGPUDeviceLostReason reason, char const * message, void * userdata) {
-  extern void gowebgpu_device_lost_callback_go(WGPUDeviceLostReason reason, char const * message, void * userdata);
-  gowebgpu_device_lost_callback_go(reason, message, userdata);
+  extern void gowebgpu_device_lost_callback_go(WGPUDeviceLostReason reason, char const * message, uintptr_t id);
+  gowebgpu_device_lost_callback_go(reason, message, (uintptr_t)userdata);
 }
 
 void gowebgpu_error_callback_c(WGPUErrorType type, char const * message, void * userdata) {
@@ -35,6 +35,45 @@ void gowebgpu_error_callback_c(WGPUErrorType type, char const * message, void *
   gowebgpu_error_callback_go(type, message, userdata);
 }
 
+// gowebgpu_unscoped_error_userdata is set while the thread makes a call
+// without error scopes, so its errors reach the call instead of the
+// uncaptured error handler.
+_Thread_local void * gowebgpu_unscoped_error_userdata = NULL;
+
+void gowebgpu_uncaptured_error_callback_c(WGPUErrorType type, char const * message, void * userdata) {
+  if (type == WGPUErrorType_NoError) {
+    return;
+  }
+
+  if (gowebgpu_unscoped_error_userdata != NULL) {
+    gowebgpu_error_callback_c(type, message, gowebgpu_unscoped_error_userdata);
+    return;
+  }
+
+  extern void gowebgpu_uncaptured_error_callback_go(WGPUErrorType type, char const * message, uintptr_t id);
+  gowebgpu_uncaptured_error_callback_go(type, message, (uintptr_t)userdata);
+}
+
+// gowebgpu_push_error_scopes and gowebgpu_pop_error_scopes wrap a call in
+// one error scope per filter. Scopes pop in reverse, so a validation
+// error is reported before an out-of-memory or internal error. The
+// device's scope lock is held in between.
+void gowebgpu_push_error_scopes(WGPUDevice device) {
+  extern void gowebgpu_lock_error_scopes(WGPUDevice device);
+  gowebgpu_lock_error_scopes(device);
+  wgpuDevicePushErrorScope(device, WGPUErrorFilter_Internal);
+  wgpuDevicePushErrorScope(device, WGPUErrorFilter_OutOfMemory);
+  wgpuDevicePushErrorScope(device, WGPUErrorFilter_Validation);
+}
+
+void gowebgpu_pop_error_scopes(WGPUDevice device, void * userdata) {
+  extern void gowebgpu_unlock_error_scopes(WGPUDevice device);
+  wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, userdata);
+  wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, userdata);
+  wgpuDevicePopErrorScope(device, gowebgpu_error_callback_c, userdata);
+  gowebgpu_unlock_error_scopes(device);
+}
+
 void gowebgpu_queue_work_done_callback_c(WGPUQueueWorkDoneStatus status, void * userdata) {
   extern void gowebgpu_queue_work_done_callback_go(WGPUQueueWorkDoneStatus status, void * userdata);
   gowebgpu_queue_work_done_callback_go(status, userdata);
@@ -0,0 +1,555 @@
+package wgsl
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Node is implemented by every node of the syntax tree.
+type Node interface {
+	Pos() Pos
+}
+
+// Directive is a global directive such as enable or requires.
+type Directive interface {
+	Node
+	directiveNode()
+}
+
+// Decl is a module-scope declaration.
+type Decl interface {
+	Node
+	declNode()
+}
+
+// Stmt is a statement inside a function body.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Expr is an expression. Type specifiers are expressions too, as WGSL
+// does not distinguish them syntactically.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Module is a parsed WGSL translation unit.
+type Module struct {
+	Directives []Directive
+	Decls      []Decl
+}
+
+func (m *Module) Pos() Pos {
+	if len(m.Directives) > 0 {
+		return m.Directives[0].Pos()
+	}
+	if len(m.Decls) > 0 {
+		return m.Decls[0].Pos()
+	}
+	return Pos{}
+}
+
+// Attribute is an attribute such as @group(0) or @builtin(position).
+type Attribute struct {
+	AtPos Pos
+	Name  string
+	Args  []Expr
+}
+
+func (a *Attribute) Pos() Pos { return a.AtPos }
+
+// Ident is an identifier, optionally followed by a template list as in
+// vec3<f32> or array<u32, 4>. It names declarations, types and values.
+type Ident struct {
+	NamePos      Pos
+	Name         string
+	TemplateArgs []Expr
+}
+
+func (x *Ident) Pos() Pos { return x.NamePos }
+
+// String returns the identifier with its template list in canonical
+// form, for example "array<vec4<f32>, 4>".
+func (x *Ident) String() string {
+	return ExprString(x)
+}
+
+// Directives.
+
+type (
+	// EnableDirective is an enable directive such as "enable f16;".
+	EnableDirective struct {
+		EnablePos  Pos
+		Extensions []*Ident
+	}
+
+	// RequiresDirective is a requires directive listing language
+	// features.
+	RequiresDirective struct {
+		RequiresPos Pos
+		Features    []*Ident
+	}
+
+	// DiagnosticDirective is a module-scope diagnostic filter.
+	DiagnosticDirective struct {
+		DiagnosticPos Pos
+		Severity      *Ident
+		Rule          string
+	}
+)
+
+func (d *EnableDirective) Pos() Pos     { return d.EnablePos }
+func (d *RequiresDirective) Pos() Pos   { return d.RequiresPos }
+func (d *DiagnosticDirective) Pos() Pos { return d.DiagnosticPos }
+
+func (*EnableDirective) directiveNode()     {}
+func (*RequiresDirective) directiveNode()   {}
+func (*DiagnosticDirective) directiveNode() {}
+
+// Declarations.
+
+type (
+	// VarDecl declares a variable with var. AddressSpace and AccessMode
+	// hold the template list, for example var<storage, read_write>.
+	VarDecl struct {
+		Attrs        []*Attribute
+		VarPos       Pos
+		AddressSpace *Ident
+		AccessMode   *Ident
+		Name         *Ident
+		Type         Expr
+		Init         Expr
+	}
+
+	// ConstDecl declares a constant with const.
+	ConstDecl struct {
+		ConstPos Pos
+		Name     *Ident
+		Type     Expr
+		Init     Expr
+	}
+
+	// OverrideDecl declares a pipeline-overridable constant.
+	OverrideDecl struct {
+		Attrs       []*Attribute
+		OverridePos Pos
+		Name        *Ident
+		Type        Expr
+		Init        Expr
+	}
+
+	// LetDecl declares a value with let. It only appears in function
+	// bodies.
+	LetDecl struct {
+		LetPos Pos
+		Name   *Ident
+		Type   Expr
+		Init   Expr
+	}
+
+	// AliasDecl declares a type alias.
+	AliasDecl struct {
+		AliasPos Pos
+		Name     *Ident
+		Type     Expr
+	}
+
+	// StructDecl declares a structure type.
+	StructDecl struct {
+		StructPos Pos
+		Name      *Ident
+		Members   []*StructMember
+	}
+
+	// FuncDecl declares a function.
+	FuncDecl struct {
+		Attrs       []*Attribute
+		FnPos       Pos
+		Name        *Ident
+		Params      []*Param
+		ReturnAttrs []*Attribute
+		ReturnType  Expr
+		Body        *CompoundStmt
+	}
+
+	// ConstAssert is a const_assert, at module scope or in a function.
+	ConstAssert struct {
+		AssertPos Pos
+		Cond      Expr
+	}
+)
+
+// StructMember is a member of a StructDecl.
+type StructMember struct {
+	Attrs []*Attribute
+	Name  *Ident
+	Type  Expr
+}
+
+func (m *StructMember) Pos() Pos {
+	if len(m.Attrs) > 0 {
+		return m.Attrs[0].Pos()
+	}
+	return m.Name.Pos()
+}
+
+// Param is a formal parameter of a FuncDecl.
+type Param struct {
+	Attrs []*Attribute
+	Name  *Ident
+	Type  Expr
+}
+
+func (p *Param) Pos() Pos {
+	if len(p.Attrs) > 0 {
+		return p.Attrs[0].Pos()
+	}
+	return p.Name.Pos()
+}
+
+func (d *VarDecl) Pos() Pos      { return attrsPos(d.Attrs, d.VarPos) }
+func (d *ConstDecl) Pos() Pos    { return d.ConstPos }
+func (d *OverrideDecl) Pos() Pos { return attrsPos(d.Attrs, d.OverridePos) }
+func (d *LetDecl) Pos() Pos      { return d.LetPos }
+func (d *AliasDecl) Pos() Pos    { return d.AliasPos }
+func (d *StructDecl) Pos() Pos   { return d.StructPos }
+func (d *FuncDecl) Pos() Pos     { return attrsPos(d.Attrs, d.FnPos) }
+func (d *ConstAssert) Pos() Pos  { return d.AssertPos }
+
+func (*VarDecl) declNode()      {}
+func (*ConstDecl) declNode()    {}
+func (*OverrideDecl) declNode() {}
+func (*LetDecl) declNode()      {}
+func (*AliasDecl) declNode()    {}
+func (*StructDecl) declNode()   {}
+func (*FuncDecl) declNode()     {}
+func (*ConstAssert) declNode()  {}
+
+// Statements.
+
+type (
+	// EmptyStmt is a lone semicolon.
+	EmptyStmt struct {
+		Semi Pos
+	}
+
+	// CompoundStmt is a braced list of statements.
+	CompoundStmt struct {
+		Attrs  []*Attribute
+		Lbrace Pos
+		List   []Stmt
+	}
+
+	// ReturnStmt is a return statement. Value is nil for a bare return.
+	ReturnStmt struct {
+		ReturnPos Pos
+		Value     Expr
+	}
+
+	// IfStmt is an if statement. Else is nil, an *IfStmt or a
+	// *CompoundStmt.
+	IfStmt struct {
+		Attrs []*Attribute
+		IfPos Pos
+		Cond  Expr
+		Body  *CompoundStmt
+		Else  Stmt
+	}
+
+	// SwitchStmt is a switch statement.
+	SwitchStmt struct {
+		Attrs     []*Attribute
+		SwitchPos Pos
+		Tag       Expr
+		BodyAttrs []*Attribute
+		Clauses   []*CaseClause
+	}
+
+	// LoopStmt is a loop statement. Body does not include the
+	// continuing block.
+	LoopStmt struct {
+		Attrs      []*Attribute
+		LoopPos    Pos
+		Body       *CompoundStmt
+		Continuing *ContinuingStmt
+	}
+
+	// ContinuingStmt is the continuing block of a loop. BreakIf is the
+	// condition of a trailing "break if", or nil.
+	ContinuingStmt struct {
+		ContinuingPos Pos
+		Body          *CompoundStmt
+		BreakIf       Expr
+	}
+
+	// ForStmt is a for statement. Init, Cond and Update may be nil.
+	ForStmt struct {
+		Attrs  []*Attribute
+		ForPos Pos
+		Init   Stmt
+		Cond   Expr
+		Update Stmt
+		Body   *CompoundStmt
+	}
+
+	// WhileStmt is a while statement.
+	WhileStmt struct {
+		Attrs    []*Attribute
+		WhilePos Pos
+		Cond     Expr
+		Body     *CompoundStmt
+	}
+
+	// BranchStmt is a break, continue or discard statement.
+	BranchStmt struct {
+		TokPos Pos
+		Tok    Token
+	}
+
+	// AssignStmt is a simple or compound assignment. The phony
+	// assignment "_ = e" has an *Ident named "_" as Lhs.
+	AssignStmt struct {
+		Lhs    Expr
+		TokPos Pos
+		Tok    Token
+		Rhs    Expr
+	}
+
+	// IncDecStmt is an increment or decrement statement.
+	IncDecStmt struct {
+		X      Expr
+		TokPos Pos
+		Tok    Token
+	}
+
+	// CallStmt is a function call evaluated for its side effects.
+	CallStmt struct {
+		Call *CallExpr
+	}
+
+	// DeclStmt is a var, let or const declaration inside a function.
+	DeclStmt struct {
+		Decl Decl
+	}
+)
+
+// CaseClause is a case or default clause of a SwitchStmt. Default is
+// set when the default selector appears among the selectors.
+type CaseClause struct {
+	CasePos   Pos
+	Selectors []Expr
+	Default   bool
+	Body      *CompoundStmt
+}
+
+func (c *CaseClause) Pos() Pos { return c.CasePos }
+
+func (s *EmptyStmt) Pos() Pos      { return s.Semi }
+func (s *CompoundStmt) Pos() Pos   { return attrsPos(s.Attrs, s.Lbrace) }
+func (s *ReturnStmt) Pos() Pos     { return s.ReturnPos }
+func (s *IfStmt) Pos() Pos         { return attrsPos(s.Attrs, s.IfPos) }
+func (s *SwitchStmt) Pos() Pos     { return attrsPos(s.Attrs, s.SwitchPos) }
+func (s *LoopStmt) Pos() Pos       { return attrsPos(s.Attrs, s.LoopPos) }
+func (s *ContinuingStmt) Pos() Pos { return s.ContinuingPos }
+func (s *ForStmt) Pos() Pos        { return attrsPos(s.Attrs, s.ForPos) }
+func (s *WhileStmt) Pos() Pos      { return attrsPos(s.Attrs, s.WhilePos) }
+func (s *BranchStmt) Pos() Pos     { return s.TokPos }
+func (s *AssignStmt) Pos() Pos     { return s.Lhs.Pos() }
+func (s *IncDecStmt) Pos() Pos     { return s.X.Pos() }
+func (s *CallStmt) Pos() Pos       { return s.Call.Pos() }
+func (s *DeclStmt) Pos() Pos       { return s.Decl.Pos() }
+
+func (*EmptyStmt) stmtNode()      {}
+func (*CompoundStmt) stmtNode()   {}
+func (*ReturnStmt) stmtNode()     {}
+func (*IfStmt) stmtNode()         {}
+func (*SwitchStmt) stmtNode()     {}
+func (*LoopStmt) stmtNode()       {}
+func (*ContinuingStmt) stmtNode() {}
+func (*ForStmt) stmtNode()        {}
+func (*WhileStmt) stmtNode()      {}
+func (*BranchStmt) stmtNode()     {}
+func (*AssignStmt) stmtNode()     {}
+func (*IncDecStmt) stmtNode()     {}
+func (*CallStmt) stmtNode()       {}
+func (*DeclStmt) stmtNode()       {}
+func (*ConstAssert) stmtNode()    {}
+
+// Expressions.
+
+type (
+	// BasicLit is a numeric or boolean literal. Kind is INT, FLOAT,
+	// TRUE or FALSE and Value holds the source text including suffix.
+	BasicLit struct {
+		ValuePos Pos
+		Kind     Token
+		Value    string
+	}
+
+	// ParenExpr is a parenthesized expression.
+	ParenExpr struct {
+		Lparen Pos
+		X      Expr
+	}
+
+	// CallExpr is a function call or value constructor such as
+	// vec4<f32>(1.0).
+	CallExpr struct {
+		Fn   *Ident
+		Args []Expr
+	}
+
+	// IndexExpr is an index expression x[i].
+	IndexExpr struct {
+		X     Expr
+		Index Expr
+	}
+
+	// MemberExpr selects a structure member or vector swizzle.
+	MemberExpr struct {
+		X      Expr
+		Member *Ident
+	}
+
+	// UnaryExpr is a unary expression, including the pointer operators
+	// * and &.
+	UnaryExpr struct {
+		OpPos Pos
+		Op    Token
+		X     Expr
+	}
+
+	// BinaryExpr is a binary expression.
+	BinaryExpr struct {
+		X     Expr
+		OpPos Pos
+		Op    Token
+		Y     Expr
+	}
+)
+
+func (x *BasicLit) Pos() Pos   { return x.ValuePos }
+func (x *ParenExpr) Pos() Pos  { return x.Lparen }
+func (x *CallExpr) Pos() Pos   { return x.Fn.Pos() }
+func (x *IndexExpr) Pos() Pos  { return x.X.Pos() }
+func (x *MemberExpr) Pos() Pos { return x.X.Pos() }
+func (x *UnaryExpr) Pos() Pos  { return x.OpPos }
+func (x *BinaryExpr) Pos() Pos { return x.X.Pos() }
+
+func (*Ident) exprNode()      {}
+func (*BasicLit) exprNode()   {}
+func (*ParenExpr) exprNode()  {}
+func (*CallExpr) exprNode()   {}
+func (*IndexExpr) exprNode()  {}
+func (*MemberExpr) exprNode() {}
+func (*UnaryExpr) exprNode()  {}
+func (*BinaryExpr) exprNode() {}
+
+// Int returns the value of an INT literal.
+func (x *BasicLit) Int() (int64, error) {
+	v := strings.TrimRight(x.Value, "iu")
+	if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+		u, err := strconv.ParseUint(v[2:], 16, 64)
+		return int64(u), err
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// Float returns the value of an INT or FLOAT literal.
+func (x *BasicLit) Float() (float64, error) {
+	if x.Kind == INT {
+		v, err := x.Int()
+		return float64(v), err
+	}
+	v := x.Value
+	if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+		v = strings.TrimRight(v, "fh")
+		if !strings.ContainsAny(v, "pP") {
+			v += "p0"
+		}
+		return strconv.ParseFloat(v, 64)
+	}
+	return strconv.ParseFloat(strings.TrimRight(v, "fh"), 64)
+}
+
+// Bool returns the value of a TRUE or FALSE literal.
+func (x *BasicLit) Bool() bool {
+	return x.Kind == TRUE
+}
+
+func attrsPos(attrs []*Attribute, pos Pos) Pos {
+	if len(attrs) > 0 {
+		return attrs[0].Pos()
+	}
+	return pos
+}
+
+// Attr returns the first attribute called name, or nil.
+func Attr(attrs []*Attribute, name string) *Attribute {
+	for _, a := range attrs {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// ExprString returns a canonical textual form of x.
+func ExprString(x Expr) string {
+	var b strings.Builder
+	writeExpr(&b, x)
+	return b.String()
+}
+
+func writeExpr(b *strings.Builder, x Expr) {
+	switch x := x.(type) {
+	case nil:
+	case *Ident:
+		b.WriteString(x.Name)
+		if len(x.TemplateArgs) > 0 {
+			b.WriteByte('<')
+			writeExprList(b, x.TemplateArgs)
+			b.WriteByte('>')
+		}
+	case *BasicLit:
+		b.WriteString(x.Value)
+	case *ParenExpr:
+		b.WriteByte('(')
+		writeExpr(b, x.X)
+		b.WriteByte(')')
+	case *CallExpr:
+		writeExpr(b, x.Fn)
+		b.WriteByte('(')
+		writeExprList(b, x.Args)
+		b.WriteByte(')')
+	case *IndexExpr:
+		writeExpr(b, x.X)
+		b.WriteByte('[')
+		writeExpr(b, x.Index)
+		b.WriteByte(']')
+	case *MemberExpr:
+		writeExpr(b, x.X)
+		b.WriteByte('.')
+		b.WriteString(x.Member.Name)
+	case *UnaryExpr:
+		b.WriteString(x.Op.String())
+		writeExpr(b, x.X)
+	case *BinaryExpr:
+		writeExpr(b, x.X)
+		b.WriteByte(' ')
+		b.WriteString(x.Op.String())
+		b.WriteByte(' ')
+		writeExpr(b, x.Y)
+	}
+}
+
+func writeExprList(b *strings.Builder, list []Expr) {
+	for i, x := range list {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writeExpr(b, x)
+	}
+}
@@ -0,0 +1,11 @@
+package wgsl
+
+// Error is a syntax error at a position in WGSL source.
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
@@ -0,0 +1,212 @@
+package wgsl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// AddressSpace is a WGSL address space, spelled as in the language.
+type AddressSpace string
+
+const (
+	Uniform AddressSpace = "uniform"
+	Storage AddressSpace = "storage"
+)
+
+// StructLayout is the memory layout of a structure in an address
+// space.
+type StructLayout struct {
+	Name    string
+	Size    uint64
+	Align   uint64
+	Members []MemberLayout
+
+	// RuntimeArrayStride is the element stride of a trailing
+	// runtime-sized array, or 0 if the structure has none. Size then
+	// counts a single element.
+	RuntimeArrayStride uint64
+}
+
+// MemberLayout is the placement of one structure member.
+type MemberLayout struct {
+	Name   string
+	Type   Type
+	Offset uint64
+	Size   uint64
+	Align  uint64
+}
+
+// SizeFor returns the size of the structure when its runtime-sized
+// array holds n elements. Without such an array it returns Size.
+func (l *StructLayout) SizeFor(n int) uint64 {
+	if l.RuntimeArrayStride == 0 {
+		return l.Size
+	}
+	last := l.Members[len(l.Members)-1]
+	return roundUp(l.Align, last.Offset+uint64(n)*l.RuntimeArrayStride)
+}
+
+// LayoutOf returns the layout of s in the given address space. The
+// layout is the same in both address spaces, but the uniform address
+// space adds constraints: structures and arrays must be 16-byte
+// aligned, array strides must be multiples of 16, a structure member
+// must be followed by at least its size rounded up to 16 bytes, and
+// runtime-sized arrays are not allowed. LayoutOf returns an error naming
+// the offending member if s violates them.
+func LayoutOf(s *Struct, space AddressSpace) (*StructLayout, error) {
+	if err := checkHostShareable(s, space, s.Name); err != nil {
+		return nil, err
+	}
+	l := &StructLayout{Name: s.Name, Size: SizeOf(s), Align: AlignOf(s)}
+	for _, m := range s.Members {
+		l.Members = append(l.Members, MemberLayout{
+			Name:   m.Name,
+			Type:   m.Type,
+			Offset: m.Offset,
+			Size:   m.Size,
+			Align:  m.Align,
+		})
+	}
+	if a, ok := s.Members[len(s.Members)-1].Type.(*Array); ok && a.RuntimeSized() {
+		l.RuntimeArrayStride = a.Stride()
+	}
+	return l, nil
+}
+
+// RequiredAlignOf returns the alignment a value of type t must have in
+// the given address space.
+func RequiredAlignOf(t Type, space AddressSpace) uint64 {
+	align := AlignOf(t)
+	if space == Uniform {
+		switch t.(type) {
+		case *Struct, *Array:
+			align = roundUp(16, align)
+		}
+	}
+	return align
+}
+
+// checkHostShareable validates t and the types it contains against the
+// constraints of space. path names t in error messages.
+func checkHostShareable(t Type, space AddressSpace, path string) error {
+	switch t := t.(type) {
+	case Scalar:
+		if t == Bool {
+			return fmt.Errorf("wgsl: %s: bool is not host-shareable", path)
+		}
+	case *Vector:
+		return checkHostShareable(t.Elem, space, path)
+	case *Matrix:
+	case *Atomic:
+		if space == Uniform {
+			return fmt.Errorf("wgsl: %s: atomic types are not allowed in the uniform address space", path)
+		}
+	case *Array:
+		if space == Uniform {
+			if t.RuntimeSized() {
+				return fmt.Errorf("wgsl: %s: runtime-sized arrays are not allowed in the uniform address space", path)
+			}
+			if t.Stride()%16 != 0 {
+				return fmt.Errorf("wgsl: %s: array stride %d is not a multiple of 16 as the uniform address space requires; use a vec4 element or a wrapper structure with @size(%d)",
+					path, t.Stride(), roundUp(16, t.Stride()))
+			}
+		}
+		return checkHostShareable(t.Elem, space, path+"[]")
+	case *Struct:
+		if len(t.Members) == 0 {
+			return fmt.Errorf("wgsl: %s: structure has no members", path)
+		}
+		for i, m := range t.Members {
+			mpath := path + "." + m.Name
+			if err := checkHostShareable(m.Type, space, mpath); err != nil {
+				return err
+			}
+			if align := RequiredAlignOf(m.Type, space); m.Offset%align != 0 {
+				return fmt.Errorf("wgsl: %s: offset %d is not a multiple of %d as the %s address space requires; add @align(%d)",
+					mpath, m.Offset, align, space, align)
+			}
+			if s, ok := m.Type.(*Struct); ok && space == Uniform && i+1 < len(t.Members) {
+				next := t.Members[i+1]
+				if need := roundUp(16, SizeOf(s)); next.Offset-m.Offset < need {
+					return fmt.Errorf("wgsl: %s: member %s must start at least %d bytes after %s in the uniform address space; add @align(16) or @size(%d)",
+						mpath, next.Name, need, m.Name, need)
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("wgsl: %s: %s is not host-shareable", path, t)
+	}
+	return nil
+}
+
+// NewStruct builds a structure from a Go-side description and computes
+// its layout. A member's Align and Size act like the @align and @size
+// attributes when non-zero; Offset is ignored and filled in.
+func NewStruct(name string, members ...*Member) (*Struct, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("wgsl: structure %s has no members", name)
+	}
+	s := &Struct{Name: name}
+	for i, in := range members {
+		if in.Type == nil {
+			return nil, fmt.Errorf("wgsl: %s.%s has no type", name, in.Name)
+		}
+		if a, ok := in.Type.(*Array); ok && a.RuntimeSized() && i != len(members)-1 {
+			return nil, fmt.Errorf("wgsl: %s.%s: runtime-sized array must be the last member", name, in.Name)
+		}
+		m := &Member{Name: in.Name, Type: in.Type, Size: SizeOf(in.Type), Align: AlignOf(in.Type)}
+		if in.Align != 0 {
+			if in.Align&(in.Align-1) != 0 {
+				return nil, fmt.Errorf("wgsl: %s.%s: alignment %d is not a power of two", name, in.Name, in.Align)
+			}
+			m.Align = in.Align
+		}
+		if in.Size != 0 {
+			if in.Size < m.Size {
+				return nil, fmt.Errorf("wgsl: %s.%s: size %d is smaller than %d, the size of %s", name, in.Name, in.Size, m.Size, in.Type)
+			}
+			m.Size = in.Size
+		}
+		s.Members = append(s.Members, m)
+	}
+	s.layoutMembers()
+	return s, nil
+}
+
+// ParseType parses and resolves a type specifier such as
+// "array<vec3<f32>, 8>". Structure and alias names are looked up in
+// decls, which may be nil.
+func ParseType(src string, decls *Reflection) (Type, error) {
+	x, err := ParseExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	r := newResolver(&Module{})
+	if decls != nil {
+		r = decls.resolver
+	}
+	return r.resolveType(x)
+}
+
+// Layout returns the layout of the structure called name in the given
+// address space.
+func (r *Reflection) Layout(name string, space AddressSpace) (*StructLayout, error) {
+	t, err := r.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := t.(*Struct)
+	if !ok {
+		return nil, fmt.Errorf("wgsl: %s is %s, not a structure", name, t)
+	}
+	return LayoutOf(s, space)
+}
+
+func (l *StructLayout) String() string {
+	b := []byte("struct " + l.Name + " { // size " + strconv.FormatUint(l.Size, 10) + ", align " + strconv.FormatUint(l.Align, 10) + "\n")
+	for _, m := range l.Members {
+		b = append(b, "\t"+m.Name+": "+m.Type.String()+", // offset "+strconv.FormatUint(m.Offset, 10)+
+			", size "+strconv.FormatUint(m.Size, 10)+", align "+strconv.FormatUint(m.Align, 10)+"\n"...)
+	}
+	return string(append(b, '}'))
+}
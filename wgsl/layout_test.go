@@ -0,0 +1,178 @@
+package wgsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		space   AddressSpace
+		size    uint64
+		align   uint64
+		offsets []uint64
+		stride  uint64
+	}{
+		{
+			name:    "vec3 padding",
+			src:     "struct S { a: vec3<f32>, b: f32, c: vec2<f32> }",
+			space:   Uniform,
+			size:    32,
+			align:   16,
+			offsets: []uint64{0, 12, 16},
+		},
+		{
+			name:    "matrix columns",
+			src:     "struct S { a: f32, m: mat3x3<f32> }",
+			space:   Uniform,
+			size:    64,
+			align:   16,
+			offsets: []uint64{0, 16},
+		},
+		{
+			name:    "nested structure aligned",
+			src:     "struct Inner { x: f32 } struct S { @align(16) a: Inner, @align(16) b: f32 }",
+			space:   Uniform,
+			size:    32,
+			align:   16,
+			offsets: []uint64{0, 16},
+		},
+		{
+			name:    "array of vec4",
+			src:     "struct S { a: u32, @align(16) v: array<vec4<f32>, 2> }",
+			space:   Uniform,
+			size:    48,
+			align:   16,
+			offsets: []uint64{0, 16},
+		},
+		{
+			name:    "size attribute",
+			src:     "struct S { @size(16) a: f32, b: f32 }",
+			space:   Uniform,
+			size:    20,
+			align:   4,
+			offsets: []uint64{0, 16},
+		},
+		{
+			name:    "storage allows tight arrays",
+			src:     "struct S { count: u32, values: array<f32> }",
+			space:   Storage,
+			size:    8,
+			align:   4,
+			offsets: []uint64{0, 4},
+			stride:  4,
+		},
+		{
+			name:    "storage allows atomics",
+			src:     "struct S { n: atomic<u32>, m: atomic<i32> }",
+			space:   Storage,
+			size:    8,
+			align:   4,
+			offsets: []uint64{0, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ReflectSource(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := r.Layout("S", tt.space)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Size != tt.size || l.Align != tt.align || l.RuntimeArrayStride != tt.stride {
+				t.Errorf("size %d align %d stride %d, want %d %d %d", l.Size, l.Align, l.RuntimeArrayStride, tt.size, tt.align, tt.stride)
+			}
+			if len(l.Members) != len(tt.offsets) {
+				t.Fatalf("%d members, want %d", len(l.Members), len(tt.offsets))
+			}
+			for i, m := range l.Members {
+				if m.Offset != tt.offsets[i] {
+					t.Errorf("member %s at offset %d, want %d", m.Name, m.Offset, tt.offsets[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLayoutUniformRules(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// err is part of the uniform error; the storage layout must
+		// succeed unless storageErr is set.
+		err        string
+		storageErr bool
+	}{
+		{"array stride", "struct S { a: array<f32, 4> }", "S.a: array stride 4 is not a multiple of 16", false},
+		{"array of vec2", "struct S { a: array<vec2<f32>, 2> }", "array stride 8", false},
+		{"runtime-sized array", "struct S { a: array<vec4<f32>> }", "S.a: runtime-sized arrays are not allowed", false},
+		{"structure member alignment", "struct Inner { x: f32 } struct S { a: f32, b: Inner }", "S.b: offset 4 is not a multiple of 16", false},
+		{"array member alignment", "struct E { @size(16) x: f32 } struct S { a: f32, b: array<E, 1> }", "S.b: offset 4 is not a multiple of 16", false},
+		{"space after structure", "struct Inner { x: f32 } struct S { a: Inner, b: f32 }", "S.a: member b must start at least 16 bytes after a", false},
+		{"atomic", "struct S { a: atomic<u32> }", "S.a: atomic types are not allowed", false},
+		{"nested path", "struct Inner { x: array<f32, 2> } struct S { @align(16) i: Inner }", "S.i.x: array stride 4", false},
+		{"bool", "struct S { a: bool }", "S.a: bool is not host-shareable", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ReflectSource(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = r.Layout("S", Uniform)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want one containing %q", err, tt.err)
+			}
+			if _, err := r.Layout("S", Storage); (err != nil) != tt.storageErr {
+				t.Errorf("storage layout error %v, want error %v", err, tt.storageErr)
+			}
+		})
+	}
+}
+
+func TestNewStruct(t *testing.T) {
+	vec3, err := ParseType("vec3<f32>", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStruct("S",
+		&Member{Name: "pos", Type: vec3},
+		&Member{Name: "mass", Type: F32},
+		&Member{Name: "id", Type: U32, Align: 16},
+		&Member{Name: "tail", Type: &Array{Elem: F32}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := LayoutOf(s, Storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "struct S { // size 32, align 16\n" +
+		"\tpos: vec3<f32>, // offset 0, size 12, align 16\n" +
+		"\tmass: f32, // offset 12, size 4, align 4\n" +
+		"\tid: u32, // offset 16, size 4, align 16\n" +
+		"\ttail: array<f32>, // offset 20, size 4, align 4\n}"
+	if got := l.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := l.SizeFor(5); got != 48 {
+		t.Errorf("SizeFor(5) = %d, want 48", got)
+	}
+
+	for _, members := range [][]*Member{
+		nil,
+		{{Name: "a"}},
+		{{Name: "a", Type: &Array{Elem: F32}}, {Name: "b", Type: F32}},
+		{{Name: "a", Type: F32, Align: 3}},
+		{{Name: "a", Type: vec3, Size: 8}},
+	} {
+		if _, err := NewStruct("S", members...); err == nil {
+			t.Errorf("NewStruct accepts %v", members)
+		}
+	}
+}
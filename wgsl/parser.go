@@ -0,0 +1,692 @@
+// Package wgsl implements a lexer, parser and syntax tree for the WebGPU
+// Shading Language. It is pure Go and needs neither cgo nor a GPU, so it
+// can be used to inspect shaders before they reach the driver.
+package wgsl
+
+// Parse parses a complete WGSL module. The returned error, if any, is
+// an *Error describing the first syntax error.
+func Parse(src string) (mod *Module, err error) {
+	p := newParser(src)
+	defer p.recover(&err)
+	return p.parseModule(), nil
+}
+
+// ParseExpr parses a single WGSL expression, such as a type specifier
+// or the initializer of a constant.
+func ParseExpr(src string) (x Expr, err error) {
+	p := newParser(src)
+	defer p.recover(&err)
+	p.next()
+	x = p.parseExpr()
+	p.expect(EOF)
+	return x, nil
+}
+
+type bailout struct{}
+
+type parser struct {
+	s   *scanner
+	err *Error
+
+	pos Pos
+	tok Token
+	lit string
+}
+
+func newParser(src string) *parser {
+	p := &parser{}
+	p.s = newScanner(src, p.error)
+	return p
+}
+
+func (p *parser) recover(err *error) {
+	if r := recover(); r != nil {
+		if _, ok := r.(bailout); !ok {
+			panic(r)
+		}
+		*err = p.err
+	}
+}
+
+// error records the first error and aborts parsing.
+func (p *parser) error(pos Pos, msg string) {
+	if p.err == nil {
+		p.err = &Error{Pos: pos, Msg: msg}
+	}
+	panic(bailout{})
+}
+
+func (p *parser) errorExpected(what string) {
+	found := "'" + p.lit + "'"
+	if p.tok == EOF {
+		found = "end of file"
+	}
+	p.error(p.pos, "expected "+what+", found "+found)
+}
+
+func (p *parser) next() {
+	p.pos, p.tok, p.lit = p.s.scan()
+}
+
+func (p *parser) expect(tok Token) Pos {
+	pos := p.pos
+	if p.tok != tok {
+		what := "'" + tok.String() + "'"
+		if tok == EOF {
+			what = "end of file"
+		}
+		p.errorExpected(what)
+	}
+	p.next()
+	return pos
+}
+
+// got consumes the current token if it is tok.
+func (p *parser) got(tok Token) bool {
+	if p.tok == tok {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// Directives and declarations.
+
+func (p *parser) parseModule() *Module {
+	p.next()
+	m := &Module{}
+	for {
+		switch p.tok {
+		case ENABLE, REQUIRES:
+			tok, pos := p.tok, p.pos
+			p.next()
+			var list []*Ident
+			for {
+				list = append(list, p.parseIdent())
+				if !p.got(COMMA) || p.tok == SEMI {
+					break
+				}
+			}
+			p.expect(SEMI)
+			if tok == ENABLE {
+				m.Directives = append(m.Directives, &EnableDirective{EnablePos: pos, Extensions: list})
+			} else {
+				m.Directives = append(m.Directives, &RequiresDirective{RequiresPos: pos, Features: list})
+			}
+			continue
+		case DIAGNOSTIC:
+			pos := p.pos
+			p.next()
+			severity, rule := p.parseDiagnosticControl()
+			p.expect(SEMI)
+			m.Directives = append(m.Directives, &DiagnosticDirective{DiagnosticPos: pos, Severity: severity, Rule: rule})
+			continue
+		}
+		break
+	}
+
+	for p.tok != EOF {
+		if p.got(SEMI) {
+			continue
+		}
+		m.Decls = append(m.Decls, p.parseDecl())
+	}
+	return m
+}
+
+func (p *parser) parseDiagnosticControl() (severity *Ident, rule string) {
+	p.expect(LPAREN)
+	severity = p.parseIdent()
+	p.expect(COMMA)
+	rule = p.parseIdent().Name
+	if p.got(PERIOD) {
+		rule += "." + p.parseIdent().Name
+	}
+	p.got(COMMA)
+	p.expect(RPAREN)
+	return severity, rule
+}
+
+func (p *parser) parseDecl() Decl {
+	if p.tok == CONST_ASSERT {
+		d := p.parseConstAssert()
+		p.expect(SEMI)
+		return d
+	}
+
+	attrs := p.parseAttributes()
+	noAttrs := func() {
+		if len(attrs) > 0 {
+			p.error(attrs[0].Pos(), "unexpected attribute @"+attrs[0].Name)
+		}
+	}
+
+	var d Decl
+	switch p.tok {
+	case VAR:
+		d = p.parseVarDecl(attrs)
+	case OVERRIDE:
+		d = p.parseOverrideDecl(attrs)
+	case CONST:
+		noAttrs()
+		d = p.parseConstDecl()
+	case ALIAS:
+		noAttrs()
+		pos := p.pos
+		p.next()
+		name := p.parseDeclName()
+		p.expect(ASSIGN)
+		d = &AliasDecl{AliasPos: pos, Name: name, Type: p.parseType()}
+	case STRUCT:
+		noAttrs()
+		return p.parseStructDecl()
+	case FN:
+		return p.parseFuncDecl(attrs)
+	default:
+		p.errorExpected("declaration")
+	}
+	p.expect(SEMI)
+	return d
+}
+
+func (p *parser) parseAttributes() []*Attribute {
+	var list []*Attribute
+	for p.tok == AT {
+		a := &Attribute{AtPos: p.pos}
+		p.next()
+		if p.tok != IDENT && !p.tok.IsKeyword() {
+			p.errorExpected("attribute name")
+		}
+		a.Name = p.lit
+		p.next()
+		if p.got(LPAREN) {
+			a.Args = p.parseExprList(RPAREN)
+			p.expect(RPAREN)
+		}
+		list = append(list, a)
+	}
+	return list
+}
+
+func (p *parser) parseIdent() *Ident {
+	x := &Ident{NamePos: p.pos, Name: p.lit}
+	p.expect(IDENT)
+	return x
+}
+
+// parseDeclName parses the name of a declaration, rejecting reserved
+// words.
+func (p *parser) parseDeclName() *Ident {
+	if p.tok == IDENT && IsReserved(p.lit) {
+		p.error(p.pos, "'"+p.lit+"' is a reserved word")
+	}
+	return p.parseIdent()
+}
+
+// parseOptionallyTyped parses "name" or "name : type".
+func (p *parser) parseOptionallyTyped() (*Ident, Expr) {
+	name := p.parseDeclName()
+	if p.got(COLON) {
+		return name, p.parseType()
+	}
+	return name, nil
+}
+
+func (p *parser) parseVarDecl(attrs []*Attribute) *VarDecl {
+	d := &VarDecl{Attrs: attrs, VarPos: p.expect(VAR)}
+	if p.tok == TMPLBEG {
+		args := p.parseTemplateList()
+		for i, arg := range args {
+			x, ok := arg.(*Ident)
+			if !ok || len(x.TemplateArgs) > 0 || i > 1 {
+				p.error(arg.Pos(), "invalid variable template argument "+ExprString(arg))
+			}
+			if i == 0 {
+				d.AddressSpace = x
+			} else {
+				d.AccessMode = x
+			}
+		}
+	}
+	d.Name, d.Type = p.parseOptionallyTyped()
+	if p.got(ASSIGN) {
+		d.Init = p.parseExpr()
+	}
+	return d
+}
+
+func (p *parser) parseOverrideDecl(attrs []*Attribute) *OverrideDecl {
+	d := &OverrideDecl{Attrs: attrs, OverridePos: p.expect(OVERRIDE)}
+	d.Name, d.Type = p.parseOptionallyTyped()
+	if p.got(ASSIGN) {
+		d.Init = p.parseExpr()
+	}
+	return d
+}
+
+func (p *parser) parseConstDecl() *ConstDecl {
+	d := &ConstDecl{ConstPos: p.expect(CONST)}
+	d.Name, d.Type = p.parseOptionallyTyped()
+	p.expect(ASSIGN)
+	d.Init = p.parseExpr()
+	return d
+}
+
+func (p *parser) parseLetDecl() *LetDecl {
+	d := &LetDecl{LetPos: p.expect(LET)}
+	d.Name, d.Type = p.parseOptionallyTyped()
+	p.expect(ASSIGN)
+	d.Init = p.parseExpr()
+	return d
+}
+
+func (p *parser) parseConstAssert() *ConstAssert {
+	pos := p.expect(CONST_ASSERT)
+	return &ConstAssert{AssertPos: pos, Cond: p.parseExpr()}
+}
+
+func (p *parser) parseStructDecl() *StructDecl {
+	d := &StructDecl{StructPos: p.expect(STRUCT)}
+	d.Name = p.parseDeclName()
+	p.expect(LBRACE)
+	for p.tok != RBRACE {
+		m := &StructMember{Attrs: p.parseAttributes()}
+		m.Name = p.parseDeclName()
+		p.expect(COLON)
+		m.Type = p.parseType()
+		d.Members = append(d.Members, m)
+		if !p.got(COMMA) {
+			break
+		}
+	}
+	p.expect(RBRACE)
+	if len(d.Members) == 0 {
+		p.error(d.StructPos, "structure "+d.Name.Name+" has no members")
+	}
+	return d
+}
+
+func (p *parser) parseFuncDecl(attrs []*Attribute) *FuncDecl {
+	d := &FuncDecl{Attrs: attrs, FnPos: p.expect(FN)}
+	d.Name = p.parseDeclName()
+	p.expect(LPAREN)
+	for p.tok != RPAREN {
+		param := &Param{Attrs: p.parseAttributes()}
+		param.Name = p.parseDeclName()
+		p.expect(COLON)
+		param.Type = p.parseType()
+		d.Params = append(d.Params, param)
+		if !p.got(COMMA) {
+			break
+		}
+	}
+	p.expect(RPAREN)
+	if p.got(ARROW) {
+		d.ReturnAttrs = p.parseAttributes()
+		d.ReturnType = p.parseType()
+	}
+	d.Body = p.parseCompoundStmt(p.parseAttributes())
+	return d
+}
+
+// parseType parses a type specifier: an identifier with an optional
+// template list.
+func (p *parser) parseType() Expr {
+	x := p.parseIdent()
+	if p.tok == TMPLBEG {
+		x.TemplateArgs = p.parseTemplateList()
+	}
+	return x
+}
+
+func (p *parser) parseTemplateList() []Expr {
+	p.expect(TMPLBEG)
+	list := p.parseExprList(TMPLEND)
+	if len(list) == 0 {
+		p.errorExpected("template argument")
+	}
+	p.expect(TMPLEND)
+	return list
+}
+
+// parseExprList parses a comma-separated, possibly empty list of
+// expressions with an optional trailing comma, stopping before end.
+func (p *parser) parseExprList(end Token) []Expr {
+	var list []Expr
+	for p.tok != end {
+		list = append(list, p.parseExpr())
+		if !p.got(COMMA) {
+			break
+		}
+	}
+	return list
+}
+
+// Statements.
+
+func (p *parser) parseCompoundStmt(attrs []*Attribute) *CompoundStmt {
+	s := &CompoundStmt{Attrs: attrs, Lbrace: p.expect(LBRACE)}
+	for p.tok != RBRACE && p.tok != EOF {
+		s.List = append(s.List, p.parseStmt())
+	}
+	p.expect(RBRACE)
+	return s
+}
+
+func (p *parser) parseStmt() Stmt {
+	switch p.tok {
+	case SEMI:
+		s := &EmptyStmt{Semi: p.pos}
+		p.next()
+		return s
+	case RETURN:
+		s := &ReturnStmt{ReturnPos: p.pos}
+		p.next()
+		if p.tok != SEMI {
+			s.Value = p.parseExpr()
+		}
+		p.expect(SEMI)
+		return s
+	case BREAK, CONTINUE, DISCARD:
+		s := &BranchStmt{TokPos: p.pos, Tok: p.tok}
+		p.next()
+		if s.Tok == BREAK && p.tok == IF {
+			p.error(p.pos, "break if is only allowed at the end of a continuing block")
+		}
+		p.expect(SEMI)
+		return s
+	case CONST_ASSERT:
+		s := p.parseConstAssert()
+		p.expect(SEMI)
+		return s
+	case VAR, LET, CONST:
+		s := p.parseDeclStmt()
+		p.expect(SEMI)
+		return s
+	case AT, LBRACE, IF, SWITCH, LOOP, FOR, WHILE:
+		attrs := p.parseAttributes()
+		switch p.tok {
+		case LBRACE:
+			return p.parseCompoundStmt(attrs)
+		case IF:
+			return p.parseIfStmt(attrs)
+		case SWITCH:
+			return p.parseSwitchStmt(attrs)
+		case LOOP:
+			return p.parseLoopStmt(attrs)
+		case FOR:
+			return p.parseForStmt(attrs)
+		case WHILE:
+			s := &WhileStmt{Attrs: attrs, WhilePos: p.expect(WHILE)}
+			s.Cond = p.parseExpr()
+			s.Body = p.parseCompoundStmt(p.parseAttributes())
+			return s
+		}
+		p.errorExpected("statement")
+	}
+	s := p.parseSimpleStmt()
+	p.expect(SEMI)
+	return s
+}
+
+func (p *parser) parseDeclStmt() *DeclStmt {
+	switch p.tok {
+	case VAR:
+		return &DeclStmt{Decl: p.parseVarDecl(nil)}
+	case LET:
+		return &DeclStmt{Decl: p.parseLetDecl()}
+	default:
+		return &DeclStmt{Decl: p.parseConstDecl()}
+	}
+}
+
+// parseSimpleStmt parses an assignment, increment, decrement or function
+// call statement without the trailing semicolon.
+func (p *parser) parseSimpleStmt() Stmt {
+	if p.tok == PHONY {
+		lhs := &Ident{NamePos: p.pos, Name: "_"}
+		p.next()
+		pos := p.expect(ASSIGN)
+		return &AssignStmt{Lhs: lhs, TokPos: pos, Tok: ASSIGN, Rhs: p.parseExpr()}
+	}
+
+	x := p.parseUnaryExpr()
+	switch p.tok {
+	case ASSIGN, ADD_ASSIGN, SUB_ASSIGN, MUL_ASSIGN, QUO_ASSIGN, REM_ASSIGN,
+		AND_ASSIGN, OR_ASSIGN, XOR_ASSIGN, SHL_ASSIGN, SHR_ASSIGN:
+		s := &AssignStmt{Lhs: x, TokPos: p.pos, Tok: p.tok}
+		p.next()
+		s.Rhs = p.parseExpr()
+		return s
+	case INC, DEC:
+		s := &IncDecStmt{X: x, TokPos: p.pos, Tok: p.tok}
+		p.next()
+		return s
+	}
+	if call, ok := x.(*CallExpr); ok {
+		return &CallStmt{Call: call}
+	}
+	p.errorExpected("assignment, increment, decrement or function call")
+	return nil
+}
+
+func (p *parser) parseIfStmt(attrs []*Attribute) *IfStmt {
+	s := &IfStmt{Attrs: attrs, IfPos: p.expect(IF)}
+	s.Cond = p.parseExpr()
+	s.Body = p.parseCompoundStmt(p.parseAttributes())
+	if p.got(ELSE) {
+		if p.tok == IF {
+			s.Else = p.parseIfStmt(nil)
+		} else {
+			s.Else = p.parseCompoundStmt(p.parseAttributes())
+		}
+	}
+	return s
+}
+
+func (p *parser) parseSwitchStmt(attrs []*Attribute) *SwitchStmt {
+	s := &SwitchStmt{Attrs: attrs, SwitchPos: p.expect(SWITCH)}
+	s.Tag = p.parseExpr()
+	s.BodyAttrs = p.parseAttributes()
+	p.expect(LBRACE)
+	for p.tok == CASE || p.tok == DEFAULT {
+		c := &CaseClause{CasePos: p.pos}
+		if p.got(DEFAULT) {
+			c.Default = true
+		} else {
+			p.next()
+			for p.tok != COLON && p.tok != LBRACE && p.tok != AT {
+				if p.got(DEFAULT) {
+					c.Default = true
+				} else {
+					c.Selectors = append(c.Selectors, p.parseExpr())
+				}
+				if !p.got(COMMA) {
+					break
+				}
+			}
+			if len(c.Selectors) == 0 && !c.Default {
+				p.errorExpected("case selector")
+			}
+		}
+		p.got(COLON)
+		c.Body = p.parseCompoundStmt(p.parseAttributes())
+		s.Clauses = append(s.Clauses, c)
+	}
+	p.expect(RBRACE)
+	return s
+}
+
+func (p *parser) parseLoopStmt(attrs []*Attribute) *LoopStmt {
+	s := &LoopStmt{Attrs: attrs, LoopPos: p.expect(LOOP)}
+	body := &CompoundStmt{Attrs: p.parseAttributes(), Lbrace: p.expect(LBRACE)}
+	for p.tok != RBRACE && p.tok != CONTINUING && p.tok != EOF {
+		body.List = append(body.List, p.parseStmt())
+	}
+	s.Body = body
+	if p.tok == CONTINUING {
+		c := &ContinuingStmt{ContinuingPos: p.pos}
+		p.next()
+		cbody := &CompoundStmt{Attrs: p.parseAttributes(), Lbrace: p.expect(LBRACE)}
+		for p.tok != RBRACE && p.tok != EOF {
+			if p.tok == BREAK {
+				pos := p.pos
+				p.next()
+				if p.got(IF) {
+					c.BreakIf = p.parseExpr()
+					p.expect(SEMI)
+					break
+				}
+				p.expect(SEMI)
+				cbody.List = append(cbody.List, &BranchStmt{TokPos: pos, Tok: BREAK})
+				continue
+			}
+			cbody.List = append(cbody.List, p.parseStmt())
+		}
+		p.expect(RBRACE)
+		c.Body = cbody
+		s.Continuing = c
+	}
+	p.expect(RBRACE)
+	return s
+}
+
+func (p *parser) parseForStmt(attrs []*Attribute) *ForStmt {
+	s := &ForStmt{Attrs: attrs, ForPos: p.expect(FOR)}
+	p.expect(LPAREN)
+	switch p.tok {
+	case SEMI:
+	case VAR, LET, CONST:
+		s.Init = p.parseDeclStmt()
+	default:
+		s.Init = p.parseSimpleStmt()
+	}
+	p.expect(SEMI)
+	if p.tok != SEMI {
+		s.Cond = p.parseExpr()
+	}
+	p.expect(SEMI)
+	if p.tok != RPAREN {
+		s.Update = p.parseSimpleStmt()
+	}
+	p.expect(RPAREN)
+	s.Body = p.parseCompoundStmt(p.parseAttributes())
+	return s
+}
+
+// Expressions.
+//
+// WGSL deliberately has no total precedence order: mixing bitwise
+// operators with other binary operators, or && with ||, requires
+// parentheses. The functions below follow the grammar of the
+// specification, so such expressions are rejected.
+
+func (p *parser) parseExpr() Expr {
+	x := p.parseUnaryExpr()
+	switch op := p.tok; op {
+	case AND, OR, XOR:
+		for p.tok == op {
+			pos := p.pos
+			p.next()
+			x = &BinaryExpr{X: x, OpPos: pos, Op: op, Y: p.parseUnaryExpr()}
+		}
+		return x
+	}
+	x = p.parseRelational(x)
+	switch op := p.tok; op {
+	case LAND, LOR:
+		for p.tok == op {
+			pos := p.pos
+			p.next()
+			x = &BinaryExpr{X: x, OpPos: pos, Op: op, Y: p.parseRelational(p.parseUnaryExpr())}
+		}
+	}
+	return x
+}
+
+// parseRelational continues a relational expression whose first unary
+// operand x has already been parsed.
+func (p *parser) parseRelational(x Expr) Expr {
+	x = p.parseShift(x)
+	switch op := p.tok; op {
+	case LSS, GTR, LEQ, GEQ, EQL, NEQ:
+		pos := p.pos
+		p.next()
+		x = &BinaryExpr{X: x, OpPos: pos, Op: op, Y: p.parseShift(p.parseUnaryExpr())}
+	}
+	return x
+}
+
+func (p *parser) parseShift(x Expr) Expr {
+	switch op := p.tok; op {
+	case SHL, SHR:
+		pos := p.pos
+		p.next()
+		return &BinaryExpr{X: x, OpPos: pos, Op: op, Y: p.parseUnaryExpr()}
+	}
+	x = p.parseMultiplicative(x)
+	for p.tok == ADD || p.tok == SUB {
+		op, pos := p.tok, p.pos
+		p.next()
+		x = &BinaryExpr{X: x, OpPos: pos, Op: op, Y: p.parseMultiplicative(p.parseUnaryExpr())}
+	}
+	return x
+}
+
+func (p *parser) parseMultiplicative(x Expr) Expr {
+	for p.tok == MUL || p.tok == QUO || p.tok == REM {
+		op, pos := p.tok, p.pos
+		p.next()
+		x = &BinaryExpr{X: x, OpPos: pos, Op: op, Y: p.parseUnaryExpr()}
+	}
+	return x
+}
+
+func (p *parser) parseUnaryExpr() Expr {
+	switch op := p.tok; op {
+	case SUB, NOT, TILDE, MUL, AND:
+		pos := p.pos
+		p.next()
+		return &UnaryExpr{OpPos: pos, Op: op, X: p.parseUnaryExpr()}
+	}
+
+	x := p.parsePrimaryExpr()
+	for {
+		switch p.tok {
+		case LBRACK:
+			p.next()
+			x = &IndexExpr{X: x, Index: p.parseExpr()}
+			p.expect(RBRACK)
+		case PERIOD:
+			p.next()
+			x = &MemberExpr{X: x, Member: p.parseIdent()}
+		default:
+			return x
+		}
+	}
+}
+
+func (p *parser) parsePrimaryExpr() Expr {
+	switch p.tok {
+	case IDENT:
+		x := p.parseType().(*Ident)
+		if p.got(LPAREN) {
+			call := &CallExpr{Fn: x, Args: p.parseExprList(RPAREN)}
+			p.expect(RPAREN)
+			return call
+		}
+		return x
+	case INT, FLOAT, TRUE, FALSE:
+		x := &BasicLit{ValuePos: p.pos, Kind: p.tok, Value: p.lit}
+		p.next()
+		return x
+	case LPAREN:
+		x := &ParenExpr{Lparen: p.pos}
+		p.next()
+		x.X = p.parseExpr()
+		p.expect(RPAREN)
+		return x
+	}
+	p.errorExpected("expression")
+	return nil
+}
@@ -0,0 +1,202 @@
+package wgsl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// groupString returns the form of x with every binary and unary
+// expression parenthesized, so that tests can check how it was grouped.
+func groupString(x Expr) string {
+	switch x := x.(type) {
+	case *BinaryExpr:
+		return "(" + groupString(x.X) + " " + x.Op.String() + " " + groupString(x.Y) + ")"
+	case *UnaryExpr:
+		return "(" + x.Op.String() + groupString(x.X) + ")"
+	case *ParenExpr:
+		return groupString(x.X)
+	case *IndexExpr:
+		return groupString(x.X) + "[" + groupString(x.Index) + "]"
+	case *MemberExpr:
+		return groupString(x.X) + "." + x.Member.Name
+	case *CallExpr:
+		args := make([]string, len(x.Args))
+		for i, arg := range x.Args {
+			args[i] = groupString(arg)
+		}
+		return ExprString(x.Fn) + "(" + strings.Join(args, ", ") + ")"
+	}
+	return ExprString(x)
+}
+
+func TestParseExprPrecedence(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"a + b * c", "(a + (b * c))"},
+		{"a * b + c", "((a * b) + c)"},
+		{"a - b - c", "((a - b) - c)"},
+		{"a / b % c", "((a / b) % c)"},
+		{"a + b < c * d", "((a + b) < (c * d))"},
+		{"a << b", "(a << b)"},
+		{"a << b == c", "((a << b) == c)"},
+		{"a < b && c > d", "((a < b) && (c > d))"},
+		{"a && b && c", "((a && b) && c)"},
+		{"a || b == c", "(a || (b == c))"},
+		{"a & b & c", "((a & b) & c)"},
+		{"a ^ b", "(a ^ b)"},
+		{"-a * b", "((-a) * b)"},
+		{"!a && b", "((!a) && b)"},
+		{"-a[i].x", "(-a[i].x)"},
+		{"*p + 1", "((*p) + 1)"},
+		{"(a + b) * c", "((a + b) * c)"},
+		{"f(a + b, c) * 2", "(f((a + b), c) * 2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			x, err := ParseExpr(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := groupString(x); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExprRejectsMixedOperators(t *testing.T) {
+	// WGSL requires parentheses to mix bitwise operators with other
+	// binary operators, && with ||, or to chain comparisons and shifts.
+	for _, src := range []string{
+		"a & b | c",
+		"a & b + c",
+		"a && b || c",
+		"a < b < c",
+		"a << b << c",
+		"a + b & c",
+	} {
+		t.Run(src, func(t *testing.T) {
+			if x, err := ParseExpr(src); err == nil {
+				t.Errorf("parsed as %s", groupString(x))
+			}
+		})
+	}
+}
+
+func TestParseExprTemplates(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		// A '<' after an identifier opens a template list only when a
+		// matching '>' follows.
+		{"vec3<f32>(1.0)", "vec3<f32>(1.0)"},
+		{"array<vec4<f32>, 4>()", "array<vec4<f32>, 4>()"},
+		{"a < b", "(a < b)"},
+		{"a < b && c > d", "((a < b) && (c > d))"},
+		{"a<b>(c)", "a<b>(c)"},
+		{"a < (b > c)", "(a < (b > c))"},
+		{"a<=b", "(a <= b)"},
+		{"a<<b", "(a << b)"},
+		{"a >= b", "(a >= b)"},
+		{"bitcast<u32>(x) >> 1u", "(bitcast<u32>(x) >> 1u)"},
+		{"vec2<i32>(i < j, 0)", "vec2<i32>((i < j), 0)"},
+		{"select(a, b, i < n)", "select(a, b, (i < n))"},
+		{"array<f32, (N > 2)>()", "array<f32, (N > 2)>()"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			x, err := ParseExpr(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := groupString(x); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	src := `
+enable f16;
+
+struct Light {
+	@align(16) color: vec3<f32>,
+	intensity: f32,
+}
+
+@group(0) @binding(0) var<uniform> light: Light;
+@group(0) @binding(1) var<storage, read_write> out: array<f32>;
+override scale: f32 = 2.0;
+const n = 4u;
+alias Color = vec4<f32>;
+
+fn shade(x: f32) -> f32 {
+	var s = 0.0;
+	for (var i = 0u; i < n; i++) {
+		if i > 2u && x < 1.0 {
+			break;
+		}
+		s += x * scale;
+	}
+	loop {
+		continuing {
+			break if s > 1.0;
+		}
+	}
+	return s;
+}
+`
+	m, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, d := range m.Decls {
+		switch d.(type) {
+		case *StructDecl:
+			kinds = append(kinds, "struct")
+		case *VarDecl:
+			kinds = append(kinds, "var")
+		case *OverrideDecl:
+			kinds = append(kinds, "override")
+		case *ConstDecl:
+			kinds = append(kinds, "const")
+		case *AliasDecl:
+			kinds = append(kinds, "alias")
+		case *FuncDecl:
+			kinds = append(kinds, "fn")
+		}
+	}
+	if got, want := strings.Join(kinds, " "), "struct var var override const alias fn"; got != want {
+		t.Errorf("got declarations %s, want %s", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		line int
+	}{
+		{"fn f() {\n\tlet x = ;\n}", 2},
+		{"struct S {\n\ta: f32\n\tb: f32,\n}", 3},
+		{"var<private> x: array<f32;", 1},
+		{"fn f() -> {}", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("got %v, want an *Error", err)
+			}
+			if e.Pos.Line != tt.line {
+				t.Errorf("error %v at line %d, want line %d", e, e.Pos.Line, tt.line)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package preprocess
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// eval evaluates the expression of an #if or #elif directive. Macros
+// are expanded first; identifiers left over evaluate to 0, as in C.
+func (st *state) eval(src string) (int64, error) {
+	toks, err := st.tokenize(src)
+	if err != nil {
+		return 0, err
+	}
+	if len(toks) == 0 {
+		return 0, errors.New("missing expression")
+	}
+	e := &evaluator{toks: toks}
+	v, err := e.binary(0)
+	if err != nil {
+		return 0, err
+	}
+	if e.pos < len(e.toks) {
+		return 0, errors.New("unexpected " + strconv.Quote(e.toks[e.pos]))
+	}
+	return v, nil
+}
+
+// tokenize splits src into tokens, replacing defined(NAME) and macros.
+func (st *state) tokenize(src string) ([]string, error) {
+	var toks []string
+	if err := st.tokenizeTo(&toks, src, nil); err != nil {
+		return nil, err
+	}
+	return toks, nil
+}
+
+// maxTokens bounds the number of tokens of an expression after macro
+// expansion.
+const maxTokens = 1 << 16
+
+// tokenizeTo appends the tokens of src to toks, expanding the macros not
+// in disabled, and stops once toks grows beyond maxTokens.
+func (st *state) tokenizeTo(toks *[]string, src string, disabled []string) error {
+	raw := lex(src)
+	for i := 0; i < len(raw); i++ {
+		if len(*toks) > maxTokens {
+			return errors.New("macro expansion longer than " + strconv.Itoa(maxTokens) + " tokens")
+		}
+		t := raw[i]
+		if t == "defined" {
+			var name string
+			switch {
+			case i+3 < len(raw) && raw[i+1] == "(" && raw[i+3] == ")":
+				name, i = raw[i+2], i+3
+			case i+1 < len(raw):
+				name, i = raw[i+1], i+1
+			}
+			if !isIdent(name) {
+				return errors.New("defined needs a macro name")
+			}
+			if _, ok := st.macros[name]; ok {
+				*toks = append(*toks, "1")
+			} else {
+				*toks = append(*toks, "0")
+			}
+			continue
+		}
+		if isIdent(t) {
+			if value, ok := st.macros[t]; ok && !contains(disabled, t) {
+				if err := st.tokenizeTo(toks, value, append(disabled, t)); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		*toks = append(*toks, t)
+	}
+	return nil
+}
+
+// lex splits an expression into identifiers, numbers and operators.
+func lex(src string) []string {
+	var toks []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case isIdentByte(c):
+			j := i + 1
+			for j < len(src) && isIdentByte(src[j]) {
+				j++
+			}
+			toks = append(toks, src[i:j])
+			i = j
+		default:
+			n := 1
+			if i+1 < len(src) {
+				switch src[i : i+2] {
+				case "&&", "||", "==", "!=", "<=", ">=", "<<", ">>":
+					n = 2
+				}
+			}
+			toks = append(toks, src[i:i+n])
+			i += n
+		}
+	}
+	return toks
+}
+
+type evaluator struct {
+	toks []string
+	pos  int
+}
+
+// precedence returns the binding power of a binary operator, 0 if op is
+// not one.
+func precedence(op string) int {
+	switch op {
+	case "||":
+		return 1
+	case "&&":
+		return 2
+	case "|":
+		return 3
+	case "^":
+		return 4
+	case "&":
+		return 5
+	case "==", "!=":
+		return 6
+	case "<", "<=", ">", ">=":
+		return 7
+	case "<<", ">>":
+		return 8
+	case "+", "-":
+		return 9
+	case "*", "/", "%":
+		return 10
+	}
+	return 0
+}
+
+func (e *evaluator) peek() string {
+	if e.pos < len(e.toks) {
+		return e.toks[e.pos]
+	}
+	return ""
+}
+
+func (e *evaluator) binary(min int) (int64, error) {
+	x, err := e.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := e.peek()
+		prec := precedence(op)
+		if prec == 0 || prec <= min {
+			return x, nil
+		}
+		e.pos++
+		y, err := e.binary(prec)
+		if err != nil {
+			return 0, err
+		}
+		if x, err = apply(op, x, y); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (e *evaluator) unary() (int64, error) {
+	t := e.peek()
+	e.pos++
+	switch t {
+	case "":
+		return 0, errors.New("unexpected end of expression")
+	case "!", "-", "+", "~":
+		x, err := e.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch t {
+		case "!":
+			return b2i(x == 0), nil
+		case "-":
+			return -x, nil
+		case "~":
+			return ^x, nil
+		}
+		return x, nil
+	case "(":
+		x, err := e.binary(0)
+		if err != nil {
+			return 0, err
+		}
+		if e.peek() != ")" {
+			return 0, errors.New("missing ')'")
+		}
+		e.pos++
+		return x, nil
+	case "true":
+		return 1, nil
+	case "false":
+		return 0, nil
+	}
+	if isDigit(t[0]) {
+		v, err := strconv.ParseInt(strings.TrimRight(t, "uUlLi"), 0, 64)
+		if err != nil {
+			return 0, errors.New("invalid number " + strconv.Quote(t))
+		}
+		return v, nil
+	}
+	if isIdent(t) {
+		return 0, nil
+	}
+	return 0, errors.New("unexpected " + strconv.Quote(t))
+}
+
+func apply(op string, x, y int64) (int64, error) {
+	switch op {
+	case "||":
+		return b2i(x != 0 || y != 0), nil
+	case "&&":
+		return b2i(x != 0 && y != 0), nil
+	case "|":
+		return x | y, nil
+	case "^":
+		return x ^ y, nil
+	case "&":
+		return x & y, nil
+	case "==":
+		return b2i(x == y), nil
+	case "!=":
+		return b2i(x != y), nil
+	case "<":
+		return b2i(x < y), nil
+	case "<=":
+		return b2i(x <= y), nil
+	case ">":
+		return b2i(x > y), nil
+	case ">=":
+		return b2i(x >= y), nil
+	case "<<":
+		return x << uint64(y), nil
+	case ">>":
+		return x >> uint64(y), nil
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/", "%":
+		if y == 0 {
+			return 0, errors.New("division by zero")
+		}
+		if op == "/" {
+			return x / y, nil
+		}
+		return x % y, nil
+	}
+	panic("unreachable")
+}
+
+func b2i(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
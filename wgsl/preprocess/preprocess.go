@@ -0,0 +1,459 @@
+// Package preprocess implements a C-like preprocessor for WGSL sources.
+//
+// A line whose first non-blank character is '#' is a directive:
+//
+//	#include "file.wgsl"   include a file relative to the current one
+//	#include <file.wgsl>   include a file relative to the root of the FS
+//	#pragma once           include the current file at most once
+//	#define NAME [value]   define an object-like macro
+//	#undef NAME            remove a macro
+//	#ifdef NAME, #ifndef NAME, #if expr, #elif expr, #else, #endif
+//	#error message         fail with message
+//
+// #if expressions use C integer syntax and semantics and may call
+// defined(NAME). Macros are replaced in all other lines, outside line
+// comments, and a macro is not replaced again within its own expansion.
+// A line may expand to at most 1 MiB. The output carries a source map
+// so that errors reported against it, by the wgsl package or by the
+// driver, can be mapped back to the original files and lines.
+package preprocess
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/openfluke/webgpu/wgsl"
+)
+
+// Preprocessor expands directives in WGSL sources. The zero value has
+// no macros and rejects #include.
+type Preprocessor struct {
+	// FS resolves #include directives. embed.FS and os.DirFS work.
+	FS fs.FS
+
+	// Defines holds the macros defined before processing starts, as
+	// ShaderModuleGLSLDescriptor.Defines does for GLSL.
+	Defines map[string]string
+}
+
+// Location is a position in an original source file. Lines and columns
+// are 1-based; a zero Column means the whole line.
+type Location struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (l Location) String() string {
+	s := l.File + ":" + strconv.Itoa(l.Line)
+	if l.Column > 0 {
+		s += ":" + strconv.Itoa(l.Column)
+	}
+	return s
+}
+
+// Output is the result of preprocessing.
+type Output struct {
+	Code string
+
+	// Lines maps every line of Code to the line it came from: line n of
+	// Code, counting from 1, came from Lines[n-1].
+	Lines []Location
+
+	// Files lists every file that contributed to Code, in the order
+	// they were first read. It is useful to track dependencies.
+	Files []string
+}
+
+// Locate maps a 1-based line and column of Code to the original
+// source. It returns false if line is out of range.
+func (o *Output) Locate(line, column int) (Location, bool) {
+	if line < 1 || line > len(o.Lines) {
+		return Location{}, false
+	}
+	l := o.Lines[line-1]
+	l.Column = column
+	return l, true
+}
+
+// Error is an error located in an original source file. Errors in
+// directives and errors mapped by MapError are of this type.
+type Error struct {
+	Location
+	Msg string
+
+	// Err is the error that was mapped, if any.
+	Err error
+}
+
+func (e *Error) Error() string { return e.Location.String() + ": " + e.Msg }
+func (e *Error) Unwrap() error { return e.Err }
+
+// mappedError is an error whose message had its positions rewritten.
+type mappedError struct {
+	msg string
+	err error
+}
+
+func (e *mappedError) Error() string { return e.msg }
+func (e *mappedError) Unwrap() error { return e.err }
+
+// driverPos matches the positions in the shader diagnostics of
+// wgpu-native, such as "┌─ wgsl:12:5".
+var driverPos = regexp.MustCompile(`wgsl:(\d+):(\d+)`)
+
+// MapError maps the positions in err, which resulted from compiling
+// o.Code, back to the original sources. A *wgsl.Error becomes an
+// *Error; in other errors, such as those returned by
+// Device.CreateShaderModule, "wgsl:line:column" references are
+// rewritten in the message and the original error stays available to
+// errors.As. Errors without positions are returned unchanged.
+func (o *Output) MapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var werr *wgsl.Error
+	if errors.As(err, &werr) {
+		if loc, ok := o.Locate(werr.Pos.Line, werr.Pos.Column); ok {
+			return &Error{Location: loc, Msg: werr.Msg, Err: err}
+		}
+		return err
+	}
+	msg := err.Error()
+	mapped := driverPos.ReplaceAllStringFunc(msg, func(m string) string {
+		sub := driverPos.FindStringSubmatch(m)
+		line, _ := strconv.Atoi(sub[1])
+		column, _ := strconv.Atoi(sub[2])
+		if loc, ok := o.Locate(line, column); ok {
+			return loc.String()
+		}
+		return m
+	})
+	if mapped == msg {
+		return err
+	}
+	return &mappedError{msg: mapped, err: err}
+}
+
+// Process preprocesses the file called name in p.FS.
+func (p *Preprocessor) Process(name string) (*Output, error) {
+	if p.FS == nil {
+		return nil, errors.New("preprocess: no file system to read " + name + " from")
+	}
+	src, err := fs.ReadFile(p.FS, name)
+	if err != nil {
+		return nil, err
+	}
+	return p.ProcessSource(name, string(src))
+}
+
+// ProcessSource preprocesses src. name identifies it in the source map
+// and is the base for relative includes.
+func (p *Preprocessor) ProcessSource(name, src string) (*Output, error) {
+	st := &state{
+		p:      p,
+		macros: map[string]string{},
+		once:   map[string]bool{},
+		seen:   map[string]bool{},
+		out:    &Output{},
+	}
+	for k, v := range p.Defines {
+		st.macros[k] = v
+	}
+	if err := st.file(name, src); err != nil {
+		return nil, err
+	}
+	st.out.Code = st.code.String()
+	return st.out, nil
+}
+
+type state struct {
+	p      *Preprocessor
+	macros map[string]string
+	once   map[string]bool
+	seen   map[string]bool
+	stack  []string // files being processed, for cycle detection
+	code   strings.Builder
+	out    *Output
+}
+
+// cond is an open conditional block.
+type cond struct {
+	loc      Location
+	active   bool // lines in the current branch are emitted
+	taken    bool // some branch was or is active
+	inactive bool // the enclosing block is inactive
+	sawElse  bool
+}
+
+func (st *state) file(name, src string) error {
+	for _, f := range st.stack {
+		if f == name {
+			return &Error{Location: Location{File: name, Line: 1}, Msg: "include cycle: " + strings.Join(append(st.stack, name), " -> ")}
+		}
+	}
+	if st.once[name] {
+		return nil
+	}
+	if !st.seen[name] {
+		st.seen[name] = true
+		st.out.Files = append(st.out.Files, name)
+	}
+	st.stack = append(st.stack, name)
+	defer func() { st.stack = st.stack[:len(st.stack)-1] }()
+
+	var conds []*cond
+	active := func() bool { return len(conds) == 0 || conds[len(conds)-1].active }
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		loc := Location{File: name, Line: i + 1}
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#") {
+			if active() {
+				expanded, err := st.expand(line, nil)
+				if err != nil {
+					return &Error{Location: loc, Msg: err.Error()}
+				}
+				st.emit(expanded, loc)
+			}
+			continue
+		}
+
+		directive, arg := splitDirective(trimmed[1:])
+		fail := func(msg string) error {
+			return &Error{Location: loc, Msg: "#" + directive + ": " + msg}
+		}
+		switch directive {
+		case "if", "ifdef", "ifndef":
+			c := &cond{loc: loc, inactive: !active()}
+			if !c.inactive {
+				v, err := st.condition(directive, arg)
+				if err != nil {
+					return fail(err.Error())
+				}
+				c.active, c.taken = v, v
+			}
+			conds = append(conds, c)
+		case "elif":
+			if len(conds) == 0 {
+				return fail("without #if")
+			}
+			c := conds[len(conds)-1]
+			if c.sawElse {
+				return fail("after #else")
+			}
+			c.active = false
+			if !c.inactive && !c.taken {
+				v, err := st.condition("if", arg)
+				if err != nil {
+					return fail(err.Error())
+				}
+				c.active, c.taken = v, v
+			}
+		case "else":
+			if len(conds) == 0 {
+				return fail("without #if")
+			}
+			c := conds[len(conds)-1]
+			if c.sawElse {
+				return fail("after #else")
+			}
+			c.sawElse = true
+			c.active = !c.inactive && !c.taken
+			c.taken = true
+		case "endif":
+			if len(conds) == 0 {
+				return fail("without #if")
+			}
+			conds = conds[:len(conds)-1]
+		default:
+			if !active() {
+				continue
+			}
+			if err := st.directive(directive, arg, name, loc); err != nil {
+				return err
+			}
+		}
+	}
+	if len(conds) > 0 {
+		c := conds[len(conds)-1]
+		return &Error{Location: c.loc, Msg: "unterminated conditional block"}
+	}
+	return nil
+}
+
+// directive handles the directives that are not part of conditional
+// blocks.
+func (st *state) directive(directive, arg, name string, loc Location) error {
+	fail := func(msg string) error {
+		return &Error{Location: loc, Msg: "#" + directive + ": " + msg}
+	}
+	switch directive {
+	case "include":
+		target, err := st.includePath(name, arg)
+		if err != nil {
+			return fail(err.Error())
+		}
+		if st.p.FS == nil {
+			return fail("no file system to read " + target + " from")
+		}
+		src, err := fs.ReadFile(st.p.FS, target)
+		if err != nil {
+			return fail(err.Error())
+		}
+		return st.file(target, string(src))
+	case "pragma":
+		if arg == "once" {
+			st.once[name] = true
+		}
+		// Unknown pragmas are ignored, as in C.
+	case "define":
+		macro, value := splitDirective(arg)
+		if !isIdent(macro) {
+			return fail("invalid macro name " + strconv.Quote(macro))
+		}
+		st.macros[macro] = value
+	case "undef":
+		if !isIdent(arg) {
+			return fail("invalid macro name " + strconv.Quote(arg))
+		}
+		delete(st.macros, arg)
+	case "error":
+		return &Error{Location: loc, Msg: arg}
+	default:
+		return fail("unknown directive")
+	}
+	return nil
+}
+
+// includePath resolves the argument of an #include directive in file
+// from.
+func (st *state) includePath(from, arg string) (string, error) {
+	if len(arg) >= 2 && arg[0] == '"' && arg[len(arg)-1] == '"' {
+		return path.Join(path.Dir(from), arg[1:len(arg)-1]), nil
+	}
+	if len(arg) >= 2 && arg[0] == '<' && arg[len(arg)-1] == '>' {
+		return path.Clean(arg[1 : len(arg)-1]), nil
+	}
+	return "", errors.New(`expected "file" or <file>`)
+}
+
+func (st *state) condition(directive, arg string) (bool, error) {
+	switch directive {
+	case "ifdef", "ifndef":
+		if !isIdent(arg) {
+			return false, errors.New("invalid macro name " + strconv.Quote(arg))
+		}
+		_, ok := st.macros[arg]
+		return ok == (directive == "ifdef"), nil
+	}
+	v, err := st.eval(arg)
+	return v != 0, err
+}
+
+func (st *state) emit(line string, loc Location) {
+	st.code.WriteString(line)
+	st.code.WriteByte('\n')
+	st.out.Lines = append(st.out.Lines, loc)
+}
+
+// maxExpansion bounds the length of a line after macro expansion, since
+// macros that use another macro several times grow exponentially.
+const maxExpansion = 1 << 20
+
+// expand replaces the macros in line. Macros in disabled are not
+// expanded again, which stops self-referencing macros.
+func (st *state) expand(line string, disabled []string) (string, error) {
+	if len(st.macros) == 0 {
+		return line, nil
+	}
+	var b strings.Builder
+	if !st.expandTo(&b, line, disabled) {
+		return "", errors.New("macro expansion longer than " + strconv.Itoa(maxExpansion) + " bytes")
+	}
+	return b.String(), nil
+}
+
+// expandTo writes the expansion of line to b and reports whether b
+// stayed within maxExpansion.
+func (st *state) expandTo(b *strings.Builder, line string, disabled []string) bool {
+	if b.Len() > maxExpansion {
+		return false
+	}
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			b.WriteString(line[i:])
+			return b.Len() <= maxExpansion
+		case isDigit(c):
+			j := i + 1
+			for j < len(line) && (isIdentByte(line[j]) || line[j] == '.') {
+				j++
+			}
+			b.WriteString(line[i:j])
+			i = j
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(line) && isIdentByte(line[j]) {
+				j++
+			}
+			word := line[i:j]
+			if value, ok := st.macros[word]; ok && !contains(disabled, word) {
+				if !st.expandTo(b, value, append(disabled, word)) {
+					return false
+				}
+			} else {
+				b.WriteString(word)
+			}
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.Len() <= maxExpansion
+}
+
+// splitDirective splits s at the first run of blanks.
+func splitDirective(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+func isDigit(c byte) bool      { return '0' <= c && c <= '9' }
+func isIdentStart(c byte) bool { return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+func isIdentByte(c byte) bool  { return isIdentStart(c) || isDigit(c) }
+
+func isIdent(s string) bool {
+	if s == "" || !isIdentStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isIdentByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+package preprocess
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/openfluke/webgpu/wgsl"
+)
+
+func TestIf(t *testing.T) {
+	defines := map[string]string{"ONE": "1", "TWO": "(ONE + ONE)", "EMPTY": "", "HAS_ONE": "defined(ONE)"}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"1", true},
+		{"0", false},
+		{"1 + 2 * 3 == 7", true},
+		{"(1 + 2) * 3 == 9", true},
+		{"10 - 2 - 3 == 5", true},
+		{"7 / 2 == 3 && 7 % 2 == 1", true},
+		{"1 << 4 == 16 && 256 >> 4 == 16", true},
+		{"(6 & 3) == 2 && (6 | 3) == 7 && (6 ^ 3) == 5", true},
+		{"-1 < 0 && ~0 == -1 && !0", true},
+		{"0 || 0 && 1", false},
+		{"1 || 0 && 0", true},
+		{"0x10 == 16 && 4u == 4", true},
+		{"true && !false", true},
+		{"defined(ONE) && defined TWO", true},
+		{"defined(THREE)", false},
+		{"TWO == 2", true},
+		{"TWO * TWO == 4", true},
+		{"HAS_ONE", true},
+		{"UNDEFINED == 0", true},
+		{"defined(EMPTY)", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p := &Preprocessor{Defines: defines}
+			out, err := p.ProcessSource("main.wgsl", "#if "+tt.expr+"\nyes\n#else\nno\n#endif\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "no\n"
+			if tt.want {
+				want = "yes\n"
+			}
+			if out.Code != want {
+				t.Errorf("got %q, want %q", out.Code, want)
+			}
+		})
+	}
+}
+
+func TestIfErrors(t *testing.T) {
+	for _, expr := range []string{"", "1 +", "(1", "1 / 0", "2 % 0", "1 2", "defined(", "0x", "@"} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := (&Preprocessor{}).ProcessSource("main.wgsl", "#if "+expr+"\n#endif\n")
+			var perr *Error
+			if !errors.As(err, &perr) || perr.Line != 1 || !strings.HasPrefix(perr.Msg, "#if: ") {
+				t.Errorf("got %v, want an #if error at line 1", err)
+			}
+		})
+	}
+}
+
+func TestConditionals(t *testing.T) {
+	src := `#define A
+#ifdef A
+a
+#elif 1
+not elif
+#else
+not else
+#endif
+#ifndef A
+not ifndef
+#elif defined(A)
+elif
+#endif
+#if 0
+#if 1
+nested
+#else
+nested else
+#endif
+#error not reached
+#endif
+`
+	out, err := (&Preprocessor{}).ProcessSource("main.wgsl", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Code != "a\nelif\n" {
+		t.Errorf("got %q", out.Code)
+	}
+
+	for _, src := range []string{"#else\n", "#endif\n", "#elif 1\n", "#if 1\n#else\n#else\n#endif\n", "#if 1\n", "#error stop\n"} {
+		if _, err := (&Preprocessor{}).ProcessSource("main.wgsl", src); err == nil {
+			t.Errorf("no error for %q", src)
+		}
+	}
+}
+
+func TestMacros(t *testing.T) {
+	tests := []struct {
+		name    string
+		defines map[string]string
+		src     string
+		want    string
+	}{
+		{"object macro", map[string]string{"N": "4"}, "var a: array<f32, N>;", "var a: array<f32, 4>;"},
+		{"whole words", map[string]string{"N": "4"}, "let NN = N_ + N;", "let NN = N_ + 4;"},
+		{"numbers", map[string]string{"f": "g"}, "let x = 1.0f + f;", "let x = 1.0f + g;"},
+		{"line comments", map[string]string{"N": "4"}, "N // N", "4 // N"},
+		{"nested", map[string]string{"A": "B + 1", "B": "C * 2", "C": "x"}, "A", "x * 2 + 1"},
+		{"self reference", map[string]string{"X": "X + 1"}, "X", "X + 1"},
+		{"mutual reference", map[string]string{"X": "Y", "Y": "X"}, "X Y", "X Y"},
+		{"reference in value", map[string]string{"X": "f(X, Y)", "Y": "X"}, "Y", "f(X, Y)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := (&Preprocessor{Defines: tt.defines}).ProcessSource("main.wgsl", tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Code != tt.want+"\n" {
+				t.Errorf("got %q, want %q", out.Code, tt.want+"\n")
+			}
+		})
+	}
+}
+
+func TestSelfReferenceInIf(t *testing.T) {
+	src := "#define X X\n#define Y (Z + 1)\n#define Z Y\n#if X == 0 && Y == 1\nok\n#endif\n"
+	out, err := (&Preprocessor{}).ProcessSource("main.wgsl", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Code != "ok\n" {
+		t.Errorf("got %q", out.Code)
+	}
+}
+
+func TestExpansionLimit(t *testing.T) {
+	// Each macro doubles the expansion of the next one.
+	var src strings.Builder
+	for i := 0; i < 39; i++ {
+		fmt.Fprintf(&src, "#define M%d M%d M%d\n", i, i+1, i+1)
+	}
+	src.WriteString("#define M39 x\n")
+	for _, line := range []string{"M0", "#if M0\n#endif"} {
+		t.Run(line, func(t *testing.T) {
+			_, err := (&Preprocessor{}).ProcessSource("main.wgsl", src.String()+line+"\n")
+			var perr *Error
+			if !errors.As(err, &perr) || perr.Line != 41 || !strings.Contains(perr.Msg, "macro expansion longer than") {
+				t.Errorf("got %v, want an expansion error at line 41", err)
+			}
+		})
+	}
+}
+
+func TestInclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"shaders/main.wgsl":       {Data: []byte("#include \"lib/common.wgsl\"\n#include <shaders/lib/common.wgsl>\nmain\n")},
+		"shaders/lib/common.wgsl": {Data: []byte("#pragma once\n#include \"types.wgsl\"\ncommon\n")},
+		"shaders/lib/types.wgsl":  {Data: []byte("types\n")},
+	}
+	out, err := (&Preprocessor{FS: fsys}).Process("shaders/main.wgsl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Code != "types\ncommon\nmain\n" {
+		t.Errorf("got %q", out.Code)
+	}
+	want := []Location{
+		{File: "shaders/lib/types.wgsl", Line: 1},
+		{File: "shaders/lib/common.wgsl", Line: 3},
+		{File: "shaders/main.wgsl", Line: 3},
+	}
+	if len(out.Lines) != len(want) {
+		t.Fatalf("got lines %v, want %v", out.Lines, want)
+	}
+	for i := range want {
+		if out.Lines[i] != want[i] {
+			t.Errorf("line %d from %v, want %v", i+1, out.Lines[i], want[i])
+		}
+	}
+	if got := strings.Join(out.Files, " "); got != "shaders/main.wgsl shaders/lib/common.wgsl shaders/lib/types.wgsl" {
+		t.Errorf("files %s", got)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		loc   Location
+		msg   string
+	}{
+		{
+			name:  "self include",
+			files: map[string]string{"a.wgsl": "#include \"a.wgsl\"\n"},
+			loc:   Location{File: "a.wgsl", Line: 1},
+			msg:   "include cycle: a.wgsl -> a.wgsl",
+		},
+		{
+			name:  "cycle",
+			files: map[string]string{"a.wgsl": "#include \"b.wgsl\"\n", "b.wgsl": "\n#include \"c.wgsl\"\n", "c.wgsl": "#include \"a.wgsl\"\n"},
+			loc:   Location{File: "a.wgsl", Line: 1},
+			msg:   "include cycle: a.wgsl -> b.wgsl -> c.wgsl -> a.wgsl",
+		},
+		{
+			name:  "missing file",
+			files: map[string]string{"a.wgsl": "\n#include \"missing.wgsl\"\n"},
+			loc:   Location{File: "a.wgsl", Line: 2},
+			msg:   "#include: ",
+		},
+		{
+			name:  "bad argument",
+			files: map[string]string{"a.wgsl": "#include missing.wgsl\n"},
+			loc:   Location{File: "a.wgsl", Line: 1},
+			msg:   "#include: expected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, src := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(src)}
+			}
+			_, err := (&Preprocessor{FS: fsys}).Process("a.wgsl")
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("got %v, want an *Error", err)
+			}
+			if perr.Location != tt.loc || !strings.HasPrefix(perr.Msg, tt.msg) {
+				t.Errorf("got %v, want %q at %v", perr, tt.msg, tt.loc)
+			}
+		})
+	}
+
+	if _, err := (&Preprocessor{}).ProcessSource("a.wgsl", "#include \"b.wgsl\"\n"); err == nil {
+		t.Error("#include without a file system succeeds")
+	}
+}
+
+func TestLocate(t *testing.T) {
+	out := &Output{Lines: []Location{{File: "a.wgsl", Line: 4}, {File: "b.wgsl", Line: 1}}}
+	tests := []struct {
+		line, column int
+		want         Location
+		ok           bool
+	}{
+		{1, 3, Location{File: "a.wgsl", Line: 4, Column: 3}, true},
+		{2, 0, Location{File: "b.wgsl", Line: 1}, true},
+		{0, 1, Location{}, false},
+		{3, 1, Location{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := out.Locate(tt.line, tt.column)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Locate(%d, %d) = %v, %v, want %v, %v", tt.line, tt.column, got, ok, tt.want, tt.ok)
+		}
+	}
+	if s := (Location{File: "a.wgsl", Line: 4, Column: 3}).String(); s != "a.wgsl:4:3" {
+		t.Errorf("String() = %q", s)
+	}
+	if s := (Location{File: "a.wgsl", Line: 4}).String(); s != "a.wgsl:4" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestMapError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.wgsl": {Data: []byte("#include \"lib.wgsl\"\nfn main() {\n\tlet x = ;\n}\n")},
+		"lib.wgsl":  {Data: []byte("#define T f32\nalias Real = T;\n")},
+	}
+	out, err := (&Preprocessor{FS: fsys}).Process("main.wgsl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = wgsl.Parse(out.Code)
+	var werr *wgsl.Error
+	if !errors.As(err, &werr) {
+		t.Fatalf("parse error %v", err)
+	}
+	mapped := out.MapError(err)
+	var perr *Error
+	if !errors.As(mapped, &perr) {
+		t.Fatalf("got %v, want an *Error", mapped)
+	}
+	if perr.File != "main.wgsl" || perr.Line != 3 || perr.Column != werr.Pos.Column || perr.Msg != werr.Msg {
+		t.Errorf("got %v, want main.wgsl:3:%d: %s", perr, werr.Pos.Column, werr.Msg)
+	}
+	if !errors.Is(mapped, err) {
+		t.Error("the mapped error does not wrap the original")
+	}
+
+	driver := errors.New("error: expected expression\n   ┌─ wgsl:3:10\n   │\n   ┌─ wgsl:1:14\n   ┌─ wgsl:99:1")
+	mapped = out.MapError(driver)
+	want := "error: expected expression\n   ┌─ main.wgsl:3:10\n   │\n   ┌─ lib.wgsl:2:14\n   ┌─ wgsl:99:1"
+	if mapped.Error() != want {
+		t.Errorf("got %q, want %q", mapped.Error(), want)
+	}
+	if !errors.Is(mapped, driver) {
+		t.Error("the mapped error does not wrap the original")
+	}
+
+	plain := errors.New("out of memory")
+	if out.MapError(plain) != plain {
+		t.Error("an error without positions is changed")
+	}
+	if out.MapError(nil) != nil {
+		t.Error("nil is mapped to an error")
+	}
+}
@@ -0,0 +1,629 @@
+package wgsl
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Stage is a set of shader stages. The bit values match
+// wgpu.ShaderStage.
+type Stage uint32
+
+const (
+	StageVertex   Stage = 0x00000001
+	StageFragment Stage = 0x00000002
+	StageCompute  Stage = 0x00000004
+)
+
+func (s Stage) String() string {
+	var out string
+	for _, v := range []struct {
+		stage Stage
+		name  string
+	}{{StageVertex, "vertex"}, {StageFragment, "fragment"}, {StageCompute, "compute"}} {
+		if s&v.stage != 0 {
+			if out != "" {
+				out += "|"
+			}
+			out += v.name
+		}
+	}
+	if out == "" {
+		return "none"
+	}
+	return out
+}
+
+// EntryPoint is a function with a @vertex, @fragment or @compute
+// attribute. WorkgroupSize is only set for compute entry points;
+// components given by override declarations use their default value, or
+// are 0 if they depend on one without an initializer.
+type EntryPoint struct {
+	Name          string
+	Stage         Stage
+	WorkgroupSize [3]uint32
+	Decl          *FuncDecl
+
+	// Overrides are the override declarations the entry point uses,
+	// directly or through the functions it calls, its workgroup size and
+	// the initializers of other overrides, in declaration order.
+	Overrides []*Override
+}
+
+// Override is a pipeline-overridable constant, declared with override.
+type Override struct {
+	Name string
+
+	// ID is the value of the @id attribute, if HasID is set.
+	ID    uint32
+	HasID bool
+
+	// Type is the declared type, or else the type of the initializer.
+	Type Scalar
+
+	// Default is the initializer, or nil if pipelines must give a value.
+	Default Expr
+
+	Decl *OverrideDecl
+}
+
+// Key returns the key pipeline constants set the override with: the ID
+// in decimal if it has one, and else the name.
+func (o *Override) Key() string {
+	if o.HasID {
+		return strconv.FormatUint(uint64(o.ID), 10)
+	}
+	return o.Name
+}
+
+// Binding is a module-scope resource variable with @group and @binding
+// attributes.
+type Binding struct {
+	Group   uint32
+	Binding uint32
+	Name    string
+
+	// AddressSpace is Uniform or Storage for buffers and empty for
+	// textures and samplers. Access is the access mode of storage
+	// buffers, "read" unless declared otherwise.
+	AddressSpace AddressSpace
+	Access       string
+	Type         Type
+
+	// Visibility is the set of stages whose entry points statically use
+	// the binding.
+	Visibility Stage
+
+	// Filtered is set for textures passed to a builtin that samples
+	// them with a sampler, such as textureSample.
+	Filtered bool
+
+	// Textures lists, for samplers, the textures sampled through the
+	// sampler, in declaration order.
+	Textures []*Binding
+
+	Decl *VarDecl
+}
+
+// Reflection describes the interface of a WGSL module.
+type Reflection struct {
+	Module      *Module
+	Structs     []*Struct
+	Bindings    []*Binding
+	EntryPoints []*EntryPoint
+	Overrides   []*Override
+
+	resolver *resolver
+}
+
+// ReflectSource parses src and reflects the resulting module.
+func ReflectSource(src string) (*Reflection, error) {
+	m, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return Reflect(m)
+}
+
+// Reflect resolves the structures, resource bindings and entry points
+// of m, and determines which entry points use each binding.
+func Reflect(m *Module) (*Reflection, error) {
+	r := &Reflection{Module: m, resolver: newResolver(m)}
+	res := r.resolver
+
+	funcs := map[string]*funcUsage{}
+	bindings := map[string]*Binding{}
+	overrides := map[string]*Override{}
+	for _, d := range m.Decls {
+		switch d := d.(type) {
+		case *OverrideDecl:
+			o, err := r.override(d)
+			if err != nil {
+				return nil, err
+			}
+			r.Overrides = append(r.Overrides, o)
+			overrides[o.Name] = o
+		case *StructDecl:
+			s, err := res.resolveStruct(d)
+			if err != nil {
+				return nil, err
+			}
+			r.Structs = append(r.Structs, s)
+		case *VarDecl:
+			b, err := r.binding(d)
+			if err != nil {
+				return nil, err
+			}
+			if b != nil {
+				r.Bindings = append(r.Bindings, b)
+				bindings[b.Name] = b
+			}
+		case *FuncDecl:
+			funcs[d.Name.Name] = newFuncUsage(d, res.decls)
+			ep, err := r.entryPoint(d)
+			if err != nil {
+				return nil, err
+			}
+			if ep != nil {
+				r.EntryPoints = append(r.EntryPoints, ep)
+			}
+		}
+	}
+
+	sort.SliceStable(r.Bindings, func(i, j int) bool {
+		a, b := r.Bindings[i], r.Bindings[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.Binding < b.Binding
+	})
+
+	// Propagate sampled texture parameters and texture-sampler pairs
+	// from callees to callers until nothing changes.
+	for changed := true; changed; {
+		changed = false
+		for _, f := range funcs {
+			for _, call := range f.calls {
+				callee := funcs[call.Fn.Name]
+				if callee == nil {
+					continue
+				}
+				args := map[string]string{}
+				for i, param := range callee.decl.Params {
+					if i >= len(call.Args) {
+						break
+					}
+					if id, ok := call.Args[i].(*Ident); ok {
+						args[param.Name.Name] = id.Name
+					}
+				}
+				for _, param := range callee.decl.Params {
+					if name, ok := args[param.Name.Name]; ok && callee.sampled[param.Name.Name] && !f.sampled[name] {
+						f.sampled[name] = true
+						changed = true
+					}
+				}
+				for pair := range callee.pairs {
+					for i, name := range pair {
+						if callee.isLocal(name) {
+							pair[i] = args[name]
+						}
+					}
+					if pair[0] != "" && pair[1] != "" && !f.pairs[pair] {
+						f.pairs[pair] = true
+						changed = true
+					}
+				}
+			}
+		}
+	}
+
+	for _, ep := range r.EntryPoints {
+		seen := map[string]bool{}
+		var visit func(name string)
+		visit = func(name string) {
+			if seen[name] {
+				return
+			}
+			seen[name] = true
+			if b := bindings[name]; b != nil {
+				b.Visibility |= ep.Stage
+			}
+			if o := overrides[name]; o != nil && o.Default != nil {
+				visitIdents(o.Default, visit)
+			}
+			if f := funcs[name]; f != nil {
+				for ref := range f.refs {
+					visit(ref)
+				}
+			}
+		}
+		visit(ep.Name)
+		for _, a := range ep.Decl.Attrs {
+			for _, arg := range a.Args {
+				visitIdents(arg, visit)
+			}
+		}
+		for _, o := range r.Overrides {
+			if seen[o.Name] {
+				ep.Overrides = append(ep.Overrides, o)
+			}
+		}
+	}
+	for _, f := range funcs {
+		for name := range f.sampled {
+			if b := bindings[name]; b != nil && !f.isLocal(name) {
+				b.Filtered = true
+			}
+		}
+		for pair := range f.pairs {
+			texture, sampler := bindings[pair[0]], bindings[pair[1]]
+			if texture == nil || sampler == nil || f.isLocal(pair[0]) || f.isLocal(pair[1]) {
+				continue
+			}
+			_, isTexture := texture.Type.(*Texture)
+			_, isSampler := sampler.Type.(*Sampler)
+			if isTexture && isSampler && !containsBinding(sampler.Textures, texture) {
+				sampler.Textures = append(sampler.Textures, texture)
+			}
+		}
+	}
+	for _, b := range r.Bindings {
+		sort.SliceStable(b.Textures, func(i, j int) bool { return b.Textures[i].Decl.Pos().Offset < b.Textures[j].Decl.Pos().Offset })
+	}
+
+	return r, nil
+}
+
+func containsBinding(list []*Binding, b *Binding) bool {
+	for _, x := range list {
+		if x == b {
+			return true
+		}
+	}
+	return false
+}
+
+// visitIdents calls visit with the name of each identifier in x.
+func visitIdents(x Expr, visit func(name string)) {
+	Inspect(x, func(n Node) bool {
+		if id, ok := n.(*Ident); ok {
+			visit(id.Name)
+		}
+		return true
+	})
+}
+
+// Lookup returns the structure or alias type declared as name.
+func (r *Reflection) Lookup(name string) (Type, error) {
+	return r.resolver.resolveType(&Ident{Name: name})
+}
+
+// Group returns the bindings of group g, ordered by binding number.
+func (r *Reflection) Group(g uint32) []*Binding {
+	var list []*Binding
+	for _, b := range r.Bindings {
+		if b.Group == g {
+			list = append(list, b)
+		}
+	}
+	return list
+}
+
+// EntryPoint returns the entry point called name, or nil.
+func (r *Reflection) EntryPoint(name string) *EntryPoint {
+	for _, ep := range r.EntryPoints {
+		if ep.Name == name {
+			return ep
+		}
+	}
+	return nil
+}
+
+func (r *Reflection) binding(d *VarDecl) (*Binding, error) {
+	group, binding := Attr(d.Attrs, "group"), Attr(d.Attrs, "binding")
+	if group == nil && binding == nil {
+		return nil, nil
+	}
+	if group == nil || binding == nil {
+		return nil, errorf(d.Pos(), "resource variable "+d.Name.Name+" requires both @group and @binding")
+	}
+	if d.Type == nil {
+		return nil, errorf(d.Name.Pos(), "resource variable "+d.Name.Name+" must have a type")
+	}
+
+	res := r.resolver
+	g, err := res.attrInt(group)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.attrInt(binding)
+	if err != nil {
+		return nil, err
+	}
+	t, err := res.resolveType(d.Type)
+	if err != nil {
+		return nil, err
+	}
+	b := &Binding{Group: uint32(g), Binding: uint32(n), Name: d.Name.Name, Type: t, Decl: d}
+	if d.AddressSpace != nil {
+		b.AddressSpace = AddressSpace(d.AddressSpace.Name)
+		switch b.AddressSpace {
+		case Uniform:
+		case Storage:
+			b.Access = "read"
+			if d.AccessMode != nil {
+				b.Access = d.AccessMode.Name
+			}
+		default:
+			return nil, errorf(d.AddressSpace.Pos(), "resource variable "+d.Name.Name+" cannot be in the "+d.AddressSpace.Name+" address space")
+		}
+	} else {
+		switch t.(type) {
+		case *Texture, *Sampler:
+		default:
+			return nil, errorf(d.Pos(), "resource variable "+d.Name.Name+" of type "+t.String()+" needs an address space")
+		}
+	}
+	return b, nil
+}
+
+func (r *Reflection) override(d *OverrideDecl) (*Override, error) {
+	o := &Override{Name: d.Name.Name, Default: d.Init, Decl: d}
+	res := r.resolver
+	if a := Attr(d.Attrs, "id"); a != nil {
+		id, err := res.attrInt(a)
+		if err != nil {
+			return nil, err
+		}
+		if id < 0 || id > 0xffff {
+			return nil, errorf(a.Pos(), "@id of "+o.Name+" must be between 0 and 65535")
+		}
+		o.ID, o.HasID = uint32(id), true
+	}
+	switch {
+	case d.Type != nil:
+		t, err := res.resolveType(d.Type)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := t.(Scalar)
+		if !ok {
+			return nil, errorf(d.Type.Pos(), "override "+o.Name+" must have a scalar type, got "+t.String())
+		}
+		o.Type = s
+	case d.Init != nil:
+		s, err := res.scalarType(&Ident{NamePos: d.Name.NamePos, Name: o.Name})
+		if err != nil {
+			return nil, err
+		}
+		o.Type = s
+	default:
+		return nil, errorf(d.Pos(), "override "+o.Name+" requires a type or an initializer")
+	}
+	return o, nil
+}
+
+func (r *Reflection) entryPoint(d *FuncDecl) (*EntryPoint, error) {
+	ep := &EntryPoint{Name: d.Name.Name, Decl: d}
+	for _, a := range d.Attrs {
+		switch a.Name {
+		case "vertex":
+			ep.Stage = StageVertex
+		case "fragment":
+			ep.Stage = StageFragment
+		case "compute":
+			ep.Stage = StageCompute
+		}
+	}
+	if ep.Stage == 0 {
+		return nil, nil
+	}
+	if a := Attr(d.Attrs, "workgroup_size"); a != nil {
+		if len(a.Args) == 0 || len(a.Args) > 3 {
+			return nil, errorf(a.Pos(), "@workgroup_size requires one to three arguments")
+		}
+		ep.WorkgroupSize = [3]uint32{1, 1, 1}
+		for i, arg := range a.Args {
+			v, err := r.resolver.evalInt(arg)
+			if _, ok := err.(*overrideError); ok {
+				ep.WorkgroupSize[i] = 0
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			ep.WorkgroupSize[i] = uint32(v)
+		}
+	}
+	return ep, nil
+}
+
+// samplingBuiltins are the builtins that sample their texture argument
+// through a sampler. The texture is the first argument, or the second
+// for textureGather with a component argument.
+var samplingBuiltins = map[string]bool{
+	"textureSample":                true,
+	"textureSampleBias":            true,
+	"textureSampleCompare":         true,
+	"textureSampleCompareLevel":    true,
+	"textureSampleGrad":            true,
+	"textureSampleLevel":           true,
+	"textureSampleBaseClampToEdge": true,
+	"textureGather":                true,
+	"textureGatherCompare":         true,
+}
+
+// funcUsage records the module-scope names a function refers to, taking
+// local scopes into account, the names it passes as the texture argument
+// of a sampling builtin, and the texture and sampler names passed
+// together.
+type funcUsage struct {
+	decl    *FuncDecl
+	globals map[string]Decl
+	scopes  []map[string]bool
+	refs    map[string]bool
+	sampled map[string]bool
+	pairs   map[[2]string]bool
+	calls   []*CallExpr
+	params  map[string]bool
+}
+
+func newFuncUsage(d *FuncDecl, globals map[string]Decl) *funcUsage {
+	f := &funcUsage{
+		decl:    d,
+		globals: globals,
+		refs:    map[string]bool{},
+		sampled: map[string]bool{},
+		pairs:   map[[2]string]bool{},
+		params:  map[string]bool{},
+	}
+	for _, p := range d.Params {
+		f.params[p.Name.Name] = true
+		f.expr(p.Type)
+	}
+	f.expr(d.ReturnType)
+	f.stmt(d.Body)
+	return f
+}
+
+// isLocal reports whether name is a parameter of the function.
+func (f *funcUsage) isLocal(name string) bool {
+	return f.params[name]
+}
+
+func (f *funcUsage) declared(name string) bool {
+	for i := len(f.scopes) - 1; i >= 0; i-- {
+		if f.scopes[i][name] {
+			return true
+		}
+	}
+	return f.params[name]
+}
+
+// visible reports whether name is a parameter or a module-scope name,
+// rather than a local declaration.
+func (f *funcUsage) visible(name string) bool {
+	return f.params[name] || !f.declared(name)
+}
+
+func (f *funcUsage) declare(name string) {
+	f.scopes[len(f.scopes)-1][name] = true
+}
+
+func (f *funcUsage) push() { f.scopes = append(f.scopes, map[string]bool{}) }
+func (f *funcUsage) pop()  { f.scopes = f.scopes[:len(f.scopes)-1] }
+
+func (f *funcUsage) expr(x Expr) {
+	if x == nil {
+		return
+	}
+	Inspect(x, func(n Node) bool {
+		switch n := n.(type) {
+		case *Ident:
+			if !f.declared(n.Name) && f.globals[n.Name] != nil {
+				f.refs[n.Name] = true
+			}
+		case *MemberExpr:
+			f.expr(n.X)
+			return false
+		case *CallExpr:
+			if samplingBuiltins[n.Fn.Name] {
+				for i, arg := range n.Args {
+					if i > 1 || (i == 1 && n.Fn.Name != "textureGather") {
+						break
+					}
+					id, ok := arg.(*Ident)
+					if !ok || !f.visible(id.Name) {
+						continue
+					}
+					f.sampled[id.Name] = true
+					// The sampler follows the texture. Pairs that are not a
+					// texture and a sampler are dropped later.
+					if i+1 < len(n.Args) {
+						if s, ok := n.Args[i+1].(*Ident); ok && f.visible(s.Name) {
+							f.pairs[[2]string{id.Name, s.Name}] = true
+						}
+					}
+				}
+			}
+			if _, ok := f.globals[n.Fn.Name].(*FuncDecl); ok && !f.declared(n.Fn.Name) {
+				f.calls = append(f.calls, n)
+			}
+		}
+		return true
+	})
+}
+
+func (f *funcUsage) stmt(s Stmt) {
+	switch s := s.(type) {
+	case nil:
+	case *CompoundStmt:
+		if s == nil {
+			return
+		}
+		f.push()
+		for _, s := range s.List {
+			f.stmt(s)
+		}
+		f.pop()
+	case *DeclStmt:
+		switch d := s.Decl.(type) {
+		case *VarDecl:
+			f.expr(d.Type)
+			f.expr(d.Init)
+			f.declare(d.Name.Name)
+		case *LetDecl:
+			f.expr(d.Type)
+			f.expr(d.Init)
+			f.declare(d.Name.Name)
+		case *ConstDecl:
+			f.expr(d.Type)
+			f.expr(d.Init)
+			f.declare(d.Name.Name)
+		}
+	case *ReturnStmt:
+		f.expr(s.Value)
+	case *IfStmt:
+		f.expr(s.Cond)
+		f.stmt(s.Body)
+		f.stmt(s.Else)
+	case *SwitchStmt:
+		f.expr(s.Tag)
+		for _, c := range s.Clauses {
+			for _, x := range c.Selectors {
+				f.expr(x)
+			}
+			f.stmt(c.Body)
+		}
+	case *LoopStmt:
+		f.push()
+		for _, s := range s.Body.List {
+			f.stmt(s)
+		}
+		if s.Continuing != nil {
+			f.stmt(s.Continuing.Body)
+			f.expr(s.Continuing.BreakIf)
+		}
+		f.pop()
+	case *ForStmt:
+		f.push()
+		f.stmt(s.Init)
+		f.expr(s.Cond)
+		f.stmt(s.Update)
+		f.stmt(s.Body)
+		f.pop()
+	case *WhileStmt:
+		f.expr(s.Cond)
+		f.stmt(s.Body)
+	case *AssignStmt:
+		f.expr(s.Lhs)
+		f.expr(s.Rhs)
+	case *IncDecStmt:
+		f.expr(s.X)
+	case *CallStmt:
+		f.expr(s.Call)
+	case *ConstAssert:
+		f.expr(s.Cond)
+	}
+}
@@ -0,0 +1,169 @@
+package wgsl
+
+import (
+	"testing"
+)
+
+const reflectSource = `
+struct Params {
+	scale: f32,
+	count: u32,
+}
+
+@group(0) @binding(0) var<uniform> params: Params;
+@group(0) @binding(1) var<storage, read_write> data: array<f32>;
+@group(1) @binding(1) var samp: sampler;
+@group(1) @binding(0) var tex: texture_2d<f32>;
+@group(1) @binding(2) var raw: texture_2d<f32>;
+@group(0) @binding(2) var<storage> lut: array<vec4<f32>>;
+
+@id(3) override size: u32 = 64u;
+override half = size / 2u;
+override unused: f32;
+
+fn sampleIt(t: texture_2d<f32>, uv: vec2<f32>) -> vec4<f32> {
+	return textureSample(t, samp, uv);
+}
+
+@vertex
+fn vs(@builtin(vertex_index) i: u32) -> @builtin(position) vec4<f32> {
+	return vec4<f32>(f32(i) * params.scale, 0.0, 0.0, 1.0);
+}
+
+@fragment
+fn fs(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+	let lut = vec4<f32>(1.0);
+	return sampleIt(tex, uv) * lut + textureLoad(raw, vec2<i32>(0), 0);
+}
+
+@compute @workgroup_size(half, 2)
+fn cs(@builtin(global_invocation_id) id: vec3<u32>) {
+	data[id.x] = lut[id.x].x * params.scale;
+}
+`
+
+func TestReflect(t *testing.T) {
+	r, err := ReflectSource(reflectSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type binding struct {
+		group, binding uint32
+		name           string
+		space          AddressSpace
+		access         string
+		typ            string
+		visibility     Stage
+		filtered       bool
+	}
+	want := []binding{
+		{0, 0, "params", Uniform, "", "Params", StageVertex | StageCompute, false},
+		{0, 1, "data", Storage, "read_write", "array<f32>", StageCompute, false},
+		// fs declares a local lut, so only cs uses the binding.
+		{0, 2, "lut", Storage, "read", "array<vec4<f32>>", StageCompute, false},
+		{1, 0, "tex", "", "", "texture_2d<f32>", StageFragment, true},
+		{1, 1, "samp", "", "", "sampler", StageFragment, false},
+		{1, 2, "raw", "", "", "texture_2d<f32>", StageFragment, false},
+	}
+	if len(r.Bindings) != len(want) {
+		t.Fatalf("got %d bindings, want %d", len(r.Bindings), len(want))
+	}
+	for i, b := range r.Bindings {
+		got := binding{b.Group, b.Binding, b.Name, b.AddressSpace, b.Access, b.Type.String(), b.Visibility, b.Filtered}
+		if got != want[i] {
+			t.Errorf("binding %d: got %+v, want %+v", i, got, want[i])
+		}
+	}
+	if g := r.Group(1); len(g) != 3 || g[0].Name != "tex" {
+		t.Errorf("Group(1) = %v", g)
+	}
+	// sampleIt receives tex as a parameter and samples it with samp.
+	if samp := r.Group(1)[1]; len(samp.Textures) != 1 || samp.Textures[0].Name != "tex" {
+		t.Errorf("samp samples %v, want [tex]", samp.Textures)
+	}
+
+	cs := r.EntryPoint("cs")
+	if cs == nil || cs.Stage != StageCompute {
+		t.Fatalf("EntryPoint(cs) = %+v", cs)
+	}
+	if cs.WorkgroupSize != [3]uint32{32, 2, 1} {
+		t.Errorf("workgroup size %v, want [32 2 1]", cs.WorkgroupSize)
+	}
+	var names []string
+	for _, o := range cs.Overrides {
+		names = append(names, o.Name)
+	}
+	if len(names) != 2 || names[0] != "size" || names[1] != "half" {
+		t.Errorf("cs overrides %v, want [size half]", names)
+	}
+	if vs := r.EntryPoint("vs"); vs == nil || vs.Stage != StageVertex || len(vs.Overrides) != 0 {
+		t.Errorf("EntryPoint(vs) = %+v", vs)
+	}
+	if r.EntryPoint("sampleIt") != nil {
+		t.Error("sampleIt is reported as an entry point")
+	}
+
+	if len(r.Overrides) != 3 {
+		t.Fatalf("got %d overrides, want 3", len(r.Overrides))
+	}
+	for i, want := range []struct {
+		key string
+		typ Scalar
+	}{{"3", U32}, {"half", U32}, {"unused", F32}} {
+		if o := r.Overrides[i]; o.Key() != want.key || o.Type != want.typ {
+			t.Errorf("override %d: key %s type %s, want %s %s", i, o.Key(), o.Type, want.key, want.typ)
+		}
+	}
+}
+
+func TestReflectWorkgroupSizeOverride(t *testing.T) {
+	r, err := ReflectSource(`
+override n: u32;
+override m = n * 2;
+override k = 4;
+@compute @workgroup_size(n, k, m) fn cs() {}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := r.EntryPoint("cs")
+	if cs.WorkgroupSize != [3]uint32{0, 4, 0} {
+		t.Errorf("workgroup size %v, want [0 4 0]", cs.WorkgroupSize)
+	}
+	var names []string
+	for _, o := range cs.Overrides {
+		names = append(names, o.Name)
+	}
+	if len(names) != 3 || names[0] != "n" || names[1] != "m" || names[2] != "k" {
+		t.Errorf("cs overrides %v, want [n m k]", names)
+	}
+
+	// Other constant expressions still need a value.
+	if _, err := ReflectSource("override n: u32;\n@id(n) override m: f32;\n"); err == nil {
+		t.Error("@id given by an override without an initializer reflected")
+	}
+}
+
+func TestReflectErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"group without binding", "@group(0) var<uniform> u: f32;"},
+		{"binding without type", "@group(0) @binding(0) var<uniform> u;"},
+		{"buffer without address space", "@group(0) @binding(0) var u: f32;"},
+		{"private resource", "@group(0) @binding(0) var<private> u: f32;"},
+		{"override id out of range", "@id(70000) override o: f32;"},
+		{"override without type", "override o;"},
+		{"override of vector type", "override o: vec2<f32>;"},
+		{"too many workgroup sizes", "@compute @workgroup_size(1, 1, 1, 1) fn main() {}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReflectSource(tt.src); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
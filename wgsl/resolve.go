@@ -0,0 +1,549 @@
+package wgsl
+
+import (
+	"strconv"
+	"strings"
+)
+
+// resolver resolves type specifiers and constant expressions against
+// the module-scope declarations of a module.
+type resolver struct {
+	decls     map[string]Decl
+	types     map[string]Type
+	consts    map[string]int64
+	resolving map[string]bool
+}
+
+func newResolver(m *Module) *resolver {
+	r := &resolver{
+		decls:     map[string]Decl{},
+		types:     map[string]Type{},
+		consts:    map[string]int64{},
+		resolving: map[string]bool{},
+	}
+	for _, d := range m.Decls {
+		if name := declName(d); name != "" {
+			r.decls[name] = d
+		}
+	}
+	return r
+}
+
+// declName returns the name a module-scope declaration introduces.
+func declName(d Decl) string {
+	switch d := d.(type) {
+	case *VarDecl:
+		return d.Name.Name
+	case *ConstDecl:
+		return d.Name.Name
+	case *OverrideDecl:
+		return d.Name.Name
+	case *AliasDecl:
+		return d.Name.Name
+	case *StructDecl:
+		return d.Name.Name
+	case *FuncDecl:
+		return d.Name.Name
+	}
+	return ""
+}
+
+func errorf(pos Pos, msg string) *Error {
+	return &Error{Pos: pos, Msg: msg}
+}
+
+var textureDims = map[string]TextureDim{
+	"1d":         Dim1D,
+	"2d":         Dim2D,
+	"2d_array":   Dim2DArray,
+	"3d":         Dim3D,
+	"cube":       DimCube,
+	"cube_array": DimCubeArray,
+}
+
+var scalarSuffixes = map[byte]Scalar{'i': I32, 'u': U32, 'f': F32, 'h': F16}
+
+// resolveType resolves a type specifier.
+func (r *resolver) resolveType(x Expr) (Type, error) {
+	id, ok := x.(*Ident)
+	if !ok {
+		return nil, errorf(x.Pos(), "invalid type "+ExprString(x))
+	}
+	name, args := id.Name, id.TemplateArgs
+
+	nargs := func(n ...int) error {
+		for _, v := range n {
+			if len(args) == v {
+				return nil
+			}
+		}
+		return errorf(id.Pos(), "wrong number of template arguments for "+name)
+	}
+	scalarArg := func(i int) (Scalar, error) {
+		t, err := r.resolveType(args[i])
+		if err != nil {
+			return 0, err
+		}
+		s, ok := t.(Scalar)
+		if !ok {
+			return 0, errorf(args[i].Pos(), name+" requires a scalar type, got "+t.String())
+		}
+		return s, nil
+	}
+	identArg := func(i int) (string, error) {
+		a, ok := args[i].(*Ident)
+		if !ok || len(a.TemplateArgs) > 0 {
+			return "", errorf(args[i].Pos(), "expected identifier, got "+ExprString(args[i]))
+		}
+		return a.Name, nil
+	}
+
+	switch name {
+	case "bool", "i32", "u32", "f32", "f16":
+		if err := nargs(0); err != nil {
+			return nil, err
+		}
+		return map[string]Scalar{"bool": Bool, "i32": I32, "u32": U32, "f32": F32, "f16": F16}[name], nil
+
+	case "vec2", "vec3", "vec4":
+		if err := nargs(1); err != nil {
+			return nil, err
+		}
+		elem, err := scalarArg(0)
+		if err != nil {
+			return nil, err
+		}
+		return &Vector{Size: int(name[3] - '0'), Elem: elem}, nil
+
+	case "array":
+		if err := nargs(1, 2); err != nil {
+			return nil, err
+		}
+		elem, err := r.resolveType(args[0])
+		if err != nil {
+			return nil, err
+		}
+		t := &Array{Elem: elem}
+		if len(args) == 2 {
+			n, err := r.evalInt(args[1])
+			if err != nil {
+				return nil, err
+			}
+			if n <= 0 {
+				return nil, errorf(args[1].Pos(), "array element count must be greater than 0")
+			}
+			t.Count = int(n)
+		}
+		return t, nil
+
+	case "atomic":
+		if err := nargs(1); err != nil {
+			return nil, err
+		}
+		elem, err := scalarArg(0)
+		if err != nil {
+			return nil, err
+		}
+		if elem != I32 && elem != U32 {
+			return nil, errorf(id.Pos(), "atomic requires i32 or u32")
+		}
+		return &Atomic{Elem: elem}, nil
+
+	case "sampler", "sampler_comparison":
+		if err := nargs(0); err != nil {
+			return nil, err
+		}
+		return &Sampler{Comparison: name == "sampler_comparison"}, nil
+
+	case "texture_external":
+		if err := nargs(0); err != nil {
+			return nil, err
+		}
+		return &Texture{Kind: TextureExternal, Dim: Dim2D}, nil
+
+	case "texture_multisampled_2d":
+		if err := nargs(1); err != nil {
+			return nil, err
+		}
+		sampled, err := scalarArg(0)
+		if err != nil {
+			return nil, err
+		}
+		return &Texture{Kind: TextureMultisampled, Dim: Dim2D, Sampled: sampled}, nil
+
+	case "texture_depth_multisampled_2d":
+		if err := nargs(0); err != nil {
+			return nil, err
+		}
+		return &Texture{Kind: TextureDepthMultisampled, Dim: Dim2D}, nil
+	}
+
+	if len(name) == 5 && strings.HasPrefix(name, "vec") && name[3] >= '2' && name[3] <= '4' {
+		if elem, ok := scalarSuffixes[name[4]]; ok {
+			if err := nargs(0); err != nil {
+				return nil, err
+			}
+			return &Vector{Size: int(name[3] - '0'), Elem: elem}, nil
+		}
+	}
+	if (len(name) == 6 || len(name) == 7) && strings.HasPrefix(name, "mat") && name[4] == 'x' &&
+		name[3] >= '2' && name[3] <= '4' && name[5] >= '2' && name[5] <= '4' {
+		t := &Matrix{Cols: int(name[3] - '0'), Rows: int(name[5] - '0')}
+		if len(name) == 6 {
+			if err := nargs(1); err != nil {
+				return nil, err
+			}
+			elem, err := scalarArg(0)
+			if err != nil {
+				return nil, err
+			}
+			t.Elem = elem
+			return t, nil
+		}
+		if elem := scalarSuffixes[name[6]]; elem == F32 || elem == F16 {
+			if err := nargs(0); err != nil {
+				return nil, err
+			}
+			t.Elem = elem
+			return t, nil
+		}
+	}
+	if rest, ok := strings.CutPrefix(name, "texture_depth_"); ok {
+		if dim, ok := textureDims[rest]; ok && dim != Dim1D && dim != Dim3D {
+			if err := nargs(0); err != nil {
+				return nil, err
+			}
+			return &Texture{Kind: TextureDepth, Dim: dim}, nil
+		}
+	}
+	if rest, ok := strings.CutPrefix(name, "texture_storage_"); ok {
+		if dim, ok := textureDims[rest]; ok && dim != DimCube && dim != DimCubeArray {
+			if err := nargs(2); err != nil {
+				return nil, err
+			}
+			format, err := identArg(0)
+			if err != nil {
+				return nil, err
+			}
+			access, err := identArg(1)
+			if err != nil {
+				return nil, err
+			}
+			return &Texture{Kind: TextureStorage, Dim: dim, Format: format, Access: access}, nil
+		}
+	}
+	if rest, ok := strings.CutPrefix(name, "texture_"); ok {
+		if dim, ok := textureDims[rest]; ok {
+			if err := nargs(1); err != nil {
+				return nil, err
+			}
+			sampled, err := scalarArg(0)
+			if err != nil {
+				return nil, err
+			}
+			return &Texture{Kind: TextureSampled, Dim: dim, Sampled: sampled}, nil
+		}
+	}
+
+	if t, ok := r.types[name]; ok {
+		return t, nil
+	}
+	switch d := r.decls[name].(type) {
+	case *StructDecl:
+		if err := nargs(0); err != nil {
+			return nil, err
+		}
+		return r.resolveStruct(d)
+	case *AliasDecl:
+		if err := nargs(0); err != nil {
+			return nil, err
+		}
+		if r.resolving[name] {
+			return nil, errorf(d.Pos(), "alias "+name+" refers to itself")
+		}
+		r.resolving[name] = true
+		defer delete(r.resolving, name)
+		t, err := r.resolveType(d.Type)
+		if err != nil {
+			return nil, err
+		}
+		r.types[name] = t
+		return t, nil
+	}
+	return nil, errorf(id.Pos(), "unresolved type "+name)
+}
+
+func (r *resolver) resolveStruct(d *StructDecl) (*Struct, error) {
+	name := d.Name.Name
+	if t, ok := r.types[name].(*Struct); ok {
+		return t, nil
+	}
+	if r.resolving[name] {
+		return nil, errorf(d.Pos(), "structure "+name+" contains itself")
+	}
+	r.resolving[name] = true
+	defer delete(r.resolving, name)
+
+	s := &Struct{Name: name, Decl: d}
+	for i, dm := range d.Members {
+		t, err := r.resolveType(dm.Type)
+		if err != nil {
+			return nil, err
+		}
+		if a, ok := t.(*Array); ok && a.RuntimeSized() && i != len(d.Members)-1 {
+			return nil, errorf(dm.Pos(), "runtime-sized array must be the last member of "+name)
+		}
+		m := &Member{Name: dm.Name.Name, Type: t, Size: SizeOf(t), Align: AlignOf(t)}
+		if a := Attr(dm.Attrs, "align"); a != nil {
+			v, err := r.attrInt(a)
+			if err != nil {
+				return nil, err
+			}
+			if v <= 0 || v&(v-1) != 0 {
+				return nil, errorf(a.Pos(), "@align must be a positive power of two")
+			}
+			m.Align = uint64(v)
+		}
+		if a := Attr(dm.Attrs, "size"); a != nil {
+			v, err := r.attrInt(a)
+			if err != nil {
+				return nil, err
+			}
+			if v < int64(m.Size) {
+				return nil, errorf(a.Pos(), "@size must be at least "+strconv.FormatUint(m.Size, 10)+" for "+t.String())
+			}
+			m.Size = uint64(v)
+		}
+		s.Members = append(s.Members, m)
+	}
+	s.layoutMembers()
+	r.types[name] = s
+	return s, nil
+}
+
+// attrInt evaluates the single argument of an attribute.
+func (r *resolver) attrInt(a *Attribute) (int64, error) {
+	if len(a.Args) != 1 {
+		return 0, errorf(a.Pos(), "@"+a.Name+" requires a single argument")
+	}
+	return r.evalInt(a.Args[0])
+}
+
+// overrideError is the error of evalInt for an override declaration
+// without an initializer, whose value is only set at pipeline creation.
+type overrideError struct{ err *Error }
+
+func (e *overrideError) Error() string { return e.err.Error() }
+
+// evalInt evaluates an integer constant expression. Identifiers may
+// refer to const declarations and to override declarations with an
+// initializer, whose default value is used.
+func (r *resolver) evalInt(x Expr) (int64, error) {
+	switch x := x.(type) {
+	case *BasicLit:
+		if x.Kind != INT {
+			return 0, errorf(x.Pos(), "expected integer constant, got "+x.Value)
+		}
+		v, err := x.Int()
+		if err != nil {
+			return 0, errorf(x.Pos(), "invalid integer literal "+x.Value)
+		}
+		return v, nil
+	case *ParenExpr:
+		return r.evalInt(x.X)
+	case *Ident:
+		if len(x.TemplateArgs) > 0 {
+			break
+		}
+		if v, ok := r.consts[x.Name]; ok {
+			return v, nil
+		}
+		var init Expr
+		switch d := r.decls[x.Name].(type) {
+		case *ConstDecl:
+			init = d.Init
+		case *OverrideDecl:
+			if d.Init == nil {
+				return 0, &overrideError{errorf(x.Pos(), x.Name+" is not an integer constant")}
+			}
+			init = d.Init
+		}
+		if init == nil {
+			return 0, errorf(x.Pos(), x.Name+" is not an integer constant")
+		}
+		if r.resolving[x.Name] {
+			return 0, errorf(x.Pos(), "initialization cycle for "+x.Name)
+		}
+		r.resolving[x.Name] = true
+		defer delete(r.resolving, x.Name)
+		v, err := r.evalInt(init)
+		if err != nil {
+			return 0, err
+		}
+		r.consts[x.Name] = v
+		return v, nil
+	case *CallExpr:
+		switch x.Fn.Name {
+		case "i32", "u32":
+			if len(x.Args) == 1 && len(x.Fn.TemplateArgs) == 0 {
+				return r.evalInt(x.Args[0])
+			}
+		}
+	case *UnaryExpr:
+		v, err := r.evalInt(x.X)
+		if err != nil {
+			return 0, err
+		}
+		switch x.Op {
+		case SUB:
+			return -v, nil
+		case TILDE:
+			return ^v, nil
+		}
+	case *BinaryExpr:
+		a, err := r.evalInt(x.X)
+		if err != nil {
+			return 0, err
+		}
+		b, err := r.evalInt(x.Y)
+		if err != nil {
+			return 0, err
+		}
+		switch x.Op {
+		case ADD:
+			return a + b, nil
+		case SUB:
+			return a - b, nil
+		case MUL:
+			return a * b, nil
+		case QUO, REM:
+			if b == 0 {
+				return 0, errorf(x.OpPos, "division by zero")
+			}
+			if x.Op == QUO {
+				return a / b, nil
+			}
+			return a % b, nil
+		case SHL:
+			return a << uint64(b), nil
+		case SHR:
+			return a >> uint64(b), nil
+		case AND:
+			return a & b, nil
+		case OR:
+			return a | b, nil
+		case XOR:
+			return a ^ b, nil
+		}
+	}
+	return 0, errorf(x.Pos(), "cannot evaluate "+ExprString(x)+" as an integer constant")
+}
+
+// scalarType infers the type of a scalar expression, such as the
+// initializer of an override declaration without a type. Abstract
+// numbers take the type i32 or f32.
+func (r *resolver) scalarType(x Expr) (Scalar, error) {
+	s, _, err := r.exprScalar(x)
+	return s, err
+}
+
+// exprScalar returns the type of a scalar expression. For abstract
+// numbers, it returns I32 or F32 and abstract set.
+func (r *resolver) exprScalar(x Expr) (s Scalar, abstract bool, err error) {
+	switch x := x.(type) {
+	case *BasicLit:
+		v := x.Value
+		hex := strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X")
+		switch x.Kind {
+		case TRUE, FALSE:
+			return Bool, false, nil
+		case INT:
+			if s := scalarSuffixes[v[len(v)-1]]; s == I32 || s == U32 {
+				return s, false, nil
+			}
+			return I32, true, nil
+		case FLOAT:
+			// Hexadecimal floats only have a suffix after an exponent.
+			if s := scalarSuffixes[v[len(v)-1]]; (s == F32 || s == F16) && (!hex || strings.ContainsAny(v, "pP")) {
+				return s, false, nil
+			}
+			return F32, true, nil
+		}
+	case *ParenExpr:
+		return r.exprScalar(x.X)
+	case *UnaryExpr:
+		if x.Op == NOT {
+			return Bool, false, nil
+		}
+		return r.exprScalar(x.X)
+	case *BinaryExpr:
+		switch x.Op {
+		case EQL, NEQ, LSS, GTR, LEQ, GEQ, LAND, LOR:
+			return Bool, false, nil
+		case SHL, SHR:
+			return r.exprScalar(x.X)
+		}
+		a, abstractA, err := r.exprScalar(x.X)
+		if err != nil {
+			return 0, false, err
+		}
+		b, abstractB, err := r.exprScalar(x.Y)
+		if err != nil {
+			return 0, false, err
+		}
+		switch {
+		case abstractA && !abstractB:
+			return b, false, nil
+		case abstractA && abstractB && b == F32:
+			return F32, true, nil
+		}
+		return a, abstractA, nil
+	case *CallExpr:
+		if len(x.Fn.TemplateArgs) == 0 {
+			switch x.Fn.Name {
+			case "bool", "i32", "u32", "f32", "f16":
+				t, err := r.resolveType(x.Fn)
+				return t.(Scalar), false, err
+			}
+		}
+	case *Ident:
+		if len(x.TemplateArgs) > 0 {
+			break
+		}
+		var typ, init Expr
+		switch d := r.decls[x.Name].(type) {
+		case *ConstDecl:
+			typ, init = d.Type, d.Init
+		case *OverrideDecl:
+			typ, init = d.Type, d.Init
+		default:
+			return 0, false, errorf(x.Pos(), x.Name+" is not a constant")
+		}
+		if typ != nil {
+			t, err := r.resolveType(typ)
+			if err != nil {
+				return 0, false, err
+			}
+			s, ok := t.(Scalar)
+			if !ok {
+				return 0, false, errorf(typ.Pos(), x.Name+" is not a scalar")
+			}
+			return s, false, nil
+		}
+		if init == nil {
+			return 0, false, errorf(x.Pos(), x.Name+" has neither a type nor an initializer")
+		}
+		if r.resolving[x.Name] {
+			return 0, false, errorf(x.Pos(), "initialization cycle for "+x.Name)
+		}
+		r.resolving[x.Name] = true
+		defer delete(r.resolving, x.Name)
+		s, abstract, err := r.exprScalar(init)
+		if _, ok := r.decls[x.Name].(*OverrideDecl); ok {
+			// Overrides have a concrete type.
+			abstract = false
+		}
+		return s, abstract, err
+	}
+	return 0, false, errorf(x.Pos(), "cannot infer the type of "+ExprString(x))
+}
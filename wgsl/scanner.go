@@ -0,0 +1,554 @@
+package wgsl
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// scanner tokenizes WGSL source. Template list delimiters are resolved
+// up front by discoverTemplates, so the scanner can report '<' and '>'
+// as TMPLBEG/TMPLEND where the specification requires it.
+type scanner struct {
+	src  string
+	tmpl map[int]Token
+
+	offset     int
+	line       int
+	lineOffset int
+
+	err func(pos Pos, msg string)
+}
+
+func newScanner(src string, err func(pos Pos, msg string)) *scanner {
+	return &scanner{
+		src:  src,
+		tmpl: discoverTemplates(src),
+		line: 1,
+		err:  err,
+	}
+}
+
+func (s *scanner) pos(offset int) Pos {
+	return Pos{Offset: offset, Line: s.line, Column: offset - s.lineOffset + 1}
+}
+
+// skip advances past blankspace and comments, keeping track of lines.
+func (s *scanner) skip() {
+	for s.offset < len(s.src) {
+		c := s.src[s.offset]
+		switch {
+		case c == '\n':
+			s.offset++
+			s.line++
+			s.lineOffset = s.offset
+		case c == ' ' || c == '\t' || c == '\r' || c == '\v' || c == '\f':
+			s.offset++
+		case c == '/' && s.offset+1 < len(s.src) && s.src[s.offset+1] == '/':
+			for s.offset < len(s.src) && s.src[s.offset] != '\n' {
+				s.offset++
+			}
+		case c == '/' && s.offset+1 < len(s.src) && s.src[s.offset+1] == '*':
+			start := s.pos(s.offset)
+			s.offset += 2
+			depth := 1
+			for depth > 0 {
+				if s.offset >= len(s.src) {
+					s.err(start, "block comment not terminated")
+					return
+				}
+				switch {
+				case s.src[s.offset] == '\n':
+					s.offset++
+					s.line++
+					s.lineOffset = s.offset
+				case hasPrefixAt(s.src, s.offset, "/*"):
+					s.offset += 2
+					depth++
+				case hasPrefixAt(s.src, s.offset, "*/"):
+					s.offset += 2
+					depth--
+				default:
+					s.offset++
+				}
+			}
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(s.src[s.offset:])
+			if !isBlank(r) {
+				return
+			}
+			if r == '\u0085' || r == '\u2028' || r == '\u2029' {
+				s.line++
+				s.lineOffset = s.offset + size
+			}
+			s.offset += size
+		default:
+			return
+		}
+	}
+}
+
+// scan returns the next token, its position and its literal text.
+func (s *scanner) scan() (pos Pos, tok Token, lit string) {
+	s.skip()
+	pos = s.pos(s.offset)
+	if s.offset >= len(s.src) {
+		return pos, EOF, ""
+	}
+
+	start := s.offset
+	c := s.src[s.offset]
+	switch {
+	case isIdentStart(c) || c >= utf8.RuneSelf:
+		end := scanIdent(s.src, start)
+		if end == start {
+			_, size := utf8.DecodeRuneInString(s.src[start:])
+			s.offset += size
+			s.err(pos, "invalid character "+quoteRune(s.src[start:start+size]))
+			return pos, ILLEGAL, s.src[start:s.offset]
+		}
+		s.offset = end
+		lit = s.src[start:end]
+		if lit == "_" {
+			return pos, PHONY, lit
+		}
+		if len(lit) > 1 && lit[0] == '_' && lit[1] == '_' {
+			s.err(pos, "identifiers must not start with two underscores")
+		}
+		return pos, Lookup(lit), lit
+	case isDigit(c) || (c == '.' && s.offset+1 < len(s.src) && isDigit(s.src[s.offset+1])):
+		end, tok, ok := scanNumber(s.src, start)
+		s.offset = end
+		lit = s.src[start:end]
+		if !ok {
+			s.err(pos, "malformed numeric literal "+lit)
+			return pos, ILLEGAL, lit
+		}
+		return pos, tok, lit
+	}
+
+	if t, ok := s.tmpl[start]; ok {
+		s.offset++
+		return pos, t, s.src[start:s.offset]
+	}
+
+	tok = ILLEGAL
+	n := 1
+	at := func(i int) byte {
+		if start+i < len(s.src) {
+			return s.src[start+i]
+		}
+		return 0
+	}
+	switch c {
+	case '&':
+		tok = AND
+		if at(1) == '&' {
+			tok, n = LAND, 2
+		} else if at(1) == '=' {
+			tok, n = AND_ASSIGN, 2
+		}
+	case '|':
+		tok = OR
+		if at(1) == '|' {
+			tok, n = LOR, 2
+		} else if at(1) == '=' {
+			tok, n = OR_ASSIGN, 2
+		}
+	case '^':
+		tok = XOR
+		if at(1) == '=' {
+			tok, n = XOR_ASSIGN, 2
+		}
+	case '<':
+		tok = LSS
+		if at(1) == '<' {
+			tok, n = SHL, 2
+			if at(2) == '=' {
+				tok, n = SHL_ASSIGN, 3
+			}
+		} else if at(1) == '=' {
+			tok, n = LEQ, 2
+		}
+	case '>':
+		tok = GTR
+		if at(1) == '>' {
+			tok, n = SHR, 2
+			if at(2) == '=' {
+				tok, n = SHR_ASSIGN, 3
+			}
+		} else if at(1) == '=' {
+			tok, n = GEQ, 2
+		}
+	case '+':
+		tok = ADD
+		if at(1) == '+' {
+			tok, n = INC, 2
+		} else if at(1) == '=' {
+			tok, n = ADD_ASSIGN, 2
+		}
+	case '-':
+		tok = SUB
+		if at(1) == '-' {
+			tok, n = DEC, 2
+		} else if at(1) == '=' {
+			tok, n = SUB_ASSIGN, 2
+		} else if at(1) == '>' {
+			tok, n = ARROW, 2
+		}
+	case '*':
+		tok = MUL
+		if at(1) == '=' {
+			tok, n = MUL_ASSIGN, 2
+		}
+	case '/':
+		tok = QUO
+		if at(1) == '=' {
+			tok, n = QUO_ASSIGN, 2
+		}
+	case '%':
+		tok = REM
+		if at(1) == '=' {
+			tok, n = REM_ASSIGN, 2
+		}
+	case '!':
+		tok = NOT
+		if at(1) == '=' {
+			tok, n = NEQ, 2
+		}
+	case '=':
+		tok = ASSIGN
+		if at(1) == '=' {
+			tok, n = EQL, 2
+		}
+	case '~':
+		tok = TILDE
+	case '@':
+		tok = AT
+	case '(':
+		tok = LPAREN
+	case ')':
+		tok = RPAREN
+	case '[':
+		tok = LBRACK
+	case ']':
+		tok = RBRACK
+	case '{':
+		tok = LBRACE
+	case '}':
+		tok = RBRACE
+	case ',':
+		tok = COMMA
+	case '.':
+		tok = PERIOD
+	case ':':
+		tok = COLON
+	case ';':
+		tok = SEMI
+	default:
+		s.err(pos, "invalid character "+quoteRune(s.src[start:start+1]))
+	}
+	s.offset += n
+	return pos, tok, s.src[start:s.offset]
+}
+
+// discoverTemplates implements the template list discovery algorithm of
+// the WGSL specification. It returns the offsets of every '<' that opens
+// and every '>' that closes a template list.
+func discoverTemplates(src string) map[int]Token {
+	type pending struct {
+		offset int
+		depth  int
+	}
+
+	result := map[int]Token{}
+	var stack []pending
+	depth := 0
+	pos := 0
+
+	skipBlank := func() {
+		for pos < len(src) {
+			c := src[pos]
+			switch {
+			case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f':
+				pos++
+			case hasPrefixAt(src, pos, "//"):
+				for pos < len(src) && src[pos] != '\n' {
+					pos++
+				}
+			case hasPrefixAt(src, pos, "/*"):
+				pos += 2
+				for d := 1; d > 0 && pos < len(src); {
+					switch {
+					case hasPrefixAt(src, pos, "/*"):
+						pos += 2
+						d++
+					case hasPrefixAt(src, pos, "*/"):
+						pos += 2
+						d--
+					default:
+						pos++
+					}
+				}
+			case c >= utf8.RuneSelf:
+				r, size := utf8.DecodeRuneInString(src[pos:])
+				if !isBlank(r) {
+					return
+				}
+				pos += size
+			default:
+				return
+			}
+		}
+	}
+	popTo := func(depth int) {
+		for len(stack) > 0 && stack[len(stack)-1].depth >= depth {
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	for {
+		skipBlank()
+		if pos >= len(src) {
+			return result
+		}
+		c := src[pos]
+
+		if isIdentStart(c) || c >= utf8.RuneSelf {
+			if end := scanIdent(src, pos); end > pos {
+				pos = end
+				skipBlank()
+				if pos < len(src) && src[pos] == '<' {
+					stack = append(stack, pending{offset: pos, depth: depth})
+					pos++
+					if pos < len(src) && (src[pos] == '<' || src[pos] == '=') {
+						stack = stack[:len(stack)-1]
+						pos++
+					}
+				}
+				continue
+			}
+		}
+		if isDigit(c) || (c == '.' && pos+1 < len(src) && isDigit(src[pos+1])) {
+			pos, _, _ = scanNumber(src, pos)
+			continue
+		}
+
+		switch c {
+		case '>':
+			if n := len(stack); n > 0 && stack[n-1].depth == depth {
+				result[stack[n-1].offset] = TMPLBEG
+				result[pos] = TMPLEND
+				stack = stack[:n-1]
+				pos++
+				continue
+			}
+			pos++
+			if pos < len(src) && src[pos] == '=' {
+				pos++
+			}
+		case '(', '[':
+			depth++
+			pos++
+		case ')', ']':
+			popTo(depth)
+			if depth > 0 {
+				depth--
+			}
+			pos++
+		case '!':
+			pos++
+			if pos < len(src) && src[pos] == '=' {
+				pos++
+			}
+		case '=':
+			pos++
+			if pos < len(src) && src[pos] == '=' {
+				pos++
+				continue
+			}
+			depth = 0
+			stack = stack[:0]
+		case ';', '{', ':':
+			depth = 0
+			stack = stack[:0]
+			pos++
+		case '&', '|':
+			if pos+1 < len(src) && src[pos+1] == c {
+				popTo(depth)
+				pos += 2
+				continue
+			}
+			pos++
+		default:
+			_, size := utf8.DecodeRuneInString(src[pos:])
+			pos += size
+		}
+	}
+}
+
+// scanIdent returns the end offset of the identifier starting at start,
+// or start if there is none.
+func scanIdent(src string, start int) int {
+	pos := start
+	for pos < len(src) {
+		r, size := rune(src[pos]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRuneInString(src[pos:])
+		}
+		if pos == start {
+			if r != '_' && !isXIDStart(r) {
+				return start
+			}
+		} else if !isXIDContinue(r) {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
+
+// scanNumber returns the end offset and kind of the numeric literal
+// starting at start. ok is false for malformed literals.
+func scanNumber(src string, start int) (end int, tok Token, ok bool) {
+	pos := start
+	at := func(i int) byte {
+		if i < len(src) {
+			return src[i]
+		}
+		return 0
+	}
+	digits := func(hex bool) int {
+		n := 0
+		for pos < len(src) && (isDigit(src[pos]) || (hex && isHex(src[pos]))) {
+			pos++
+			n++
+		}
+		return n
+	}
+	exponent := func(marks string) bool {
+		c := at(pos)
+		if c != marks[0] && c != marks[1] {
+			return true
+		}
+		pos++
+		if c := at(pos); c == '+' || c == '-' {
+			pos++
+		}
+		return digits(false) > 0
+	}
+	// Consume any trailing identifier characters so that errors such as
+	// 1abc are reported as a single malformed literal.
+	finish := func(tok Token, ok bool) (int, Token, bool) {
+		if end := scanIdent(src, pos); end > pos {
+			pos, ok = end, false
+		} else {
+			for pos < len(src) && isDigit(src[pos]) {
+				pos++
+				ok = false
+			}
+		}
+		return pos, tok, ok
+	}
+
+	if at(pos) == '0' && (at(pos+1) == 'x' || at(pos+1) == 'X') {
+		pos += 2
+		intDigits := digits(true)
+		if at(pos) == '.' {
+			pos++
+			fracDigits := digits(true)
+			if intDigits+fracDigits == 0 {
+				return finish(FLOAT, false)
+			}
+			if at(pos) == 'p' || at(pos) == 'P' {
+				if !exponent("pP") {
+					return finish(FLOAT, false)
+				}
+				if c := at(pos); c == 'f' || c == 'h' {
+					pos++
+				}
+			}
+			return finish(FLOAT, true)
+		}
+		if intDigits == 0 {
+			return finish(INT, false)
+		}
+		if at(pos) == 'p' || at(pos) == 'P' {
+			if !exponent("pP") {
+				return finish(FLOAT, false)
+			}
+			if c := at(pos); c == 'f' || c == 'h' {
+				pos++
+			}
+			return finish(FLOAT, true)
+		}
+		if c := at(pos); c == 'i' || c == 'u' {
+			pos++
+		}
+		return finish(INT, true)
+	}
+
+	intDigits := digits(false)
+	isFloat := false
+	if at(pos) == '.' {
+		pos++
+		digits(false)
+		isFloat = true
+	}
+	if c := at(pos); c == 'e' || c == 'E' {
+		if !exponent("eE") {
+			return finish(FLOAT, false)
+		}
+		isFloat = true
+	}
+	if isFloat {
+		if c := at(pos); c == 'f' || c == 'h' {
+			pos++
+		}
+		return finish(FLOAT, true)
+	}
+	leadingZero := intDigits > 1 && src[start] == '0'
+	switch at(pos) {
+	case 'f', 'h':
+		pos++
+		return finish(FLOAT, !leadingZero)
+	case 'i', 'u':
+		pos++
+	}
+	return finish(INT, !leadingZero)
+}
+
+func hasPrefixAt(s string, i int, prefix string) bool {
+	return len(s)-i >= len(prefix) && s[i:i+len(prefix)] == prefix
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func isHex(c byte) bool {
+	return isDigit(c) || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isXIDStart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.Is(unicode.Nl, r)
+}
+
+func isXIDContinue(r rune) bool {
+	return r == '_' || isXIDStart(r) || unicode.IsDigit(r) ||
+		unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) ||
+		unicode.Is(unicode.Nd, r) || unicode.Is(unicode.Pc, r)
+}
+
+func isBlank(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\v', '\f', '\r', '\u0085', '\u200E', '\u200F', '\u2028', '\u2029':
+		return true
+	}
+	return false
+}
+
+func quoteRune(s string) string {
+	return "'" + s + "'"
+}
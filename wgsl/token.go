@@ -0,0 +1,279 @@
+package wgsl
+
+import (
+	"strconv"
+)
+
+// Pos describes a position in WGSL source. Line and Column are
+// 1-based, Column counts bytes, and Offset is the 0-based byte offset.
+type Pos struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// IsValid reports whether the position is known.
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Pos) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
+// Token is the set of lexical tokens of WGSL.
+type Token int
+
+const (
+	ILLEGAL Token = iota
+	EOF
+
+	literalBeg
+	IDENT // main
+	INT   // 12345, 0x1fu
+	FLOAT // 1.5, 2e3f, 0x1p4h
+	literalEnd
+
+	operatorBeg
+	AND     // &
+	OR      // |
+	XOR     // ^
+	SHL     // <<
+	SHR     // >>
+	ADD     // +
+	SUB     // -
+	MUL     // *
+	QUO     // /
+	REM     // %
+	LAND    // &&
+	LOR     // ||
+	NOT     // !
+	TILDE   // ~
+	EQL     // ==
+	NEQ     // !=
+	LSS     // <
+	GTR     // >
+	LEQ     // <=
+	GEQ     // >=
+	INC     // ++
+	DEC     // --
+	ASSIGN  // =
+	ARROW   // ->
+	AT      // @
+	LPAREN  // (
+	RPAREN  // )
+	LBRACK  // [
+	RBRACK  // ]
+	LBRACE  // {
+	RBRACE  // }
+	COMMA   // ,
+	PERIOD  // .
+	COLON   // :
+	SEMI    // ;
+	PHONY   // _
+	TMPLBEG // < opening a template list
+	TMPLEND // > closing a template list
+
+	ADD_ASSIGN // +=
+	SUB_ASSIGN // -=
+	MUL_ASSIGN // *=
+	QUO_ASSIGN // /=
+	REM_ASSIGN // %=
+	AND_ASSIGN // &=
+	OR_ASSIGN  // |=
+	XOR_ASSIGN // ^=
+	SHL_ASSIGN // <<=
+	SHR_ASSIGN // >>=
+	operatorEnd
+
+	keywordBeg
+	ALIAS
+	BREAK
+	CASE
+	CONST
+	CONST_ASSERT
+	CONTINUE
+	CONTINUING
+	DEFAULT
+	DIAGNOSTIC
+	DISCARD
+	ELSE
+	ENABLE
+	FALSE
+	FN
+	FOR
+	IF
+	LET
+	LOOP
+	OVERRIDE
+	REQUIRES
+	RETURN
+	STRUCT
+	SWITCH
+	TRUE
+	VAR
+	WHILE
+	keywordEnd
+)
+
+var tokens = [...]string{
+	ILLEGAL: "ILLEGAL",
+	EOF:     "EOF",
+
+	IDENT: "IDENT",
+	INT:   "INT",
+	FLOAT: "FLOAT",
+
+	AND:     "&",
+	OR:      "|",
+	XOR:     "^",
+	SHL:     "<<",
+	SHR:     ">>",
+	ADD:     "+",
+	SUB:     "-",
+	MUL:     "*",
+	QUO:     "/",
+	REM:     "%",
+	LAND:    "&&",
+	LOR:     "||",
+	NOT:     "!",
+	TILDE:   "~",
+	EQL:     "==",
+	NEQ:     "!=",
+	LSS:     "<",
+	GTR:     ">",
+	LEQ:     "<=",
+	GEQ:     ">=",
+	INC:     "++",
+	DEC:     "--",
+	ASSIGN:  "=",
+	ARROW:   "->",
+	AT:      "@",
+	LPAREN:  "(",
+	RPAREN:  ")",
+	LBRACK:  "[",
+	RBRACK:  "]",
+	LBRACE:  "{",
+	RBRACE:  "}",
+	COMMA:   ",",
+	PERIOD:  ".",
+	COLON:   ":",
+	SEMI:    ";",
+	PHONY:   "_",
+	TMPLBEG: "<",
+	TMPLEND: ">",
+
+	ADD_ASSIGN: "+=",
+	SUB_ASSIGN: "-=",
+	MUL_ASSIGN: "*=",
+	QUO_ASSIGN: "/=",
+	REM_ASSIGN: "%=",
+	AND_ASSIGN: "&=",
+	OR_ASSIGN:  "|=",
+	XOR_ASSIGN: "^=",
+	SHL_ASSIGN: "<<=",
+	SHR_ASSIGN: ">>=",
+
+	ALIAS:        "alias",
+	BREAK:        "break",
+	CASE:         "case",
+	CONST:        "const",
+	CONST_ASSERT: "const_assert",
+	CONTINUE:     "continue",
+	CONTINUING:   "continuing",
+	DEFAULT:      "default",
+	DIAGNOSTIC:   "diagnostic",
+	DISCARD:      "discard",
+	ELSE:         "else",
+	ENABLE:       "enable",
+	FALSE:        "false",
+	FN:           "fn",
+	FOR:          "for",
+	IF:           "if",
+	LET:          "let",
+	LOOP:         "loop",
+	OVERRIDE:     "override",
+	REQUIRES:     "requires",
+	RETURN:       "return",
+	STRUCT:       "struct",
+	SWITCH:       "switch",
+	TRUE:         "true",
+	VAR:          "var",
+	WHILE:        "while",
+}
+
+func (tok Token) String() string {
+	if 0 <= tok && int(tok) < len(tokens) && tokens[tok] != "" {
+		return tokens[tok]
+	}
+	return "token(" + strconv.Itoa(int(tok)) + ")"
+}
+
+// IsLiteral reports whether tok is an identifier or a numeric literal.
+func (tok Token) IsLiteral() bool { return literalBeg < tok && tok < literalEnd }
+
+// IsOperator reports whether tok is an operator or delimiter.
+func (tok Token) IsOperator() bool { return operatorBeg < tok && tok < operatorEnd }
+
+// IsKeyword reports whether tok is a keyword.
+func (tok Token) IsKeyword() bool { return keywordBeg < tok && tok < keywordEnd }
+
+var keywords map[string]Token
+
+func init() {
+	keywords = make(map[string]Token, keywordEnd-keywordBeg)
+	for i := keywordBeg + 1; i < keywordEnd; i++ {
+		keywords[tokens[i]] = i
+	}
+}
+
+// Lookup maps an identifier to its keyword token or IDENT.
+func Lookup(ident string) Token {
+	if tok, ok := keywords[ident]; ok {
+		return tok
+	}
+	return IDENT
+}
+
+// reserved holds the words the WGSL specification reserves for future
+// use. They may not be used to name declarations.
+var reserved = map[string]bool{
+	"NULL": true, "Self": true, "abstract": true, "active": true, "alignas": true,
+	"alignof": true, "as": true, "asm": true, "asm_fragment": true, "async": true,
+	"attribute": true, "auto": true, "await": true, "become": true, "cast": true,
+	"catch": true, "class": true, "co_await": true, "co_return": true, "co_yield": true,
+	"coherent": true, "column_major": true, "common": true, "compile": true,
+	"compile_fragment": true, "concept": true, "const_cast": true, "consteval": true,
+	"constexpr": true, "constinit": true, "crate": true, "debugger": true, "decltype": true,
+	"delete": true, "demote": true, "demote_to_helper": true, "do": true,
+	"dynamic_cast": true, "enum": true, "explicit": true, "export": true, "extends": true,
+	"extern": true, "external": true, "fallthrough": true, "filter": true, "final": true,
+	"finally": true, "friend": true, "from": true, "fxgroup": true, "get": true,
+	"goto": true, "groupshared": true, "highp": true, "impl": true, "implements": true,
+	"import": true, "inline": true, "instanceof": true, "interface": true, "layout": true,
+	"lowp": true, "macro": true, "macro_rules": true, "match": true, "mediump": true,
+	"meta": true, "mod": true, "module": true, "move": true, "mut": true, "mutable": true,
+	"namespace": true, "new": true, "nil": true, "noexcept": true, "noinline": true,
+	"nointerpolation": true, "noperspective": true, "null": true, "nullptr": true,
+	"of": true, "operator": true, "package": true, "packoffset": true, "partition": true,
+	"pass": true, "patch": true, "pixelfragment": true, "precise": true, "precision": true,
+	"premerge": true, "priv": true, "protected": true, "pub": true, "public": true,
+	"readonly": true, "ref": true, "regardless": true, "register": true,
+	"reinterpret_cast": true, "require": true, "resource": true, "restrict": true,
+	"self": true, "set": true, "shared": true, "sizeof": true, "smooth": true, "snorm": true,
+	"static": true, "static_assert": true, "static_cast": true, "std": true,
+	"subroutine": true, "super": true, "target": true, "template": true, "this": true,
+	"thread_local": true, "throw": true, "trait": true, "try": true, "type": true,
+	"typedef": true, "typeid": true, "typename": true, "typeof": true, "union": true,
+	"unless": true, "unorm": true, "unsafe": true, "unsized": true, "use": true,
+	"using": true, "varying": true, "virtual": true, "volatile": true, "wgsl": true,
+	"where": true, "with": true, "writeonly": true, "yield": true,
+}
+
+// IsReserved reports whether ident is a reserved word.
+func IsReserved(ident string) bool {
+	return reserved[ident]
+}
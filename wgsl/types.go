@@ -0,0 +1,270 @@
+package wgsl
+
+import (
+	"strconv"
+)
+
+// Type is a resolved WGSL type.
+type Type interface {
+	String() string
+	typeNode()
+}
+
+// Scalar is a WGSL scalar type.
+type Scalar int
+
+const (
+	Bool Scalar = iota + 1
+	I32
+	U32
+	F32
+	F16
+)
+
+func (s Scalar) String() string {
+	switch s {
+	case Bool:
+		return "bool"
+	case I32:
+		return "i32"
+	case U32:
+		return "u32"
+	case F32:
+		return "f32"
+	case F16:
+		return "f16"
+	default:
+		return "scalar(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// Vector is a vecN<T> type.
+type Vector struct {
+	Size int
+	Elem Scalar
+}
+
+func (t *Vector) String() string {
+	return "vec" + strconv.Itoa(t.Size) + "<" + t.Elem.String() + ">"
+}
+
+// Matrix is a matCxR<T> type with Cols column vectors of Rows
+// components each.
+type Matrix struct {
+	Cols int
+	Rows int
+	Elem Scalar
+}
+
+func (t *Matrix) String() string {
+	return "mat" + strconv.Itoa(t.Cols) + "x" + strconv.Itoa(t.Rows) + "<" + t.Elem.String() + ">"
+}
+
+// Atomic is an atomic<T> type.
+type Atomic struct {
+	Elem Scalar
+}
+
+func (t *Atomic) String() string {
+	return "atomic<" + t.Elem.String() + ">"
+}
+
+// Array is a fixed-size or, when Count is 0, runtime-sized array.
+type Array struct {
+	Elem  Type
+	Count int
+}
+
+func (t *Array) String() string {
+	if t.Count == 0 {
+		return "array<" + t.Elem.String() + ">"
+	}
+	return "array<" + t.Elem.String() + ", " + strconv.Itoa(t.Count) + ">"
+}
+
+// RuntimeSized reports whether the array has no fixed element count.
+func (t *Array) RuntimeSized() bool {
+	return t.Count == 0
+}
+
+// Stride returns the distance in bytes between consecutive elements.
+func (t *Array) Stride() uint64 {
+	return roundUp(AlignOf(t.Elem), SizeOf(t.Elem))
+}
+
+// Struct is a structure type. Offset, Size and Align of each member
+// are filled in when the structure is resolved.
+type Struct struct {
+	Name    string
+	Members []*Member
+	Decl    *StructDecl
+}
+
+// Member is a member of a Struct. Size and Align include the effect of
+// @size and @align attributes.
+type Member struct {
+	Name   string
+	Type   Type
+	Offset uint64
+	Size   uint64
+	Align  uint64
+}
+
+func (t *Struct) String() string {
+	return t.Name
+}
+
+// Member returns the member called name, or nil.
+func (t *Struct) Member(name string) *Member {
+	for _, m := range t.Members {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Sampler is a sampler or sampler_comparison type.
+type Sampler struct {
+	Comparison bool
+}
+
+func (t *Sampler) String() string {
+	if t.Comparison {
+		return "sampler_comparison"
+	}
+	return "sampler"
+}
+
+// TextureKind distinguishes the families of WGSL texture types.
+type TextureKind int
+
+const (
+	TextureSampled TextureKind = iota + 1
+	TextureMultisampled
+	TextureDepth
+	TextureDepthMultisampled
+	TextureStorage
+	TextureExternal
+)
+
+// TextureDim is the view dimension of a texture type, spelled as in the
+// WGSL type name.
+type TextureDim string
+
+const (
+	Dim1D        TextureDim = "1d"
+	Dim2D        TextureDim = "2d"
+	Dim2DArray   TextureDim = "2d_array"
+	Dim3D        TextureDim = "3d"
+	DimCube      TextureDim = "cube"
+	DimCubeArray TextureDim = "cube_array"
+)
+
+// Texture is a texture type. Sampled is the sampled scalar type of
+// sampled and multisampled textures. Format and Access are the texel
+// format and access mode of storage textures.
+type Texture struct {
+	Kind    TextureKind
+	Dim     TextureDim
+	Sampled Scalar
+	Format  string
+	Access  string
+}
+
+func (t *Texture) String() string {
+	switch t.Kind {
+	case TextureSampled:
+		return "texture_" + string(t.Dim) + "<" + t.Sampled.String() + ">"
+	case TextureMultisampled:
+		return "texture_multisampled_" + string(t.Dim) + "<" + t.Sampled.String() + ">"
+	case TextureDepth:
+		return "texture_depth_" + string(t.Dim)
+	case TextureDepthMultisampled:
+		return "texture_depth_multisampled_" + string(t.Dim)
+	case TextureStorage:
+		return "texture_storage_" + string(t.Dim) + "<" + t.Format + ", " + t.Access + ">"
+	default:
+		return "texture_external"
+	}
+}
+
+func (Scalar) typeNode()   {}
+func (*Vector) typeNode()  {}
+func (*Matrix) typeNode()  {}
+func (*Atomic) typeNode()  {}
+func (*Array) typeNode()   {}
+func (*Struct) typeNode()  {}
+func (*Sampler) typeNode() {}
+func (*Texture) typeNode() {}
+
+// SizeOf returns the size in bytes of a host-shareable type. A
+// runtime-sized array, on its own or as the last member of a
+// structure, is counted with a single element, which makes the result
+// the minimum size of a buffer binding holding t.
+func SizeOf(t Type) uint64 {
+	switch t := t.(type) {
+	case Scalar:
+		if t == F16 {
+			return 2
+		}
+		return 4
+	case *Atomic:
+		return 4
+	case *Vector:
+		return uint64(t.Size) * SizeOf(t.Elem)
+	case *Matrix:
+		return uint64(t.Cols) * roundUp(AlignOf(&Vector{Size: t.Rows, Elem: t.Elem}), SizeOf(&Vector{Size: t.Rows, Elem: t.Elem}))
+	case *Array:
+		return uint64(max(t.Count, 1)) * t.Stride()
+	case *Struct:
+		if len(t.Members) == 0 {
+			return 0
+		}
+		last := t.Members[len(t.Members)-1]
+		return roundUp(AlignOf(t), last.Offset+last.Size)
+	}
+	return 0
+}
+
+// AlignOf returns the alignment in bytes of a host-shareable type.
+func AlignOf(t Type) uint64 {
+	switch t := t.(type) {
+	case Scalar, *Atomic:
+		return SizeOf(t)
+	case *Vector:
+		n := t.Size
+		if n == 3 {
+			n = 4
+		}
+		return uint64(n) * SizeOf(t.Elem)
+	case *Matrix:
+		return AlignOf(&Vector{Size: t.Rows, Elem: t.Elem})
+	case *Array:
+		return AlignOf(t.Elem)
+	case *Struct:
+		var align uint64 = 1
+		for _, m := range t.Members {
+			align = max(align, m.Align)
+		}
+		return align
+	}
+	return 1
+}
+
+// layoutMembers computes member offsets. Size and Align of each member
+// must already hold the natural or attribute-specified values.
+func (t *Struct) layoutMembers() {
+	var offset uint64
+	for _, m := range t.Members {
+		m.Offset = roundUp(m.Align, offset)
+		offset = m.Offset + m.Size
+	}
+}
+
+func roundUp(k, n uint64) uint64 {
+	if k == 0 {
+		return n
+	}
+	return (n + k - 1) / k * k
+}
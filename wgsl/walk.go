@@ -0,0 +1,215 @@
+package wgsl
+
+// Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses a syntax tree in depth-first order, like go/ast.Walk.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Module:
+		for _, d := range n.Directives {
+			Walk(v, d)
+		}
+		for _, d := range n.Decls {
+			Walk(v, d)
+		}
+
+	case *Attribute:
+		walkExprs(v, n.Args)
+	case *Ident:
+		walkExprs(v, n.TemplateArgs)
+	case *EnableDirective:
+		for _, x := range n.Extensions {
+			Walk(v, x)
+		}
+	case *RequiresDirective:
+		for _, x := range n.Features {
+			Walk(v, x)
+		}
+	case *DiagnosticDirective:
+		Walk(v, n.Severity)
+
+	case *VarDecl:
+		walkAttrs(v, n.Attrs)
+		walkOpt(v, n.AddressSpace)
+		walkOpt(v, n.AccessMode)
+		Walk(v, n.Name)
+		walkOpt(v, n.Type)
+		walkOpt(v, n.Init)
+	case *ConstDecl:
+		Walk(v, n.Name)
+		walkOpt(v, n.Type)
+		Walk(v, n.Init)
+	case *OverrideDecl:
+		walkAttrs(v, n.Attrs)
+		Walk(v, n.Name)
+		walkOpt(v, n.Type)
+		walkOpt(v, n.Init)
+	case *LetDecl:
+		Walk(v, n.Name)
+		walkOpt(v, n.Type)
+		Walk(v, n.Init)
+	case *AliasDecl:
+		Walk(v, n.Name)
+		Walk(v, n.Type)
+	case *StructDecl:
+		Walk(v, n.Name)
+		for _, m := range n.Members {
+			Walk(v, m)
+		}
+	case *StructMember:
+		walkAttrs(v, n.Attrs)
+		Walk(v, n.Name)
+		Walk(v, n.Type)
+	case *FuncDecl:
+		walkAttrs(v, n.Attrs)
+		Walk(v, n.Name)
+		for _, param := range n.Params {
+			Walk(v, param)
+		}
+		walkAttrs(v, n.ReturnAttrs)
+		walkOpt(v, n.ReturnType)
+		Walk(v, n.Body)
+	case *Param:
+		walkAttrs(v, n.Attrs)
+		Walk(v, n.Name)
+		Walk(v, n.Type)
+	case *ConstAssert:
+		Walk(v, n.Cond)
+
+	case *EmptyStmt, *BranchStmt:
+		// nothing to do
+	case *CompoundStmt:
+		walkAttrs(v, n.Attrs)
+		for _, s := range n.List {
+			Walk(v, s)
+		}
+	case *ReturnStmt:
+		walkOpt(v, n.Value)
+	case *IfStmt:
+		walkAttrs(v, n.Attrs)
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+		walkOpt(v, n.Else)
+	case *SwitchStmt:
+		walkAttrs(v, n.Attrs)
+		Walk(v, n.Tag)
+		walkAttrs(v, n.BodyAttrs)
+		for _, c := range n.Clauses {
+			Walk(v, c)
+		}
+	case *CaseClause:
+		walkExprs(v, n.Selectors)
+		Walk(v, n.Body)
+	case *LoopStmt:
+		walkAttrs(v, n.Attrs)
+		Walk(v, n.Body)
+		if n.Continuing != nil {
+			Walk(v, n.Continuing)
+		}
+	case *ContinuingStmt:
+		Walk(v, n.Body)
+		walkOpt(v, n.BreakIf)
+	case *ForStmt:
+		walkAttrs(v, n.Attrs)
+		walkOpt(v, n.Init)
+		walkOpt(v, n.Cond)
+		walkOpt(v, n.Update)
+		Walk(v, n.Body)
+	case *WhileStmt:
+		walkAttrs(v, n.Attrs)
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+	case *AssignStmt:
+		Walk(v, n.Lhs)
+		Walk(v, n.Rhs)
+	case *IncDecStmt:
+		Walk(v, n.X)
+	case *CallStmt:
+		Walk(v, n.Call)
+	case *DeclStmt:
+		Walk(v, n.Decl)
+
+	case *BasicLit:
+		// nothing to do
+	case *ParenExpr:
+		Walk(v, n.X)
+	case *CallExpr:
+		Walk(v, n.Fn)
+		walkExprs(v, n.Args)
+	case *IndexExpr:
+		Walk(v, n.X)
+		Walk(v, n.Index)
+	case *MemberExpr:
+		Walk(v, n.X)
+		Walk(v, n.Member)
+	case *UnaryExpr:
+		Walk(v, n.X)
+	case *BinaryExpr:
+		Walk(v, n.X)
+		Walk(v, n.Y)
+
+	default:
+		panic("wgsl.Walk: unexpected node type")
+	}
+
+	v.Visit(nil)
+}
+
+// walkOpt walks node unless it is a nil interface or a typed nil
+// pointer of one of the optional node kinds.
+func walkOpt(v Visitor, node Node) {
+	switch n := node.(type) {
+	case nil:
+		return
+	case *Ident:
+		if n == nil {
+			return
+		}
+	case *CompoundStmt:
+		if n == nil {
+			return
+		}
+	case *IfStmt:
+		if n == nil {
+			return
+		}
+	}
+	Walk(v, node)
+}
+
+func walkAttrs(v Visitor, list []*Attribute) {
+	for _, a := range list {
+		Walk(v, a)
+	}
+}
+
+func walkExprs(v Visitor, list []Expr) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a syntax tree in depth-first order, calling f for
+// each node and then f(nil) after its children. If f returns false the
+// children of that node are skipped.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}